@@ -15,203 +15,210 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/follows/followers": {
+        "/.well-known/jwks.json": {
             "get": {
-                "security": [
-                    {
-                        "ApiKeyAuth": []
-                    }
+                "description": "Public keys (EdDSA and RS256) that other services can use to verify our tokens. HS256 keys are never published.",
+                "produces": [
+                    "application/json"
                 ],
-                "description": "Get all followers of the authenticated user",
                 "tags": [
-                    "Follow"
+                    "Auth"
                 ],
-                "summary": "Get followers",
+                "summary": "JSON Web Key Set",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.User"
-                            }
+                            "$ref": "#/definitions/handlers.JwksResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "500": {
+                        "description": "Keyring is not configured",
                         "schema": {
-                            "$ref": "#/definitions/handlers.FollowErrorResponse"
+                            "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/follows/following": {
+        "/admin/erasures/{id}": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Get all users the authenticated user is following",
+                "description": "Get the status and progress of an erasure job. Admin only.",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "Follow"
+                    "Admin"
+                ],
+                "summary": "Get erasure job",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Get following",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.User"
-                            }
+                            "$ref": "#/definitions/models.ErasureJob"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Invalid job id",
                         "schema": {
-                            "$ref": "#/definitions/handlers.FollowErrorResponse"
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Job not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/follows/{following_id}": {
-            "post": {
-                "description": "Follow another user. User cannot follow themselves and following must exist.",
-                "consumes": [
-                    "application/json"
+        "/admin/lockouts/{user_id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
                 ],
+                "description": "Remove the login lockout and the failed attempt counter of a user. Admin only.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Follow"
+                    "Admin"
                 ],
-                "summary": "Follow a user",
+                "summary": "Clear login lockout",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "ID of the user to follow",
-                        "name": "following_id",
+                        "description": "User ID",
+                        "name": "user_id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "followed successfully",
+                        "description": "Lockout cleared",
                         "schema": {
-                            "$ref": "#/definitions/handlers.FollowResponse"
+                            "$ref": "#/definitions/handlers.AdminMessageResponse"
                         }
                     },
                     "400": {
-                        "description": "validation error or user not found or trying to follow yourself",
+                        "description": "Invalid user id",
                         "schema": {
-                            "$ref": "#/definitions/handlers.FollowErrorResponse"
+                            "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "follower not found",
+                    "403": {
+                        "description": "Admin role required",
                         "schema": {
-                            "$ref": "#/definitions/handlers.FollowErrorResponse"
+                            "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/admin/posts/{id}": {
             "delete": {
-                "description": "UnFollow another user. User cannot Unfollow themselves and following must exist.",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
                 ],
+                "description": "Remove any post, it is also removed from the timelines of the author's followers. Moderators and admins only.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Follow"
+                    "Admin"
                 ],
-                "summary": "Follow a user",
+                "summary": "Remove a post",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "ID of the user to unfollow",
-                        "name": "following_id",
+                        "description": "Post ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "unfollowed successfully",
+                        "description": "Post removed",
                         "schema": {
-                            "$ref": "#/definitions/handlers.FollowResponse"
+                            "$ref": "#/definitions/handlers.AdminMessageResponse"
                         }
                     },
                     "400": {
-                        "description": "validation error or user not found or trying to unfollow yourself",
+                        "description": "Invalid post id",
                         "schema": {
-                            "$ref": "#/definitions/handlers.FollowErrorResponse"
+                            "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "following not found",
+                        "description": "Post not found",
                         "schema": {
-                            "$ref": "#/definitions/handlers.FollowErrorResponse"
+                            "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/posts": {
-            "post": {
+        "/admin/users": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Create a new post with optional media file .User must be logged in and provide a valid token.",
-                "consumes": [
-                    "multipart/form-data"
-                ],
+                "description": "List accounts, newest first. Moderators and admins only.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Posts"
+                    "Admin"
                 ],
-                "summary": "Create a new post",
+                "summary": "List recent accounts",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Post title",
-                        "name": "title",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Post content",
-                        "name": "content",
-                        "in": "formData",
-                        "required": true
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
                     },
                     {
-                        "type": "file",
-                        "description": "Media file (image/video)",
-                        "name": "media",
-                        "in": "formData"
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Accounts per page, at most 100",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Post created successfully",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.PostSuccessfullResponse"
+                            "$ref": "#/definitions/handlers.AdminUsersResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad request or validation error",
+                        "description": "Failed to list users",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -219,166 +226,155 @@ const docTemplate = `{
                 }
             }
         },
-        "/posts/{id}": {
-            "get": {
+        "/admin/users/{user_id}/erasure": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Get a single post by its ID",
+                "description": "Start the erasure of a user's account with their posts, media, follows and timelines. Admin only.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Posts"
+                    "Admin"
                 ],
-                "summary": "Get post by ID",
+                "summary": "Erase a user",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Post ID",
-                        "name": "id",
+                        "description": "User ID",
+                        "name": "user_id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "202": {
+                        "description": "Erasure started",
                         "schema": {
-                            "$ref": "#/definitions/models.Post"
+                            "$ref": "#/definitions/models.ErasureJob"
                         }
                     },
                     "400": {
-                        "description": "Invalid post id",
+                        "description": "Invalid user id",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "Post not found",
+                        "description": "User not found",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/admin/users/{user_id}/role": {
             "put": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Edit a post's title, content, or media (only author can edit)",
+                "description": "Make a user a regular user, a moderator or an admin. Admin only, admins can not change their own role.",
                 "consumes": [
-                    "multipart/form-data"
+                    "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Posts"
+                    "Admin"
                 ],
-                "summary": "Edit a post",
+                "summary": "Change a user's role",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Post ID",
-                        "name": "id",
+                        "description": "User ID",
+                        "name": "user_id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Post title",
-                        "name": "title",
-                        "in": "formData"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Post content",
-                        "name": "content",
-                        "in": "formData"
-                    },
-                    {
-                        "type": "file",
-                        "description": "Media file",
-                        "name": "media",
-                        "in": "formData"
+                        "description": "New role",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SetRoleRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Post updated successfully",
+                    "200": {
+                        "description": "Role changed",
                         "schema": {
-                            "$ref": "#/definitions/handlers.PostSuccessfullResponse"
+                            "$ref": "#/definitions/handlers.AdminMessageResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad request or validation error",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
-                        }
-                    },
-                    "403": {
-                        "description": "Forbidden: not the author",
+                        "description": "Invalid user id or role",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "Post not found",
+                        "description": "User not found",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/admin/users/{user_id}/suspend": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Delete a post by ID (only author can delete)",
+                "description": "Suspend a user and log out all their sessions, suspended users can not log in. Moderators can only suspend regular users.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Posts"
+                    "Admin"
                 ],
-                "summary": "Delete a post",
+                "summary": "Suspend a user",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Post ID",
-                        "name": "id",
+                        "description": "User ID",
+                        "name": "user_id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Post deleted successfully",
+                        "description": "User suspended",
                         "schema": {
-                            "$ref": "#/definitions/handlers.PostSuccessfullResponse"
+                            "$ref": "#/definitions/handlers.AdminMessageResponse"
                         }
                     },
                     "400": {
-                        "description": "Invalid post id",
+                        "description": "Invalid user id",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "403": {
-                        "description": "Forbidden: not the author",
+                        "description": "Not allowed to suspend this user",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "Post not found",
+                        "description": "User not found",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -386,49 +382,45 @@ const docTemplate = `{
                 }
             }
         },
-        "/timeline/{limit}/{page}": {
-            "get": {
+        "/admin/users/{user_id}/unsuspend": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Get posts from user's followings with pagination",
+                "description": "Lift the suspension of a user. Moderators and admins only.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Posts"
+                    "Admin"
                 ],
-                "summary": "Get user's timeline posts",
+                "summary": "Unsuspend a user",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Number of posts per page",
-                        "name": "limit",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Page number",
-                        "name": "page",
+                        "description": "User ID",
+                        "name": "user_id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "202": {
-                        "description": "Accepted",
+                    "200": {
+                        "description": "User unsuspended",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Post"
-                            }
+                            "$ref": "#/definitions/handlers.AdminMessageResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad request",
+                        "description": "Invalid user id",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -436,141 +428,3463 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/login": {
-            "post": {
-                "description": "Authenticate user using email or username and password, returns JWT",
-                "consumes": [
-                    "application/json"
+        "/blocks": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
                 ],
+                "description": "Get the users the authenticated user has blocked, most recent first",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Auth"
-                ],
-                "summary": "Login user",
-                "parameters": [
-                    {
-                        "description": "User Login Data.  NOTE: Send either username or email for login, but do not provide both at the same time.",
-                        "name": "input",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.UserLoginRequest"
-                        }
-                    }
+                    "Blocks"
                 ],
+                "summary": "Get blocked users",
                 "responses": {
                     "200": {
-                        "description": "User Logged in successfully",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.UserLoginResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.UserSummary"
+                            }
                         }
                     },
                     "400": {
-                        "description": "Validation Error.",
+                        "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/blocks/{user_id}": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Block a user. Follows between the two users are removed in both directions, their posts leave each other's timelines and they can no longer follow each other or see each other's posts and profiles.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Blocks"
+                ],
+                "summary": "Block a user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID of the user to block",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "user blocked",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.BlockMessageResponse"
                         }
                     },
-                    "401": {
-                        "description": "Password is wrong",
+                    "400": {
+                        "description": "invalid user id, yourself or already blocked",
                         "schema": {
-                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                            "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "User not found",
+                        "description": "user not found",
                         "schema": {
-                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                            "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/users/register": {
-            "post": {
-                "description": "Register a new user and return JWT token",
-                "consumes": [
-                    "application/json"
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
                 ],
+                "description": "Lift a block. Follows removed by the block are not restored.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Auth"
+                    "Blocks"
                 ],
-                "summary": "Register a new user",
+                "summary": "Unblock a user",
                 "parameters": [
                     {
-                        "description": "User registration info",
-                        "name": "input",
-                        "in": "body",
-                        "required": true,
+                        "type": "integer",
+                        "description": "ID of the user to unblock",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "user unblocked",
                         "schema": {
-                            "$ref": "#/definitions/handlers.UserRegisterRequest"
+                            "$ref": "#/definitions/handlers.BlockMessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid user id or not blocked",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     }
+                }
+            }
+        },
+        "/follows/followers": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get all followers of the authenticated user",
+                "tags": [
+                    "Follow"
                 ],
+                "summary": "Get followers",
                 "responses": {
-                    "201": {
-                        "description": "User created successfully",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.UserRegisterResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.PublicUser"
+                            }
                         }
                     },
                     "400": {
-                        "description": "Failed to create user",
+                        "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/handlers.FollowErrorResponse"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "handlers.ErrorResponse": {
-            "type": "object",
-            "properties": {
-                "error": {
-                    "type": "string",
-                    "example": "failed to create post"
-                },
-                "message": {
-                    "type": "string",
-                    "example": "error message"
+        },
+        "/follows/following": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get all users the authenticated user is following",
+                "tags": [
+                    "Follow"
+                ],
+                "summary": "Get following",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.PublicUser"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.FollowErrorResponse"
+                        }
+                    }
                 }
             }
         },
-        "handlers.FollowErrorResponse": {
-            "type": "object",
-            "properties": {
-                "error": {
-                    "type": "string",
-                    "example": "error"
-                },
-                "message": {
-                    "type": "string",
-                    "example": "error message"
+        "/follows/requests": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get the pending follow requests of the authenticated user, oldest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Follow"
+                ],
+                "summary": "Get follow requests",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.FollowRequest"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.FollowErrorResponse"
+                        }
+                    }
                 }
             }
         },
-        "handlers.FollowResponse": {
-            "type": "object",
-            "properties": {
-                "message": {
-                    "type": "string",
-                    "example": "followed successfully"
+        "/follows/requests/{id}/approve": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Approve a pending follow request, the requester starts following the authenticated user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Follow"
+                ],
+                "summary": "Approve a follow request",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Follow request ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "follow request approved",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.FollowResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request id",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.FollowErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "follow request not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.FollowErrorResponse"
+                        }
+                    }
                 }
             }
         },
-        "handlers.PostSuccessfullResponse": {
-            "type": "object",
-            "properties": {
-                "message": {
+        "/follows/requests/{id}/reject": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Reject a pending follow request of the authenticated user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Follow"
+                ],
+                "summary": "Reject a follow request",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Follow request ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "follow request rejected",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.FollowResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid request id",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.FollowErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "follow request not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.FollowErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/follows/{following_id}": {
+            "post": {
+                "description": "Follow another user. User cannot follow themselves and following must exist. Following a private account sends a follow request that the account has to approve.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Follow"
+                ],
+                "summary": "Follow a user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID of the user to follow",
+                        "name": "following_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "followed successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.FollowResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "follow request sent to a private account",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.FollowRequestedResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "validation error or user not found or trying to follow yourself",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.FollowErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "follower not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.FollowErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "UnFollow another user. User cannot Unfollow themselves and following must exist. A pending follow request to the user is canceled.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Follow"
+                ],
+                "summary": "Follow a user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID of the user to unfollow",
+                        "name": "following_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "unfollowed successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.FollowResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "validation error or user not found or trying to unfollow yourself",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.FollowErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "following not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.FollowErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/mutes": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get the users the authenticated user has muted, most recent first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Blocks"
+                ],
+                "summary": "Get muted users",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.UserSummary"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/mutes/{user_id}": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Hide the posts of a user from the authenticated user's timeline. The muted user is not told and can still follow and see the authenticated user.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Blocks"
+                ],
+                "summary": "Mute a user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID of the user to mute",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "user muted",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.BlockMessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid user id, yourself or already muted",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "user not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Show the posts of a muted user in the timeline again",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Blocks"
+                ],
+                "summary": "Unmute a user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID of the user to unmute",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "user unmuted",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.BlockMessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid user id or not muted",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Create a new post with optional media file .User must be logged in and provide a valid token.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Posts"
+                ],
+                "summary": "Create a new post",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Post title",
+                        "name": "title",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Post content",
+                        "name": "content",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "Media file (image/video)",
+                        "name": "media",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Post created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PostSuccessfullResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request or validation error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/search": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Search the title and content of posts, most relevant first. All words must match; use \"quotes\" for a phrase and a trailing * for a prefix, for example go* \"hello world\".",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Posts"
+                ],
+                "summary": "Search posts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Search query",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Username of the author",
+                        "name": "author",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Posts created on or after, YYYY-MM-DD or RFC 3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Posts created before, a date includes that whole day",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only posts with or without media",
+                        "name": "has_media",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Posts per page, at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PostSearchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query or filter",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/trash": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get your posts that are in the trash, the last deleted first. They can be restored until purge_at.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Posts"
+                ],
+                "summary": "Get your deleted posts",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Posts per page, at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TrashResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Failed to get trash",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get a single post by its ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Posts"
+                ],
+                "summary": "Get post by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Post"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid post id",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Post not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Edit a post's title, content, or media (only author can edit)",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Posts"
+                ],
+                "summary": "Edit a post",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Post title",
+                        "name": "title",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Post content",
+                        "name": "content",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "file",
+                        "description": "Media file",
+                        "name": "media",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Post updated successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PostSuccessfullResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request or validation error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden: not the author",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Post not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Move a post to the trash by ID (only author can delete). It can be restored until it is purged after the retention period. A purged post with replies or quotes is kept as a tombstone without content so the conversation and quotes stay whole, reposts of it are removed. Deleting a repost removes it right away.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Posts"
+                ],
+                "summary": "Delete a post",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Post deleted successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PostSuccessfullResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid post id",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden: not the author",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Post not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/quote": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Share a post with your own commentary. When the quoted post is deleted the quote shows a tombstone in its place. Posts of private accounts can not be quoted.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Posts"
+                ],
+                "summary": "Quote a post",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Quote title",
+                        "name": "title",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Quote content",
+                        "name": "content",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "Media file (image/video)",
+                        "name": "media",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Quote created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Post"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request or validation error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Post of a private account",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Post not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Post was deleted",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/reactions": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Set your reaction to a post, an earlier reaction of yours is replaced. Types: like, love, laugh, wow, sad, angry.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Posts"
+                ],
+                "summary": "React to a post",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reaction",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReactionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReactionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid post id or reaction type",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Post not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Post was deleted",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Remove your reaction to a post",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Posts"
+                ],
+                "summary": "Remove your reaction",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReactionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid post id",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Post or reaction not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Post was deleted",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/replies": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Reply to a post with optional media file. The reply joins the conversation of the post and is shown in the timelines of the author's followers.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Posts"
+                ],
+                "summary": "Reply to a post",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Reply title",
+                        "name": "title",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Reply content",
+                        "name": "content",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "Media file (image/video)",
+                        "name": "media",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Reply created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Post"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request or validation error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Post not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Post was deleted",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/repost": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Share a post as it is with your followers, reposting a repost shares the original post. Posts of private accounts can not be shared.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Posts"
+                ],
+                "summary": "Repost a post",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Repost created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Post"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid post id",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Post of a private account",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Post not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Already reposted or post was deleted",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Remove your repost of a post, it is also removed from your followers' timelines",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Posts"
+                ],
+                "summary": "Undo a repost",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID of the reposted post",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Repost removed",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PostSuccessfullResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid post id",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Post not found or not reposted",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Take your post out of the trash, it goes back to your followers' timelines",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Posts"
+                ],
+                "summary": "Restore a deleted post",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Post ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Post restored",
+                        "schema": {
+                            "$ref": "#/definitions/models.Post"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid post id",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Post not in the trash",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/thread": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get the first post of the conversation the post belongs to and its replies, oldest first. Replies are flattened, use parent_id to build the tree. Deleted posts that have replies are shown as tombstones without content.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Posts"
+                ],
+                "summary": "Get the conversation of a post",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Post ID, any post of the conversation",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Replies per page, at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PostThreadResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid post id or cursor",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Post not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tags/{tag}/posts": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get posts whose content has the hashtag, newest first. Tags are matched case-insensitively, a leading # is ignored.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Posts"
+                ],
+                "summary": "Get posts with a hashtag",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Hashtag",
+                        "name": "tag",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Posts per page, at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TagPostsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid tag or cursor",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/timeline/{limit}/{page}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get posts from user's followings with pagination",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Posts"
+                ],
+                "summary": "Get user's timeline posts",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Number of posts per page",
+                        "name": "limit",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Post"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/export/download": {
+            "get": {
+                "description": "Download a data archive with the one-time link from the status endpoint",
+                "produces": [
+                    "application/zip"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Download export",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Download token",
+                        "name": "token",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "ZIP archive",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "404": {
+                        "description": "Link is invalid or has expired",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/login": {
+            "post": {
+                "description": "Authenticate user using email or username and password, returns an access token and a refresh token. If two-factor authentication is enabled, an mfa_token is returned instead and the login must be completed at /users/login/2fa. Repeated failures lock the account and the IP for an exponentially growing time.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Login user",
+                "parameters": [
+                    {
+                        "description": "User Login Data.  NOTE: Send either username or email for login, but do not provide both at the same time.",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserLoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User Logged in successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserLoginResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Validation Error.",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Account is suspended",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many failed attempts, see Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/login/2fa": {
+            "post": {
+                "description": "Exchange the mfa_token from /users/login and a TOTP code or recovery code for an access token and a refresh token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Complete login with two-factor code",
+                "parameters": [
+                    {
+                        "description": "MFA token and code",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MfaLoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User Logged in successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserLoginResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Token or code is invalid",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many failed attempts, see Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/logout": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Revoke the current access token and every refresh token issued with it",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Logout user",
+                "responses": {
+                    "200": {
+                        "description": "Logged out successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserMessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Failed to logout",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get the profile of the authenticated user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Get my profile",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Erase the authenticated user's account with their posts, media, follows and timelines. The password is required. The erasure runs in the background, every session is logged out immediately.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Delete my account",
+                "parameters": [
+                    {
+                        "description": "Current password",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DeleteAccountRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Erasure started",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErasureJob"
+                        }
+                    },
+                    "400": {
+                        "description": "Failed to delete account",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Password is wrong",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Update first name, last name, username, email, privacy, bio (160 characters), website (an http or https URL, 200 characters), location (100 characters) or pronouns (40 characters) of the authenticated user. Send an empty string to clear bio, website, location or pronouns. Changing the email requires verifying it again. Followers of a private account (is_private) must be approved, making it public approves every pending request.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Update my profile",
+                "parameters": [
+                    {
+                        "description": "Fields to update",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UpdateProfileRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Validation Error.",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Username or email already exists",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/2fa/confirm": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Enable two-factor authentication with a code from the authenticator app. Returns one-time recovery codes, they are shown only once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Confirm two-factor setup",
+                "parameters": [
+                    {
+                        "description": "TOTP code",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MfaCodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MfaConfirmResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Code is invalid or setup was not started",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/2fa/disable": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Turn two-factor authentication off. Needs the password and a TOTP code or a recovery code.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Disable two-factor authentication",
+                "parameters": [
+                    {
+                        "description": "Password and code",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MfaDisableRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Two-factor authentication disabled",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserMessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Code is invalid",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Password is wrong",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/2fa/setup": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Create a TOTP secret for the authenticated user. Two-factor authentication is enforced only after it is confirmed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Start two-factor setup",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/repositories.MfaSetup"
+                        }
+                    },
+                    "400": {
+                        "description": "Two-factor authentication is already enabled",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/avatar": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Upload a new avatar for the authenticated user, it replaces the old one. Images only (png, jpg, jpeg, gif, bmp, webp), at most MAX_PROFILE_IMAGE_SIZE MB.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Upload my avatar",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Avatar image",
+                        "name": "avatar",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Missing, too large or invalid file",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Remove the avatar of the authenticated user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Remove my avatar",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Failed to remove avatar",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/banner": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Upload a new profile banner for the authenticated user, it replaces the old one. Images only (png, jpg, jpeg, gif, bmp, webp), at most MAX_PROFILE_IMAGE_SIZE MB.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Upload my banner",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Banner image",
+                        "name": "banner",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Missing, too large or invalid file",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Remove the profile banner of the authenticated user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Remove my banner",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Failed to remove banner",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/export": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Start building a ZIP archive with the profile, posts, media, followers and followings of the authenticated user. Poll the status endpoint for the download link.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Export my data",
+                "responses": {
+                    "202": {
+                        "description": "Export started",
+                        "schema": {
+                            "$ref": "#/definitions/models.ExportJob"
+                        }
+                    },
+                    "400": {
+                        "description": "Failed to start export",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/export/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get the status of an export job. Every call on a finished export returns a new one-time download link.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Get my export",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Export ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ExportStatusResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid export id",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Export not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/password": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Change the password of the authenticated user. Every other session is logged out and a new token pair is returned.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Change my password",
+                "parameters": [
+                    {
+                        "description": "Old and new password",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ChangePasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Password changed successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserLoginResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Validation Error or the new password does not meet the policy",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Old password is wrong",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Existing sessions could not be logged out",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/sessions": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "List the devices the authenticated user is logged in on, most recently used first. The session of the current token is marked with current.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "List my sessions",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Session"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Failed to list sessions",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/sessions/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Revoke a session of the authenticated user, e.g. on a lost device. Its access and refresh tokens stop working at once.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Log out a session",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Session ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Session revoked",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserMessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid session id",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Session not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/tokens": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "List the personal access tokens of the authenticated user, newest first. Secrets are never shown again.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "List my personal access tokens",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.PersonalTokenResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Failed to list tokens",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Create a token for scripts and integrations. Send it as \"Authorization: Bearer pat_...\". It can only call routes its scopes allow (profile:read, users:read, posts:read, posts:write, follows:read, follows:write) and expires after expires_in_days (default 30, at most 365). The token is only shown in this response.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Create a personal access token",
+                "parameters": [
+                    {
+                        "description": "Token name, scopes and lifetime",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreatePersonalTokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Token created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreatePersonalTokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/tokens/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Delete a personal access token of the authenticated user, it stops working at once.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Delete a personal access token",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Token ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Token deleted",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserMessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid token id",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Token not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/oidc/{provider}/callback": {
+            "get": {
+                "description": "The provider redirects here after the user signed in. The account is created on the first login. Answers like /users/login.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Provider login callback",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Provider name",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization code",
+                        "name": "code",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "State from the login redirect",
+                        "name": "state",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserLoginResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Login expired or provider answer is invalid",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Account is suspended",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown provider",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Email belongs to another account",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/oidc/{provider}/login": {
+            "get": {
+                "description": "Redirect to an OpenID Connect provider (e.g. google or the corporate SSO) configured in OIDC_PROVIDERS. The provider sends the user back to the callback.",
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Log in with a provider",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Provider name",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Redirect to the provider"
+                    },
+                    "404": {
+                        "description": "Unknown provider",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Provider unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/password/forgot": {
+            "post": {
+                "description": "Send a single-use password reset link to the email. The response is the same whether the email exists or not.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Request password reset",
+                "parameters": [
+                    {
+                        "description": "Account email",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ForgotPasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Reset link sent if the account exists",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserMessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Validation Error.",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/password/reset": {
+            "post": {
+                "description": "Set a new password with a reset token. The token works once and every existing session is logged out.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Reset password",
+                "parameters": [
+                    {
+                        "description": "Reset token and new password",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ResetPasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Password changed successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserMessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Token is invalid or expired, or the password does not meet the policy",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Existing sessions could not be logged out",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/refresh": {
+            "post": {
+                "description": "Exchange a refresh token for a new access token and refresh token. Each refresh token can be used only once, reusing it revokes the whole login.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Refresh tokens",
+                "parameters": [
+                    {
+                        "description": "Refresh token",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserRefreshRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Tokens refreshed successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserLoginResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Validation Error.",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Refresh token is invalid, expired or reused",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/register": {
+            "post": {
+                "description": "Register a new user and return an access token and a refresh token. A verification link is sent to the email. The password must meet the password policy: long enough, hard to guess, not containing the username or email and not found in known data breaches.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Register a new user",
+                "parameters": [
+                    {
+                        "description": "User registration info",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserRegisterRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "User created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserRegisterResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Failed to create user",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Password check unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/search": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Find users whose username, first name or last name starts with the query, for example to autocomplete @mentions. Exact matches come first, then prefix matches.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Search users",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Search query, a leading @ is ignored",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Number of results, at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.UserSummary"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Query is missing",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/verify": {
+            "get": {
+                "description": "Verify the user's email with the signed token from the verification link",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Verify email",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Verification token",
+                        "name": "token",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Email verified successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserMessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Token is invalid, expired or the email has changed",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/verify/resend": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Send a new verification link to the authenticated user's email",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Resend verification email",
+                "responses": {
+                    "200": {
+                        "description": "Verification email sent",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserMessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Email is already verified or mail could not be sent",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{username}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get the public profile of a user with follower, following and post counts and whether the caller follows them",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Get user profile",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username",
+                        "name": "username",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PublicProfile"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{username}/posts": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get the posts of a user, newest first. Posts of private accounts are only shown to their followers.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Get user posts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username",
+                        "name": "username",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Posts per page, at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserPostsResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Account is private",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "handlers.AdminMessageResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "lockout cleared"
+                }
+            }
+        },
+        "handlers.AdminUsersResponse": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer",
+                    "example": 20
+                },
+                "page": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "users": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.User"
+                    }
+                }
+            }
+        },
+        "handlers.BlockMessageResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "user blocked"
+                }
+            }
+        },
+        "handlers.ChangePasswordRequest": {
+            "type": "object",
+            "properties": {
+                "new_password": {
+                    "type": "string",
+                    "example": "newpassword123"
+                },
+                "old_password": {
+                    "type": "string",
+                    "example": "password123"
+                }
+            }
+        },
+        "handlers.CreatePersonalTokenRequest": {
+            "type": "object",
+            "properties": {
+                "expires_in_days": {
+                    "type": "integer",
+                    "example": 30
+                },
+                "name": {
+                    "type": "string",
+                    "example": "deploy bot"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "posts:read",
+                        "posts:write"
+                    ]
+                }
+            }
+        },
+        "handlers.CreatePersonalTokenResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "posts:read",
+                        "posts:write"
+                    ]
+                },
+                "token": {
+                    "type": "string",
+                    "example": "pat_abc"
+                }
+            }
+        },
+        "handlers.DeleteAccountRequest": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "example": "password123"
+                }
+            }
+        },
+        "handlers.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "failed to create post"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "error message"
+                }
+            }
+        },
+        "handlers.ExportStatusResponse": {
+            "type": "object",
+            "properties": {
+                "download_expires_in": {
+                    "type": "integer",
+                    "example": 900
+                },
+                "download_url": {
+                    "type": "string",
+                    "example": "http://localhost:3001/users/export/download?token=abc"
+                },
+                "job": {
+                    "$ref": "#/definitions/models.ExportJob"
+                }
+            }
+        },
+        "handlers.FollowErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "error"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "error message"
+                }
+            }
+        },
+        "handlers.FollowRequestedResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "follow request sent"
+                }
+            }
+        },
+        "handlers.FollowResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "followed successfully"
+                }
+            }
+        },
+        "handlers.ForgotPasswordRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "john@example.com"
+                }
+            }
+        },
+        "handlers.JwksResponse": {
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/utils.Jwk"
+                    }
+                }
+            }
+        },
+        "handlers.MfaCodeRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "123456"
+                }
+            }
+        },
+        "handlers.MfaConfirmResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "two-factor authentication enabled"
+                },
+                "recovery_codes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "abcd2345-efgh6789"
+                    ]
+                }
+            }
+        },
+        "handlers.MfaDisableRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "123456"
+                },
+                "password": {
+                    "type": "string",
+                    "example": "password123"
+                }
+            }
+        },
+        "handlers.MfaLoginRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "123456"
+                },
+                "mfa_token": {
+                    "type": "string",
+                    "example": "jwt_mfa_pending_token"
+                }
+            }
+        },
+        "handlers.PersonalTokenResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "posts:read",
+                        "posts:write"
+                    ]
+                }
+            }
+        },
+        "handlers.PostSearchResponse": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer",
+                    "example": 20
+                },
+                "page": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "posts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Post"
+                    }
+                }
+            }
+        },
+        "handlers.PostSuccessfullResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "operation was successfully"
+                }
+            }
+        },
+        "handlers.PostThreadResponse": {
+            "type": "object",
+            "properties": {
+                "next_cursor": {
+                    "type": "string",
+                    "example": "42"
+                },
+                "replies": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Post"
+                    }
+                },
+                "root": {
+                    "$ref": "#/definitions/models.Post"
+                }
+            }
+        },
+        "handlers.PublicProfile": {
+            "type": "object",
+            "properties": {
+                "avatar_path": {
+                    "type": "string",
+                    "example": "./uploads/1700000000_1_avatar.png"
+                },
+                "banner_path": {
+                    "type": "string",
+                    "example": "./uploads/1700000000_1_banner.png"
+                },
+                "bio": {
+                    "type": "string",
+                    "example": "Backend developer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "firstname": {
+                    "type": "string",
+                    "example": "John"
+                },
+                "followers_count": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "followings_count": {
+                    "type": "integer",
+                    "example": 5
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "is_following": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "is_private": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "lastname": {
+                    "type": "string",
+                    "example": "Doe"
+                },
+                "location": {
+                    "type": "string",
+                    "example": "Tehran"
+                },
+                "posts_count": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "pronouns": {
+                    "type": "string",
+                    "example": "he/him"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "johndoe"
+                },
+                "website": {
+                    "type": "string",
+                    "example": "https://johndoe.dev"
+                }
+            }
+        },
+        "handlers.ReactionRequest": {
+            "type": "object",
+            "properties": {
+                "type": {
+                    "type": "string",
+                    "example": "like"
+                }
+            }
+        },
+        "handlers.ReactionResponse": {
+            "type": "object",
+            "properties": {
+                "my_reaction": {
+                    "type": "string",
+                    "example": "like"
+                },
+                "reactions": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "handlers.ResetPasswordRequest": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "example": "newpassword123"
+                },
+                "token": {
+                    "type": "string",
+                    "example": "reset_token_from_email"
+                }
+            }
+        },
+        "handlers.SetRoleRequest": {
+            "type": "object",
+            "properties": {
+                "role": {
+                    "type": "string",
+                    "example": "moderator"
+                }
+            }
+        },
+        "handlers.TagPostsResponse": {
+            "type": "object",
+            "properties": {
+                "next_cursor": {
+                    "type": "string",
+                    "example": "42"
+                },
+                "posts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Post"
+                    }
+                },
+                "tag": {
+                    "type": "string",
+                    "example": "golang"
+                }
+            }
+        },
+        "handlers.TrashResponse": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer",
+                    "example": 20
+                },
+                "page": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "posts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.TrashedPost"
+                    }
+                }
+            }
+        },
+        "handlers.TrashedPost": {
+            "type": "object",
+            "properties": {
+                "author": {
+                    "$ref": "#/definitions/models.PublicUser"
+                },
+                "author_id": {
+                    "description": "0 on tombstones of erased users, they keep no link to the account",
+                    "type": "integer"
+                },
+                "content": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "entities": {
+                    "description": "Hashtags and mentions in the content",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PostEntity"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "media_path": {
+                    "type": "string"
+                },
+                "my_reaction": {
+                    "type": "string"
+                },
+                "original": {
+                    "description": "The shared post of a repost or quote, a quoted post that is gone or hidden only has its id and tombstoned_at",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Post"
+                        }
+                    ]
+                },
+                "parent_id": {
+                    "description": "Replies point to the post they answer and the first post of the conversation",
+                    "type": "integer"
+                },
+                "purge_at": {
+                    "description": "The post is deleted for good after this time",
+                    "type": "string"
+                },
+                "quote_count": {
+                    "type": "integer"
+                },
+                "quote_of_id": {
+                    "type": "integer"
+                },
+                "reactions": {
+                    "description": "Filled from the reaction counters when the post is shown, not stored with the post",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "reply_count": {
+                    "type": "integer"
+                },
+                "repost_count": {
+                    "type": "integer"
+                },
+                "repost_of_id": {
+                    "description": "A repost shares another post as it is, a quote shares it with the quoting post's content",
+                    "type": "integer"
+                },
+                "root_id": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "tombstoned_at": {
+                    "description": "Deleted posts with replies or quotes are kept without their content, so conversations and quotes stay whole",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.UpdateProfileRequest": {
+            "type": "object",
+            "properties": {
+                "bio": {
+                    "type": "string",
+                    "example": "Backend developer"
+                },
+                "email": {
+                    "type": "string",
+                    "example": "john@example.com"
+                },
+                "first_name": {
+                    "type": "string",
+                    "example": "John"
+                },
+                "is_private": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "last_name": {
+                    "type": "string",
+                    "example": "Doe"
+                },
+                "location": {
+                    "type": "string",
+                    "example": "Tehran"
+                },
+                "pronouns": {
+                    "type": "string",
+                    "example": "he/him"
+                },
+                "username": {
                     "type": "string",
-                    "example": "operation was successfully"
+                    "example": "johndoe"
+                },
+                "website": {
+                    "type": "string",
+                    "example": "https://johndoe.dev"
                 }
             }
         },
@@ -607,16 +3921,70 @@ const docTemplate = `{
         "handlers.UserLoginResponse": {
             "type": "object",
             "properties": {
+                "expires_in": {
+                    "type": "integer",
+                    "example": 900
+                },
                 "message": {
                     "type": "string",
                     "example": "user logged in successfully"
                 },
+                "mfa_required": {
+                    "description": "Set when the account has two-factor authentication, send MfaToken to /users/login/2fa",
+                    "type": "boolean",
+                    "example": false
+                },
+                "mfa_token": {
+                    "type": "string",
+                    "example": "jwt_mfa_pending_token"
+                },
+                "refresh_token": {
+                    "type": "string",
+                    "example": "jwt_refresh_token_string"
+                },
                 "token": {
                     "type": "string",
                     "example": "jwt_token_string"
                 }
             }
         },
+        "handlers.UserMessageResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "logged out successfully"
+                }
+            }
+        },
+        "handlers.UserPostsResponse": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer",
+                    "example": 20
+                },
+                "page": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "posts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Post"
+                    }
+                }
+            }
+        },
+        "handlers.UserRefreshRequest": {
+            "type": "object",
+            "properties": {
+                "refresh_token": {
+                    "type": "string",
+                    "example": "jwt_refresh_token_string"
+                }
+            }
+        },
         "handlers.UserRegisterRequest": {
             "type": "object",
             "properties": {
@@ -645,23 +4013,144 @@ const docTemplate = `{
         "handlers.UserRegisterResponse": {
             "type": "object",
             "properties": {
+                "expires_in": {
+                    "type": "integer",
+                    "example": 900
+                },
                 "message": {
                     "type": "string",
                     "example": "user created successfully"
                 },
+                "refresh_token": {
+                    "type": "string",
+                    "example": "jwt_refresh_token_string"
+                },
                 "token": {
                     "type": "string",
                     "example": "jwt_token_string"
                 }
             }
         },
+        "handlers.UserSummary": {
+            "type": "object",
+            "properties": {
+                "avatar_path": {
+                    "type": "string",
+                    "example": "./uploads/1700000000_1_avatar.png"
+                },
+                "firstname": {
+                    "type": "string",
+                    "example": "John"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "lastname": {
+                    "type": "string",
+                    "example": "Doe"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "johndoe"
+                }
+            }
+        },
+        "models.ErasureJob": {
+            "type": "object",
+            "properties": {
+                "completed_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "progress": {
+                    "type": "integer"
+                },
+                "requested_by": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "step": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ExportJob": {
+            "type": "object",
+            "properties": {
+                "completed_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.FollowRequest": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "requester": {
+                    "$ref": "#/definitions/models.PublicUser"
+                },
+                "requester_id": {
+                    "type": "integer"
+                },
+                "target_id": {
+                    "type": "integer"
+                }
+            }
+        },
         "models.Post": {
             "type": "object",
             "properties": {
                 "author": {
-                    "$ref": "#/definitions/models.User"
+                    "$ref": "#/definitions/models.PublicUser"
                 },
                 "author_id": {
+                    "description": "0 on tombstones of erased users, they keep no link to the account",
                     "type": "integer"
                 },
                 "content": {
@@ -670,43 +4159,263 @@ const docTemplate = `{
                 "created_at": {
                     "type": "string"
                 },
+                "entities": {
+                    "description": "Hashtags and mentions in the content",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PostEntity"
+                    }
+                },
                 "id": {
                     "type": "integer"
                 },
                 "media_path": {
                     "type": "string"
                 },
+                "my_reaction": {
+                    "type": "string"
+                },
+                "original": {
+                    "description": "The shared post of a repost or quote, a quoted post that is gone or hidden only has its id and tombstoned_at",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Post"
+                        }
+                    ]
+                },
+                "parent_id": {
+                    "description": "Replies point to the post they answer and the first post of the conversation",
+                    "type": "integer"
+                },
+                "quote_count": {
+                    "type": "integer"
+                },
+                "quote_of_id": {
+                    "type": "integer"
+                },
+                "reactions": {
+                    "description": "Filled from the reaction counters when the post is shown, not stored with the post",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "reply_count": {
+                    "type": "integer"
+                },
+                "repost_count": {
+                    "type": "integer"
+                },
+                "repost_of_id": {
+                    "description": "A repost shares another post as it is, a quote shares it with the quoting post's content",
+                    "type": "integer"
+                },
+                "root_id": {
+                    "type": "integer"
+                },
                 "title": {
                     "type": "string"
                 },
+                "tombstoned_at": {
+                    "description": "Deleted posts with replies or quotes are kept without their content, so conversations and quotes stay whole",
+                    "type": "string"
+                },
                 "updated_at": {
                     "type": "string"
                 }
             }
         },
+        "models.PostEntity": {
+            "type": "object",
+            "properties": {
+                "end": {
+                    "type": "integer",
+                    "example": 13
+                },
+                "start": {
+                    "type": "integer",
+                    "example": 6
+                },
+                "text": {
+                    "type": "string",
+                    "example": "golang"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "hashtag"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.PublicUser": {
+            "type": "object",
+            "properties": {
+                "avatar_path": {
+                    "type": "string"
+                },
+                "banner_path": {
+                    "type": "string"
+                },
+                "bio": {
+                    "type": "string"
+                },
+                "firstname": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_private": {
+                    "type": "boolean"
+                },
+                "lastname": {
+                    "type": "string"
+                },
+                "location": {
+                    "type": "string"
+                },
+                "pronouns": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                },
+                "website": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Session": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "current": {
+                    "type": "boolean"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "last_seen_at": {
+                    "type": "string"
+                },
+                "user_agent": {
+                    "type": "string"
+                }
+            }
+        },
         "models.User": {
             "type": "object",
             "properties": {
+                "avatar_path": {
+                    "type": "string"
+                },
+                "banner_path": {
+                    "type": "string"
+                },
+                "bio": {
+                    "type": "string"
+                },
                 "created_at": {
                     "type": "string"
                 },
                 "email": {
                     "type": "string"
                 },
+                "email_verified_at": {
+                    "type": "string"
+                },
                 "firstname": {
                     "type": "string"
                 },
                 "id": {
                     "type": "integer"
                 },
+                "is_private": {
+                    "type": "boolean"
+                },
                 "lastname": {
                     "type": "string"
                 },
+                "location": {
+                    "type": "string"
+                },
+                "pronouns": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "suspended_at": {
+                    "type": "string"
+                },
+                "totp_enabled": {
+                    "type": "boolean"
+                },
                 "updated_at": {
                     "type": "string"
                 },
                 "username": {
                     "type": "string"
+                },
+                "website": {
+                    "type": "string"
+                }
+            }
+        },
+        "repositories.MfaSetup": {
+            "type": "object",
+            "properties": {
+                "otpauth_url": {
+                    "type": "string",
+                    "example": "otpauth://totp/SocialMedia:johndoe?secret=JBSWY3DPEHPK3PXP"
+                },
+                "secret": {
+                    "type": "string",
+                    "example": "JBSWY3DPEHPK3PXP"
+                }
+            }
+        },
+        "utils.Jwk": {
+            "type": "object",
+            "properties": {
+                "alg": {
+                    "type": "string",
+                    "example": "EdDSA"
+                },
+                "crv": {
+                    "type": "string",
+                    "example": "Ed25519"
+                },
+                "e": {
+                    "type": "string"
+                },
+                "kid": {
+                    "type": "string",
+                    "example": "2025-01"
+                },
+                "kty": {
+                    "type": "string",
+                    "example": "OKP"
+                },
+                "n": {
+                    "type": "string"
+                },
+                "use": {
+                    "type": "string",
+                    "example": "sig"
+                },
+                "x": {
+                    "type": "string"
                 }
             }
         }
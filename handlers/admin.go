@@ -0,0 +1,359 @@
+package handlers
+
+import (
+	"errors"
+	"golang_task/models"
+	"golang_task/repositories"
+	"golang_task/utils"
+	"log"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AdminMessageResponse represents a successful admin operation
+type AdminMessageResponse struct {
+	Message string `json:"message" example:"lockout cleared"`
+}
+
+// SetRoleRequest represents the request body for changing a user's role
+type SetRoleRequest struct {
+	Role string `json:"role" example:"moderator"`
+}
+
+// AdminUsersResponse represents a page of accounts
+type AdminUsersResponse struct {
+	Users []models.User `json:"users"`
+	Page  int           `json:"page" example:"1"`
+	Limit int           `json:"limit" example:"20"`
+}
+
+// ClearLockoutHandler godoc
+// @Summary Clear login lockout
+// @Description Remove the login lockout and the failed attempt counter of a user. Admin only.
+// @Tags Admin
+// @Produce json
+// @Param user_id path int true "User ID"
+// @Success 200 {object} AdminMessageResponse "Lockout cleared"
+// @Failure 400 {object} ErrorResponse "Invalid user id"
+// @Failure 403 {object} ErrorResponse "Admin role required"
+// @Security ApiKeyAuth
+// @Router /admin/lockouts/{user_id} [delete]
+func ClearLockoutHandler(attemptRepo repositories.LoginAttemptRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		adminID := c.Locals("user_id").(uint)
+
+		userID, err := strconv.ParseUint(c.Params("user_id"), 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "failed to clear lockout",
+				Message: "invalid user id",
+			})
+		}
+
+		if err := attemptRepo.ClearLockout(repositories.LoginAccountKey(uint(userID), "")); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "failed to clear lockout",
+				Message: err.Error(),
+			})
+		}
+
+		log.Printf("[INFO] Admin %d cleared login lockout of user %d", adminID, userID)
+		return c.Status(fiber.StatusOK).JSON(AdminMessageResponse{
+			Message: "lockout cleared",
+		})
+	}
+}
+
+// RequestErasureHandler godoc
+// @Summary Erase a user
+// @Description Start the erasure of a user's account with their posts, media, follows and timelines. Admin only.
+// @Tags Admin
+// @Produce json
+// @Param user_id path int true "User ID"
+// @Success 202 {object} models.ErasureJob "Erasure started"
+// @Failure 400 {object} ErrorResponse "Invalid user id"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Security ApiKeyAuth
+// @Router /admin/users/{user_id}/erasure [post]
+func RequestErasureHandler(erasureRepo repositories.ErasureRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		adminID := c.Locals("user_id").(uint)
+
+		userID, err := strconv.ParseUint(c.Params("user_id"), 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "failed to start erasure",
+				Message: "invalid user id",
+			})
+		}
+
+		job, err := erasureRepo.Request(uint(userID), adminID)
+		if err != nil {
+			status := fiber.StatusBadRequest
+			if errors.Is(err, repositories.ErrUserNotFound) {
+				status = fiber.StatusNotFound
+			}
+			return c.Status(status).JSON(ErrorResponse{
+				Error:   "failed to start erasure",
+				Message: err.Error(),
+			})
+		}
+
+		log.Printf("[INFO] Admin %d requested erasure of user %d, job %d", adminID, userID, job.ID)
+		return c.Status(fiber.StatusAccepted).JSON(job)
+	}
+}
+
+// GetErasureJobHandler godoc
+// @Summary Get erasure job
+// @Description Get the status and progress of an erasure job. Admin only.
+// @Tags Admin
+// @Produce json
+// @Param id path int true "Job ID"
+// @Success 200 {object} models.ErasureJob
+// @Failure 400 {object} ErrorResponse "Invalid job id"
+// @Failure 404 {object} ErrorResponse "Job not found"
+// @Security ApiKeyAuth
+// @Router /admin/erasures/{id} [get]
+func GetErasureJobHandler(erasureRepo repositories.ErasureRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		jobID, err := strconv.ParseUint(c.Params("id"), 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "failed to get erasure job",
+				Message: "invalid job id",
+			})
+		}
+
+		job, err := erasureRepo.GetByID(uint(jobID))
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+				Error:   "failed to get erasure job",
+				Message: err.Error(),
+			})
+		}
+
+		return c.Status(fiber.StatusOK).JSON(job)
+	}
+}
+
+// RemovePostHandler godoc
+// @Summary Remove a post
+// @Description Remove any post, it is also removed from the timelines of the author's followers. Moderators and admins only.
+// @Tags Admin
+// @Produce json
+// @Param id path int true "Post ID"
+// @Success 200 {object} AdminMessageResponse "Post removed"
+// @Failure 400 {object} ErrorResponse "Invalid post id"
+// @Failure 404 {object} ErrorResponse "Post not found"
+// @Security ApiKeyAuth
+// @Router /admin/posts/{id} [delete]
+func RemovePostHandler(postRepo repositories.PostRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		moderatorID := c.Locals("user_id").(uint)
+
+		postID, err := strconv.ParseUint(c.Params("id"), 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "failed to remove post",
+				Message: "invalid post id",
+			})
+		}
+
+		post, err := postRepo.GetByID(uint(postID))
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+				Error:   "failed to remove post",
+				Message: err.Error(),
+			})
+		}
+
+		if err := postRepo.RemovePost(post); err != nil {
+			log.Printf("[ERROR] Moderator %d failed to remove post %d: %v", moderatorID, post.ID, err)
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "failed to remove post",
+				Message: err.Error(),
+			})
+		}
+		log.Printf("[INFO] Moderator %d removed post %d of user %d", moderatorID, post.ID, post.AuthorID)
+		return c.Status(fiber.StatusOK).JSON(AdminMessageResponse{
+			Message: "post removed",
+		})
+	}
+}
+
+// SuspendUserHandler godoc
+// @Summary Suspend a user
+// @Description Suspend a user and log out all their sessions, suspended users can not log in. Moderators can only suspend regular users.
+// @Tags Admin
+// @Produce json
+// @Param user_id path int true "User ID"
+// @Success 200 {object} AdminMessageResponse "User suspended"
+// @Failure 400 {object} ErrorResponse "Invalid user id"
+// @Failure 403 {object} ErrorResponse "Not allowed to suspend this user"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Security ApiKeyAuth
+// @Router /admin/users/{user_id}/suspend [post]
+func SuspendUserHandler(repo repositories.UserRepositoryInterface, tokenRepo repositories.TokenRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		moderatorID := c.Locals("user_id").(uint)
+		moderatorRole := c.Locals("user_role").(string)
+
+		userID, err := strconv.ParseUint(c.Params("user_id"), 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "failed to suspend user",
+				Message: "invalid user id",
+			})
+		}
+
+		user, err := repo.GetByID(uint(userID))
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+				Error:   "failed to suspend user",
+				Message: err.Error(),
+			})
+		}
+		if user.ID == moderatorID || (moderatorRole != models.RoleAdmin && user.Role != models.RoleUser) {
+			return c.Status(fiber.StatusForbidden).JSON(ErrorResponse{
+				Error:   "failed to suspend user",
+				Message: "you can not suspend this user",
+			})
+		}
+
+		if err := repo.Suspend(user.ID); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "failed to suspend user",
+				Message: err.Error(),
+			})
+		}
+		if err := tokenRepo.RevokeAllForUser(user.ID); err != nil {
+			log.Printf("[ERROR] Failed to revoke sessions of suspended user %d: %v", user.ID, err)
+		}
+
+		log.Printf("[INFO] Moderator %d suspended user %d", moderatorID, user.ID)
+		return c.Status(fiber.StatusOK).JSON(AdminMessageResponse{
+			Message: "user suspended",
+		})
+	}
+}
+
+// UnsuspendUserHandler godoc
+// @Summary Unsuspend a user
+// @Description Lift the suspension of a user. Moderators and admins only.
+// @Tags Admin
+// @Produce json
+// @Param user_id path int true "User ID"
+// @Success 200 {object} AdminMessageResponse "User unsuspended"
+// @Failure 400 {object} ErrorResponse "Invalid user id"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Security ApiKeyAuth
+// @Router /admin/users/{user_id}/unsuspend [post]
+func UnsuspendUserHandler(repo repositories.UserRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		moderatorID := c.Locals("user_id").(uint)
+
+		userID, err := strconv.ParseUint(c.Params("user_id"), 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "failed to unsuspend user",
+				Message: "invalid user id",
+			})
+		}
+
+		if err := repo.Unsuspend(uint(userID)); err != nil {
+			status := fiber.StatusBadRequest
+			if errors.Is(err, repositories.ErrUserNotFound) {
+				status = fiber.StatusNotFound
+			}
+			return c.Status(status).JSON(ErrorResponse{
+				Error:   "failed to unsuspend user",
+				Message: err.Error(),
+			})
+		}
+
+		log.Printf("[INFO] Moderator %d unsuspended user %d", moderatorID, userID)
+		return c.Status(fiber.StatusOK).JSON(AdminMessageResponse{
+			Message: "user unsuspended",
+		})
+	}
+}
+
+// ListRecentUsersHandler godoc
+// @Summary List recent accounts
+// @Description List accounts, newest first. Moderators and admins only.
+// @Tags Admin
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Accounts per page, at most 100" default(20)
+// @Success 200 {object} AdminUsersResponse
+// @Failure 400 {object} ErrorResponse "Failed to list users"
+// @Security ApiKeyAuth
+// @Router /admin/users [get]
+func ListRecentUsersHandler(repo repositories.UserRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		page, limit := utils.PageQuery(c)
+		users, err := repo.ListRecent((page-1)*limit, limit)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "failed to list users",
+				Message: err.Error(),
+			})
+		}
+
+		return c.Status(fiber.StatusOK).JSON(AdminUsersResponse{
+			Users: users,
+			Page:  page,
+			Limit: limit,
+		})
+	}
+}
+
+// SetRoleHandler godoc
+// @Summary Change a user's role
+// @Description Make a user a regular user, a moderator or an admin. Admin only, admins can not change their own role.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param user_id path int true "User ID"
+// @Param input body SetRoleRequest true "New role"
+// @Success 200 {object} AdminMessageResponse "Role changed"
+// @Failure 400 {object} ErrorResponse "Invalid user id or role"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Security ApiKeyAuth
+// @Router /admin/users/{user_id}/role [put]
+func SetRoleHandler(repo repositories.UserRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		adminID := c.Locals("user_id").(uint)
+
+		userID, err := strconv.ParseUint(c.Params("user_id"), 10, 64)
+		if err != nil || uint(userID) == adminID {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "failed to change role",
+				Message: "invalid user id",
+			})
+		}
+
+		var input SetRoleRequest
+		if err := utils.BodyParse(c, &input); err != nil {
+			return err
+		}
+
+		if err := repo.SetRole(uint(userID), input.Role); err != nil {
+			status := fiber.StatusBadRequest
+			if errors.Is(err, repositories.ErrUserNotFound) {
+				status = fiber.StatusNotFound
+			}
+			return c.Status(status).JSON(ErrorResponse{
+				Error:   "failed to change role",
+				Message: err.Error(),
+			})
+		}
+
+		log.Printf("[INFO] Admin %d set role of user %d to %s", adminID, userID, input.Role)
+		return c.Status(fiber.StatusOK).JSON(AdminMessageResponse{
+			Message: "role changed",
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"golang_task/models"
+	"golang_task/repositories"
+	"golang_task/utils"
+	"log"
+	"os"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// UploadAvatarHandler godoc
+// @Summary Upload my avatar
+// @Description Upload a new avatar for the authenticated user, it replaces the old one. Images only (png, jpg, jpeg, gif, bmp, webp), at most MAX_PROFILE_IMAGE_SIZE MB.
+// @Tags Users
+// @Accept multipart/form-data
+// @Produce json
+// @Param avatar formData file true "Avatar image"
+// @Success 200 {object} models.User
+// @Failure 400 {object} UserErrorResponse "Missing, too large or invalid file"
+// @Security ApiKeyAuth
+// @Router /users/me/avatar [put]
+func UploadAvatarHandler(repo repositories.UserRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return uploadProfileImage(c, repo, "avatar")
+	}
+}
+
+// DeleteAvatarHandler godoc
+// @Summary Remove my avatar
+// @Description Remove the avatar of the authenticated user
+// @Tags Users
+// @Produce json
+// @Success 200 {object} models.User
+// @Failure 400 {object} UserErrorResponse "Failed to remove avatar"
+// @Security ApiKeyAuth
+// @Router /users/me/avatar [delete]
+func DeleteAvatarHandler(repo repositories.UserRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return removeProfileImage(c, repo, "avatar")
+	}
+}
+
+// UploadBannerHandler godoc
+// @Summary Upload my banner
+// @Description Upload a new profile banner for the authenticated user, it replaces the old one. Images only (png, jpg, jpeg, gif, bmp, webp), at most MAX_PROFILE_IMAGE_SIZE MB.
+// @Tags Users
+// @Accept multipart/form-data
+// @Produce json
+// @Param banner formData file true "Banner image"
+// @Success 200 {object} models.User
+// @Failure 400 {object} UserErrorResponse "Missing, too large or invalid file"
+// @Security ApiKeyAuth
+// @Router /users/me/banner [put]
+func UploadBannerHandler(repo repositories.UserRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return uploadProfileImage(c, repo, "banner")
+	}
+}
+
+// DeleteBannerHandler godoc
+// @Summary Remove my banner
+// @Description Remove the profile banner of the authenticated user
+// @Tags Users
+// @Produce json
+// @Success 200 {object} models.User
+// @Failure 400 {object} UserErrorResponse "Failed to remove banner"
+// @Security ApiKeyAuth
+// @Router /users/me/banner [delete]
+func DeleteBannerHandler(repo repositories.UserRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return removeProfileImage(c, repo, "banner")
+	}
+}
+
+// Saves the image sent in the form field kind ("avatar" or "banner") and stores its path in the {kind}_path column
+func uploadProfileImage(c *fiber.Ctx, repo repositories.UserRepositoryInterface, kind string) error {
+	userID := c.Locals("user_id").(uint)
+
+	file, err := c.FormFile(kind)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+			Error:   "failed to upload " + kind,
+			Message: kind + " file is required",
+		})
+	}
+
+	user, err := repo.GetByID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(UserErrorResponse{
+			Error:   "failed to upload " + kind,
+			Message: err.Error(),
+		})
+	}
+
+	path, err := utils.SaveUpload(c, file, userID, utils.ImageTypes, utils.MaxProfileImageSize())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+			Error:   "failed to upload " + kind,
+			Message: err.Error(),
+		})
+	}
+
+	if err := repo.Update(userID, map[string]interface{}{kind + "_path": path}); err != nil {
+		log.Printf("[ERROR] Failed to save %s of user %d: %v", kind, userID, err)
+		_ = os.Remove(path)
+		return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+			Error:   "failed to upload " + kind,
+			Message: err.Error(),
+		})
+	}
+	removeOldProfileImage(profileImagePath(user, kind))
+
+	log.Printf("[INFO] User %d uploaded a new %s", userID, kind)
+	return respondWithProfile(c, repo, userID, "failed to upload "+kind)
+}
+
+func removeProfileImage(c *fiber.Ctx, repo repositories.UserRepositoryInterface, kind string) error {
+	userID := c.Locals("user_id").(uint)
+
+	user, err := repo.GetByID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(UserErrorResponse{
+			Error:   "failed to remove " + kind,
+			Message: err.Error(),
+		})
+	}
+
+	if oldPath := profileImagePath(user, kind); oldPath != "" {
+		if err := repo.Update(userID, map[string]interface{}{kind + "_path": ""}); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to remove " + kind,
+				Message: err.Error(),
+			})
+		}
+		removeOldProfileImage(oldPath)
+		log.Printf("[INFO] User %d removed the %s", userID, kind)
+	}
+
+	return respondWithProfile(c, repo, userID, "failed to remove "+kind)
+}
+
+func profileImagePath(user *models.User, kind string) string {
+	if kind == "avatar" {
+		return user.AvatarPath
+	}
+	return user.BannerPath
+}
+
+// The profile already points to the new image, so a file that can not be removed is only logged
+func removeOldProfileImage(path string) {
+	if path == "" {
+		return
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Printf("[ERROR] Failed to remove old profile image %s: %v", path, err)
+	}
+}
+
+func respondWithProfile(c *fiber.Ctx, repo repositories.UserRepositoryInterface, userID uint, failure string) error {
+	user, err := repo.GetByID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(UserErrorResponse{
+			Error:   failure,
+			Message: err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(user)
+}
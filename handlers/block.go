@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"errors"
+	"golang_task/repositories"
+	"log"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// BlockMessageResponse represents the response of block and mute actions
+type BlockMessageResponse struct {
+	Message string `json:"message" example:"user blocked"`
+}
+
+// GetBlocked godoc
+// @Summary Get blocked users
+// @Description Get the users the authenticated user has blocked, most recent first
+// @Tags Blocks
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} UserSummary
+// @Failure 400 {object} ErrorResponse
+// @Router /blocks [get]
+func GetBlocked(repo repositories.BlockRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		users, err := repo.ListBlocked(userID)
+		if err != nil {
+			log.Printf("[ERROR] Failed to get blocked users of user %d: %v", userID, err)
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "failed to get blocked users",
+				Message: err.Error(),
+			})
+		}
+
+		results := make([]UserSummary, 0, len(users))
+		for _, user := range users {
+			results = append(results, UserSummary{
+				ID:         user.ID,
+				Username:   user.Username,
+				Firstname:  user.Firstname,
+				Lastname:   user.Lastname,
+				AvatarPath: user.AvatarPath,
+			})
+		}
+		return c.Status(fiber.StatusOK).JSON(results)
+	}
+}
+
+// BlockUser godoc
+// @Summary Block a user
+// @Description Block a user. Follows between the two users are removed in both directions, their posts leave each other's timelines and they can no longer follow each other or see each other's posts and profiles.
+// @Tags Blocks
+// @Produce json
+// @Param user_id path int true "ID of the user to block"
+// @Security ApiKeyAuth
+// @Success 200 {object} BlockMessageResponse "user blocked"
+// @Failure 400 {object} ErrorResponse "invalid user id, yourself or already blocked"
+// @Failure 404 {object} ErrorResponse "user not found"
+// @Router /blocks/{user_id} [post]
+func BlockUser(repo repositories.BlockRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return blockAction(c, "block", repo.Block, "user blocked")
+	}
+}
+
+// UnblockUser godoc
+// @Summary Unblock a user
+// @Description Lift a block. Follows removed by the block are not restored.
+// @Tags Blocks
+// @Produce json
+// @Param user_id path int true "ID of the user to unblock"
+// @Security ApiKeyAuth
+// @Success 200 {object} BlockMessageResponse "user unblocked"
+// @Failure 400 {object} ErrorResponse "invalid user id or not blocked"
+// @Router /blocks/{user_id} [delete]
+func UnblockUser(repo repositories.BlockRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return blockAction(c, "unblock", repo.Unblock, "user unblocked")
+	}
+}
+
+// GetMuted godoc
+// @Summary Get muted users
+// @Description Get the users the authenticated user has muted, most recent first
+// @Tags Blocks
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} UserSummary
+// @Failure 400 {object} ErrorResponse
+// @Router /mutes [get]
+func GetMuted(repo repositories.BlockRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		users, err := repo.ListMuted(userID)
+		if err != nil {
+			log.Printf("[ERROR] Failed to get muted users of user %d: %v", userID, err)
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "failed to get muted users",
+				Message: err.Error(),
+			})
+		}
+
+		results := make([]UserSummary, 0, len(users))
+		for _, user := range users {
+			results = append(results, UserSummary{
+				ID:         user.ID,
+				Username:   user.Username,
+				Firstname:  user.Firstname,
+				Lastname:   user.Lastname,
+				AvatarPath: user.AvatarPath,
+			})
+		}
+		return c.Status(fiber.StatusOK).JSON(results)
+	}
+}
+
+// MuteUser godoc
+// @Summary Mute a user
+// @Description Hide the posts of a user from the authenticated user's timeline. The muted user is not told and can still follow and see the authenticated user.
+// @Tags Blocks
+// @Produce json
+// @Param user_id path int true "ID of the user to mute"
+// @Security ApiKeyAuth
+// @Success 200 {object} BlockMessageResponse "user muted"
+// @Failure 400 {object} ErrorResponse "invalid user id, yourself or already muted"
+// @Failure 404 {object} ErrorResponse "user not found"
+// @Router /mutes/{user_id} [post]
+func MuteUser(repo repositories.BlockRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return blockAction(c, "mute", repo.Mute, "user muted")
+	}
+}
+
+// UnmuteUser godoc
+// @Summary Unmute a user
+// @Description Show the posts of a muted user in the timeline again
+// @Tags Blocks
+// @Produce json
+// @Param user_id path int true "ID of the user to unmute"
+// @Security ApiKeyAuth
+// @Success 200 {object} BlockMessageResponse "user unmuted"
+// @Failure 400 {object} ErrorResponse "invalid user id or not muted"
+// @Router /mutes/{user_id} [delete]
+func UnmuteUser(repo repositories.BlockRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return blockAction(c, "unmute", repo.Unmute, "user unmuted")
+	}
+}
+
+func blockAction(c *fiber.Ctx, action string, apply func(userID, otherID uint) error, message string) error {
+	userID := c.Locals("user_id").(uint)
+
+	otherID, err := strconv.ParseUint(c.Params("user_id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error:   "failed to " + action + " user",
+			Message: "invalid user id",
+		})
+	}
+
+	if err := apply(userID, uint(otherID)); err != nil {
+		log.Printf("[ERROR] User %d failed to %s user %d: %v", userID, action, otherID, err)
+		status := fiber.StatusBadRequest
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			status = fiber.StatusNotFound
+		}
+		return c.Status(status).JSON(ErrorResponse{
+			Error:   "failed to " + action + " user",
+			Message: err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(BlockMessageResponse{
+		Message: message,
+	})
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"golang_task/models"
+	"golang_task/repositories"
+	"golang_task/utils"
+	"log"
+	"net/url"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ExportStatusResponse represents an export job, the download link is set once the archive is ready
+type ExportStatusResponse struct {
+	Job               *models.ExportJob `json:"job"`
+	DownloadURL       string            `json:"download_url,omitempty" example:"http://localhost:3001/users/export/download?token=abc"`
+	DownloadExpiresIn int64             `json:"download_expires_in,omitempty" example:"900"`
+}
+
+// RequestExportHandler godoc
+// @Summary Export my data
+// @Description Start building a ZIP archive with the profile, posts, media, followers and followings of the authenticated user. Poll the status endpoint for the download link.
+// @Tags Users
+// @Produce json
+// @Success 202 {object} models.ExportJob "Export started"
+// @Failure 400 {object} UserErrorResponse "Failed to start export"
+// @Security ApiKeyAuth
+// @Router /users/me/export [post]
+func RequestExportHandler(exportRepo repositories.ExportRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		job, err := exportRepo.Request(userID)
+		if err != nil {
+			log.Printf("[ERROR] Failed to start export for user %d: %v", userID, err)
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to start export",
+				Message: err.Error(),
+			})
+		}
+
+		return c.Status(fiber.StatusAccepted).JSON(job)
+	}
+}
+
+// GetExportHandler godoc
+// @Summary Get my export
+// @Description Get the status of an export job. Every call on a finished export returns a new one-time download link.
+// @Tags Users
+// @Produce json
+// @Param id path int true "Export ID"
+// @Success 200 {object} ExportStatusResponse
+// @Failure 400 {object} UserErrorResponse "Invalid export id"
+// @Failure 404 {object} UserErrorResponse "Export not found"
+// @Security ApiKeyAuth
+// @Router /users/me/export/{id} [get]
+func GetExportHandler(exportRepo repositories.ExportRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		jobID, err := strconv.ParseUint(c.Params("id"), 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to get export",
+				Message: "invalid export id",
+			})
+		}
+
+		job, err := exportRepo.GetForUser(uint(jobID), userID)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(UserErrorResponse{
+				Error:   "failed to get export",
+				Message: err.Error(),
+			})
+		}
+
+		response := ExportStatusResponse{Job: job}
+		token, err := exportRepo.CreateDownloadToken(job)
+		if err == nil {
+			response.DownloadURL = fmt.Sprintf("%s/users/export/download?token=%s", utils.AppBaseURL(), url.QueryEscape(token))
+			response.DownloadExpiresIn = int64(repositories.ExportLinkTTL().Seconds())
+		} else if !errors.Is(err, repositories.ErrExportNotReady) {
+			log.Printf("[ERROR] Failed to create download link for export %d: %v", job.ID, err)
+		}
+
+		return c.Status(fiber.StatusOK).JSON(response)
+	}
+}
+
+// DownloadExportHandler godoc
+// @Summary Download export
+// @Description Download a data archive with the one-time link from the status endpoint
+// @Tags Users
+// @Produce application/zip
+// @Param token query string true "Download token"
+// @Success 200 {file} file "ZIP archive"
+// @Failure 404 {object} UserErrorResponse "Link is invalid or has expired"
+// @Router /users/export/download [get]
+func DownloadExportHandler(exportRepo repositories.ExportRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		job, err := exportRepo.ConsumeDownloadToken(c.Query("token"))
+		if err != nil {
+			status := fiber.StatusBadRequest
+			if errors.Is(err, repositories.ErrInvalidDownloadToken) {
+				status = fiber.StatusNotFound
+			}
+			return c.Status(status).JSON(UserErrorResponse{
+				Error:   "failed to download export",
+				Message: err.Error(),
+			})
+		}
+
+		log.Printf("[INFO] User %d downloaded export %d", job.UserID, job.ID)
+		return c.Download(job.FilePath, fmt.Sprintf("export-%d.zip", job.ID))
+	}
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"strconv"
 
@@ -16,6 +17,10 @@ type FollowResponse struct {
 	Message string `json:"message" example:"followed successfully"`
 }
 
+type FollowRequestedResponse struct {
+	Message string `json:"message" example:"follow request sent"`
+}
+
 type UnfollowResponse struct {
 	Message string `json:"message" example:"unfollowed successfully"`
 }
@@ -30,7 +35,7 @@ type FollowErrorResponse struct {
 // @Description Get all followers of the authenticated user
 // @Tags Follow
 // @Security ApiKeyAuth
-// @Success 200 {array} models.User
+// @Success 200 {array} models.PublicUser
 // @Failure 400 {object} FollowErrorResponse
 // @Router /follows/followers [get]
 func GetFollowers(repo repositories.FollowRepositoryInterface) fiber.Handler {
@@ -55,7 +60,7 @@ func GetFollowers(repo repositories.FollowRepositoryInterface) fiber.Handler {
 // @Description Get all users the authenticated user is following
 // @Tags Follow
 // @Security ApiKeyAuth
-// @Success 200 {array} models.User
+// @Success 200 {array} models.PublicUser
 // @Failure 400 {object} FollowErrorResponse
 // @Router /follows/following [get]
 func GetFollowing(repo repositories.FollowRepositoryInterface) fiber.Handler {
@@ -72,7 +77,7 @@ func GetFollowing(repo repositories.FollowRepositoryInterface) fiber.Handler {
 			})
 		}
 		if len(following) == 0 {
-			following = []models.User{}
+			following = []models.PublicUser{}
 		}
 		log.Printf("[INFO] User %d followings fetched successfully, count=%d", userID, len(following))
 
@@ -82,12 +87,13 @@ func GetFollowing(repo repositories.FollowRepositoryInterface) fiber.Handler {
 
 // Follow godoc
 // @Summary Follow a user
-// @Description Follow another user. User cannot follow themselves and following must exist.
+// @Description Follow another user. User cannot follow themselves and following must exist. Following a private account sends a follow request that the account has to approve.
 // @Tags Follow
 // @Accept json
 // @Produce json
 // @Param following_id path int true "ID of the user to follow"
 // @Success 200 {object} FollowResponse "followed successfully"
+// @Success 202 {object} FollowRequestedResponse "follow request sent to a private account"
 // @Failure 400 {object} FollowErrorResponse "validation error or user not found or trying to follow yourself"
 // @Failure 404 {object} FollowErrorResponse "follower not found"
 // @Router /follows/{following_id} [post]
@@ -112,7 +118,8 @@ func Follow(repo repositories.FollowRepositoryInterface) fiber.Handler {
 			})
 		}
 
-		if err := repo.Follow(userID, followingId); err != nil {
+		requested, err := repo.Follow(userID, followingId)
+		if err != nil {
 			log.Printf("[ERROR] User %d failed to follow user %d: %v", userID, followingId, err)
 
 			var statusCode int
@@ -126,6 +133,11 @@ func Follow(repo repositories.FollowRepositoryInterface) fiber.Handler {
 				Message: err.Error(),
 			})
 		}
+		if requested {
+			return c.Status(fiber.StatusAccepted).JSON(FollowRequestedResponse{
+				Message: "follow request sent",
+			})
+		}
 		log.Printf("[INFO] User %d followed user %d successfully", userID, followingId)
 		return c.Status(fiber.StatusOK).JSON(FollowResponse{
 			Message: "followed successfully",
@@ -135,7 +147,7 @@ func Follow(repo repositories.FollowRepositoryInterface) fiber.Handler {
 
 // UnFollow godoc
 // @Summary Follow a user
-// @Description UnFollow another user. User cannot Unfollow themselves and following must exist.
+// @Description UnFollow another user. User cannot Unfollow themselves and following must exist. A pending follow request to the user is canceled.
 // @Tags Follow
 // @Accept json
 // @Produce json
@@ -181,3 +193,90 @@ func Unfollow(repo repositories.FollowRepositoryInterface) fiber.Handler {
 		})
 	}
 }
+
+// GetFollowRequests godoc
+// @Summary Get follow requests
+// @Description Get the pending follow requests of the authenticated user, oldest first
+// @Tags Follow
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.FollowRequest
+// @Failure 400 {object} FollowErrorResponse
+// @Router /follows/requests [get]
+func GetFollowRequests(repo repositories.FollowRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		requests, err := repo.ListRequests(userID)
+		if err != nil {
+			log.Printf("[ERROR] Failed to get follow requests for user %d: %v", userID, err)
+			return c.Status(fiber.StatusBadRequest).JSON(FollowErrorResponse{
+				Error:   "failed to get follow requests",
+				Message: err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusOK).JSON(requests)
+	}
+}
+
+// ApproveFollowRequest godoc
+// @Summary Approve a follow request
+// @Description Approve a pending follow request, the requester starts following the authenticated user
+// @Tags Follow
+// @Produce json
+// @Param id path int true "Follow request ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} FollowResponse "follow request approved"
+// @Failure 400 {object} FollowErrorResponse "invalid request id"
+// @Failure 404 {object} FollowErrorResponse "follow request not found"
+// @Router /follows/requests/{id}/approve [post]
+func ApproveFollowRequest(repo repositories.FollowRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return answerFollowRequest(c, "approve", repo.ApproveRequest)
+	}
+}
+
+// RejectFollowRequest godoc
+// @Summary Reject a follow request
+// @Description Reject a pending follow request of the authenticated user
+// @Tags Follow
+// @Produce json
+// @Param id path int true "Follow request ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} FollowResponse "follow request rejected"
+// @Failure 400 {object} FollowErrorResponse "invalid request id"
+// @Failure 404 {object} FollowErrorResponse "follow request not found"
+// @Router /follows/requests/{id}/reject [post]
+func RejectFollowRequest(repo repositories.FollowRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return answerFollowRequest(c, "reject", repo.RejectRequest)
+	}
+}
+
+func answerFollowRequest(c *fiber.Ctx, action string, answer func(id, targetID uint) error) error {
+	userID := c.Locals("user_id").(uint)
+
+	requestID, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(FollowErrorResponse{
+			Error:   "invalid request id",
+			Message: err.Error(),
+		})
+	}
+
+	if err := answer(uint(requestID), userID); err != nil {
+		log.Printf("[ERROR] User %d failed to %s follow request %d: %v", userID, action, requestID, err)
+		statusCode := fiber.StatusBadRequest
+		if errors.Is(err, repositories.ErrFollowRequestNotFound) {
+			statusCode = fiber.StatusNotFound
+		}
+		return c.Status(statusCode).JSON(FollowErrorResponse{
+			Error:   "failed to " + action + " follow request",
+			Message: err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(FollowResponse{
+		Message: "follow request " + action + "d",
+	})
+}
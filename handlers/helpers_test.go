@@ -0,0 +1,12 @@
+package handlers
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// The keyring is loaded once per process, so the secret is set before any test runs
+	os.Setenv("JWT_SECRET", "handlers-test-secret")
+	os.Exit(m.Run())
+}
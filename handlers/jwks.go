@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"golang_task/utils"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// JwksResponse represents the JSON Web Key Set document
+type JwksResponse struct {
+	Keys []utils.Jwk `json:"keys"`
+}
+
+// JwksHandler godoc
+// @Summary JSON Web Key Set
+// @Description Public keys (EdDSA and RS256) that other services can use to verify our tokens. HS256 keys are never published.
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} JwksResponse
+// @Failure 500 {object} ErrorResponse "Keyring is not configured"
+// @Router /.well-known/jwks.json [get]
+func JwksHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		keyring, err := utils.GetJwtKeyring()
+		if err != nil {
+			log.Printf("[ERROR] Failed to load jwt keyring: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+				Error:   "failed to load keys",
+				Message: err.Error(),
+			})
+		}
+
+		c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+		return c.JSON(JwksResponse{
+			Keys: keyring.Jwks(),
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"golang_task/repositories"
+	"golang_task/utils"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// MfaCodeRequest represents a request carrying a TOTP code or a recovery code
+type MfaCodeRequest struct {
+	Code string `json:"code" example:"123456"`
+}
+
+// MfaDisableRequest represents the request body for turning two-factor authentication off
+type MfaDisableRequest struct {
+	Password string `json:"password" example:"password123"`
+	Code     string `json:"code" example:"123456"`
+}
+
+// MfaLoginRequest represents the second step of a login with two-factor authentication
+type MfaLoginRequest struct {
+	MfaToken string `json:"mfa_token" example:"jwt_mfa_pending_token"`
+	Code     string `json:"code" example:"123456"`
+}
+
+// MfaConfirmResponse represents the response after enabling two-factor authentication
+type MfaConfirmResponse struct {
+	Message       string   `json:"message" example:"two-factor authentication enabled"`
+	RecoveryCodes []string `json:"recovery_codes" example:"abcd2345-efgh6789"`
+}
+
+// MfaSetupHandler godoc
+// @Summary Start two-factor setup
+// @Description Create a TOTP secret for the authenticated user. Two-factor authentication is enforced only after it is confirmed.
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} repositories.MfaSetup
+// @Failure 400 {object} UserErrorResponse "Two-factor authentication is already enabled"
+// @Security ApiKeyAuth
+// @Router /users/me/2fa/setup [post]
+func MfaSetupHandler(mfaRepo repositories.MfaRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		setup, err := mfaRepo.BeginSetup(userID)
+		if err != nil {
+			log.Printf("[ERROR] Failed to start two-factor setup for user %d: %v", userID, err)
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to setup two-factor authentication",
+				Message: err.Error(),
+			})
+		}
+
+		return c.Status(fiber.StatusOK).JSON(setup)
+	}
+}
+
+// MfaConfirmHandler godoc
+// @Summary Confirm two-factor setup
+// @Description Enable two-factor authentication with a code from the authenticator app. Returns one-time recovery codes, they are shown only once.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param input body MfaCodeRequest true "TOTP code"
+// @Success 200 {object} MfaConfirmResponse
+// @Failure 400 {object} UserErrorResponse "Code is invalid or setup was not started"
+// @Security ApiKeyAuth
+// @Router /users/me/2fa/confirm [post]
+func MfaConfirmHandler(mfaRepo repositories.MfaRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		var input MfaCodeRequest
+		if err := utils.BodyParse(c, &input); err != nil {
+			return err
+		}
+
+		codes, err := mfaRepo.ConfirmSetup(userID, input.Code)
+		if err != nil {
+			log.Printf("[ERROR] Failed to confirm two-factor setup for user %d: %v", userID, err)
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to enable two-factor authentication",
+				Message: err.Error(),
+			})
+		}
+
+		return c.Status(fiber.StatusOK).JSON(MfaConfirmResponse{
+			Message:       "two-factor authentication enabled",
+			RecoveryCodes: codes,
+		})
+	}
+}
+
+// MfaDisableHandler godoc
+// @Summary Disable two-factor authentication
+// @Description Turn two-factor authentication off. Needs the password and a TOTP code or a recovery code.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param input body MfaDisableRequest true "Password and code"
+// @Success 200 {object} UserMessageResponse "Two-factor authentication disabled"
+// @Failure 400 {object} UserErrorResponse "Code is invalid"
+// @Failure 401 {object} UserErrorResponse "Password is wrong"
+// @Security ApiKeyAuth
+// @Router /users/me/2fa/disable [post]
+func MfaDisableHandler(repo repositories.UserRepositoryInterface, mfaRepo repositories.MfaRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		var input MfaDisableRequest
+		if err := utils.BodyParse(c, &input); err != nil {
+			return err
+		}
+
+		user, err := repo.GetByID(userID)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to disable two-factor authentication",
+				Message: err.Error(),
+			})
+		}
+		if err := utils.CheckPasswordHash(input.Password, user.Password); err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(UserErrorResponse{
+				Error:   "unauthorized",
+				Message: "password is wrong",
+			})
+		}
+
+		if err := mfaRepo.Disable(userID, input.Code); err != nil {
+			log.Printf("[ERROR] Failed to disable two-factor authentication for user %d: %v", userID, err)
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to disable two-factor authentication",
+				Message: err.Error(),
+			})
+		}
+
+		return c.Status(fiber.StatusOK).JSON(UserMessageResponse{
+			Message: "two-factor authentication disabled",
+		})
+	}
+}
+
+// MfaLoginHandler godoc
+// @Summary Complete login with two-factor code
+// @Description Exchange the mfa_token from /users/login and a TOTP code or recovery code for an access token and a refresh token
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param input body MfaLoginRequest true "MFA token and code"
+// @Success 200 {object} UserLoginResponse "User Logged in successfully"
+// @Failure 401 {object} UserErrorResponse "Token or code is invalid"
+// @Failure 429 {object} UserErrorResponse "Too many failed attempts, see Retry-After header"
+// @Router /users/login/2fa [post]
+func MfaLoginHandler(mfaRepo repositories.MfaRepositoryInterface, tokenRepo repositories.TokenRepositoryInterface, attemptRepo repositories.LoginAttemptRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var input MfaLoginRequest
+		if err := utils.BodyParse(c, &input); err != nil {
+			return err
+		}
+
+		claims, err := utils.VerifyJwt(input.MfaToken)
+		if err != nil || claims.Type != utils.MfaPendingTokenType {
+			return c.Status(fiber.StatusUnauthorized).JSON(UserErrorResponse{
+				Error:   "unauthorized",
+				Message: "mfa token is invalid or has expired",
+			})
+		}
+		revoked, err := tokenRepo.IsRevoked(claims)
+		if err != nil || revoked {
+			return c.Status(fiber.StatusUnauthorized).JSON(UserErrorResponse{
+				Error:   "unauthorized",
+				Message: "mfa token is invalid or has expired",
+			})
+		}
+
+		// Codes are short, so they share the lockout of the password step
+		account := repositories.LoginAccountKey(claims.UserID, "")
+		retryAfter, err := attemptRepo.Check(account, c.IP())
+		if err != nil {
+			log.Printf("[ERROR] Failed to check login lockout for %s: %v", account, err)
+			return c.Status(fiber.StatusServiceUnavailable).JSON(UserErrorResponse{
+				Error:   "login unavailable",
+				Message: "please try again later",
+			})
+		}
+		if retryAfter > 0 {
+			return tooManyLoginAttempts(c, retryAfter)
+		}
+
+		if err := mfaRepo.Verify(claims.UserID, input.Code); err != nil {
+			log.Printf("[ERROR] Two-factor login failed for user %d: %v", claims.UserID, err)
+			retryAfter, recordErr := attemptRepo.RecordFailure(account, c.IP())
+			if recordErr != nil {
+				log.Printf("[ERROR] Failed to record login failure for %s: %v", account, recordErr)
+			}
+			if retryAfter > 0 {
+				return tooManyLoginAttempts(c, retryAfter)
+			}
+			return c.Status(fiber.StatusUnauthorized).JSON(UserErrorResponse{
+				Error:   "unauthorized",
+				Message: err.Error(),
+			})
+		}
+		if err := attemptRepo.Reset(account); err != nil {
+			log.Printf("[ERROR] Failed to reset login failures for %s: %v", account, err)
+		}
+
+		// The pending token must not be used for a second login
+		if err := tokenRepo.Revoke(claims); err != nil {
+			log.Printf("[ERROR] Failed to revoke mfa token of user %d: %v", claims.UserID, err)
+		}
+
+		tokens, err := tokenRepo.IssuePair(claims.UserID, clientInfo(c))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to create JWT",
+				Message: err.Error(),
+			})
+		}
+
+		log.Printf("[INFO] User %d logged in with two-factor authentication", claims.UserID)
+		return c.Status(fiber.StatusOK).JSON(UserLoginResponse{
+			Message:      "user logged in successfully",
+			Token:        tokens.AccessToken,
+			RefreshToken: tokens.RefreshToken,
+			ExpiresIn:    tokens.ExpiresIn,
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/json"
+	"golang_task/internal/testutil"
+	"golang_task/models"
+	"golang_task/repositories"
+	"golang_task/utils"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var resetToken = regexp.MustCompile(`\?token=(\S+)`)
+
+// Sends a JSON body and decodes the JSON answer into out
+func postJSON(t *testing.T, app *fiber.App, path, body string, out interface{}) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != nil {
+		json.NewDecoder(resp.Body).Decode(out)
+	}
+	return resp.StatusCode
+}
+
+func TestMfaLoginWorksAfterPasswordReset(t *testing.T) {
+	db := testutil.NewDB(t)
+	rdb := testutil.NewRedis(t)
+	repo := repositories.NewUserRepository(db, rdb)
+	tokenRepo := repositories.NewTokenRepository(db, rdb)
+	mfaRepo := repositories.NewMfaRepository(db, rdb, utils.SystemClock{})
+	attemptRepo := repositories.NewLoginAttemptRepository(rdb, repositories.LoginAttemptPolicyFromEnv())
+	mailer := utils.NewMemoryMailer()
+
+	app := fiber.New()
+	app.Post("/users/login", LoginHandler(repo, tokenRepo, attemptRepo))
+	app.Post("/users/login/2fa", MfaLoginHandler(mfaRepo, tokenRepo, attemptRepo))
+	app.Post("/users/password/forgot", ForgotPasswordHandler(repo, repositories.NewPasswordResetRepository(rdb), mailer))
+	app.Post("/users/password/reset", ResetPasswordHandler(repo, repositories.NewPasswordResetRepository(rdb), tokenRepo, utils.PasswordPolicy{MinLength: 8}))
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := utils.HashPassword("old password 123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := models.User{
+		Firstname:   "John",
+		Lastname:    "Doe",
+		Username:    "johndoe",
+		Email:       "john@example.com",
+		Password:    hash,
+		TOTPSecret:  secret,
+		TOTPEnabled: true,
+	}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// A login started before the reset must not be finished after it
+	var stale UserLoginResponse
+	if status := postJSON(t, app, "/users/login", `{"email":"john@example.com","password":"old password 123"}`, &stale); status != fiber.StatusOK || stale.MfaToken == "" {
+		t.Fatalf("login before reset: status %d, mfa token %q", status, stale.MfaToken)
+	}
+
+	if status := postJSON(t, app, "/users/password/forgot", `{"email":"john@example.com"}`, nil); status != fiber.StatusOK {
+		t.Fatalf("forgot status = %d, want %d", status, fiber.StatusOK)
+	}
+	sent := mailer.Sent()
+	if len(sent) != 1 {
+		t.Fatalf("sent %d mails, want 1", len(sent))
+	}
+	match := resetToken.FindStringSubmatch(sent[0].Body)
+	if match == nil {
+		t.Fatalf("no reset link in mail body:\n%s", sent[0].Body)
+	}
+	if status := postJSON(t, app, "/users/password/reset", `{"token":"`+match[1]+`","password":"new password 456"}`, nil); status != fiber.StatusOK {
+		t.Fatalf("reset status = %d, want %d", status, fiber.StatusOK)
+	}
+
+	var login UserLoginResponse
+	if status := postJSON(t, app, "/users/login", `{"email":"john@example.com","password":"new password 456"}`, &login); status != fiber.StatusOK {
+		t.Fatalf("login status = %d, want %d", status, fiber.StatusOK)
+	}
+	if !login.MfaRequired || login.MfaToken == "" || login.Token != "" {
+		t.Fatalf("login did not ask for the second factor: %+v", login)
+	}
+
+	code, err := utils.TOTPCode(secret, utils.TOTPStep(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := postJSON(t, app, "/users/login/2fa", `{"mfa_token":"`+stale.MfaToken+`","code":"`+code+`"}`, nil); status != fiber.StatusUnauthorized {
+		t.Fatalf("mfa token from before the reset: status %d, want %d", status, fiber.StatusUnauthorized)
+	}
+
+	var tokens UserLoginResponse
+	if status := postJSON(t, app, "/users/login/2fa", `{"mfa_token":"`+login.MfaToken+`","code":"`+code+`"}`, &tokens); status != fiber.StatusOK {
+		t.Fatalf("2fa status = %d, want %d (%s)", status, fiber.StatusOK, tokens.Message)
+	}
+	if tokens.Token == "" || tokens.RefreshToken == "" {
+		t.Fatal("2fa login returned no tokens")
+	}
+}
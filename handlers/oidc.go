@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"errors"
+	"golang_task/repositories"
+	"golang_task/utils"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// OIDCLoginHandler godoc
+// @Summary Log in with a provider
+// @Description Redirect to an OpenID Connect provider (e.g. google or the corporate SSO) configured in OIDC_PROVIDERS. The provider sends the user back to the callback.
+// @Tags Users
+// @Param provider path string true "Provider name"
+// @Success 302 "Redirect to the provider"
+// @Failure 404 {object} UserErrorResponse "Unknown provider"
+// @Failure 503 {object} UserErrorResponse "Provider unavailable"
+// @Router /users/oidc/{provider}/login [get]
+func OIDCLoginHandler(providers map[string]*utils.OIDCProvider, identityRepo repositories.IdentityRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		provider, ok := providers[c.Params("provider")]
+		if !ok {
+			return c.Status(fiber.StatusNotFound).JSON(UserErrorResponse{
+				Error:   "failed to start login",
+				Message: utils.ErrUnknownOIDCProvider.Error(),
+			})
+		}
+
+		var login repositories.OIDCLoginState
+		state, err := utils.NewOIDCSecret()
+		if err == nil {
+			login.Nonce, err = utils.NewOIDCSecret()
+		}
+		if err == nil {
+			login.CodeVerifier, err = utils.NewOIDCSecret()
+		}
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to start login",
+				Message: err.Error(),
+			})
+		}
+		login.Provider = provider.Name
+
+		authURL, err := provider.AuthCodeURL(state, login.Nonce, login.CodeVerifier)
+		if err != nil {
+			log.Printf("[ERROR] Login provider %s is unavailable: %v", provider.Name, err)
+			return c.Status(fiber.StatusServiceUnavailable).JSON(UserErrorResponse{
+				Error:   "failed to start login",
+				Message: "login provider is unavailable",
+			})
+		}
+		if err := identityRepo.SaveState(state, login); err != nil {
+			log.Printf("[ERROR] Failed to store login state for %s: %v", provider.Name, err)
+			return c.Status(fiber.StatusServiceUnavailable).JSON(UserErrorResponse{
+				Error:   "failed to start login",
+				Message: "please try again later",
+			})
+		}
+
+		return c.Redirect(authURL, fiber.StatusFound)
+	}
+}
+
+// OIDCCallbackHandler godoc
+// @Summary Provider login callback
+// @Description The provider redirects here after the user signed in. The account is created on the first login. Answers like /users/login.
+// @Tags Users
+// @Produce json
+// @Param provider path string true "Provider name"
+// @Param code query string true "Authorization code"
+// @Param state query string true "State from the login redirect"
+// @Success 200 {object} UserLoginResponse
+// @Failure 400 {object} UserErrorResponse "Login expired or provider answer is invalid"
+// @Failure 403 {object} UserErrorResponse "Account is suspended"
+// @Failure 404 {object} UserErrorResponse "Unknown provider"
+// @Failure 409 {object} UserErrorResponse "Email belongs to another account"
+// @Router /users/oidc/{provider}/callback [get]
+func OIDCCallbackHandler(providers map[string]*utils.OIDCProvider, identityRepo repositories.IdentityRepositoryInterface, tokenRepo repositories.TokenRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		provider, ok := providers[c.Params("provider")]
+		if !ok {
+			return c.Status(fiber.StatusNotFound).JSON(UserErrorResponse{
+				Error:   "failed to log in",
+				Message: utils.ErrUnknownOIDCProvider.Error(),
+			})
+		}
+
+		// The provider reports a cancelled or refused login with an error parameter
+		if providerErr := c.Query("error"); providerErr != "" {
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to log in",
+				Message: providerErr,
+			})
+		}
+
+		login, err := identityRepo.ConsumeState(c.Query("state"))
+		if err != nil || login.Provider != provider.Name {
+			if err == nil {
+				err = repositories.ErrInvalidOIDCState
+			}
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to log in",
+				Message: err.Error(),
+			})
+		}
+
+		tokens, err := provider.Exchange(c.Query("code"), login.CodeVerifier)
+		if err != nil {
+			log.Printf("[ERROR] Code exchange with %s failed: %v", provider.Name, err)
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to log in",
+				Message: "login provider refused the code",
+			})
+		}
+		claims, err := provider.VerifyIDToken(tokens.IDToken, login.Nonce)
+		if err != nil {
+			log.Printf("[ERROR] Invalid id token from %s: %v", provider.Name, err)
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to log in",
+				Message: utils.ErrInvalidIDToken.Error(),
+			})
+		}
+
+		user, created, err := identityRepo.Resolve(provider.Name, claims)
+		if err != nil {
+			status := fiber.StatusBadRequest
+			if errors.Is(err, repositories.ErrIdentityEmailTaken) {
+				status = fiber.StatusConflict
+			}
+			return c.Status(status).JSON(UserErrorResponse{
+				Error:   "failed to log in",
+				Message: err.Error(),
+			})
+		}
+		if created {
+			log.Printf("[INFO] User %d signed up with %s", user.ID, provider.Name)
+		}
+
+		return completeLogin(c, user, tokenRepo)
+	}
+}
@@ -0,0 +1,422 @@
+package handlers
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"golang_task/internal/testutil"
+	"golang_task/models"
+	"golang_task/repositories"
+	"golang_task/utils"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	mockClientID     = "test-client"
+	mockClientSecret = "test-secret"
+	mockKeyID        = "mock-key"
+	mockRedirectURL  = "http://localhost:3001/users/oidc/mock/callback"
+)
+
+// What the mock provider remembers about an authorization code
+type mockAuthorization struct {
+	challenge   string
+	nonce       string
+	redirectURI string
+}
+
+// mockOIDCProvider is a minimal OpenID Connect provider with discovery, JWKS, authorize and token endpoints
+//
+// Codes are single use and the token endpoint checks the PKCE verifier like a real provider.
+type mockOIDCProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]mockAuthorization
+	// Changes the claims of the ID tokens it issues
+	claims func(claims map[string]interface{})
+	// Token requests and the ones refused because of a wrong PKCE verifier
+	tokenRequests int
+	pkceFailures  int
+}
+
+func newMockOIDCProvider(t *testing.T) *mockOIDCProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := &mockOIDCProvider{key: key, codes: map[string]mockAuthorization{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", provider.discovery)
+	mux.HandleFunc("/jwks", provider.jwks)
+	mux.HandleFunc("/authorize", provider.authorize)
+	mux.HandleFunc("/token", provider.token)
+	provider.server = httptest.NewServer(mux)
+	t.Cleanup(provider.server.Close)
+	return provider
+}
+
+func (p *mockOIDCProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeMockJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 p.server.URL,
+		"authorization_endpoint": p.server.URL + "/authorize",
+		"token_endpoint":         p.server.URL + "/token",
+		"jwks_uri":               p.server.URL + "/jwks",
+	})
+}
+
+func (p *mockOIDCProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	writeMockJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []utils.Jwk{{
+			Kty: "RSA",
+			Kid: mockKeyID,
+			Alg: utils.AlgRS256,
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	})
+}
+
+// Signs the user in right away and sends them back with a code
+func (p *mockOIDCProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != mockClientID || query.Get("code_challenge_method") != "S256" {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	code := fmt.Sprintf("code-%d", time.Now().UnixNano())
+	p.mu.Lock()
+	p.codes[code] = mockAuthorization{
+		challenge:   query.Get("code_challenge"),
+		nonce:       query.Get("nonce"),
+		redirectURI: query.Get("redirect_uri"),
+	}
+	p.mu.Unlock()
+
+	callback := query.Get("redirect_uri") + "?" + url.Values{"code": {code}, "state": {query.Get("state")}}.Encode()
+	http.Redirect(w, r, callback, http.StatusFound)
+}
+
+func (p *mockOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.tokenRequests++
+
+	if err := r.ParseForm(); err != nil {
+		writeMockJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	authorization, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	switch {
+	case r.PostForm.Get("grant_type") != "authorization_code", !ok:
+		writeMockJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	case r.PostForm.Get("client_id") != mockClientID, r.PostForm.Get("client_secret") != mockClientSecret:
+		writeMockJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	case r.PostForm.Get("redirect_uri") != authorization.redirectURI:
+		writeMockJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	case utils.PKCEChallenge(r.PostForm.Get("code_verifier")) != authorization.challenge:
+		p.pkceFailures++
+		writeMockJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss":            p.server.URL,
+		"sub":            "mock-user-1",
+		"aud":            mockClientID,
+		"nonce":          authorization.nonce,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"email":          "jane@example.com",
+		"email_verified": true,
+		"given_name":     "Jane",
+		"family_name":    "Doe",
+	}
+	if p.claims != nil {
+		p.claims(claims)
+	}
+
+	writeMockJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "mock-access-token",
+		"token_type":   "Bearer",
+		"id_token":     p.sign(claims),
+		"expires_in":   3600,
+	})
+}
+
+func (p *mockOIDCProvider) sign(claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": utils.AlgRS256, "kid": mockKeyID, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		panic(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func writeMockJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+type oidcTestEnv struct {
+	app      *fiber.App
+	db       *gorm.DB
+	provider *mockOIDCProvider
+	// Talks to the mock provider without following its redirect back to us
+	browser *http.Client
+}
+
+func newOIDCTestEnv(t *testing.T) *oidcTestEnv {
+	t.Helper()
+	provider := newMockOIDCProvider(t)
+	db := testutil.NewDB(t)
+	rdb := testutil.NewRedis(t)
+
+	providers := map[string]*utils.OIDCProvider{
+		"mock": {
+			Name:         "mock",
+			Issuer:       provider.server.URL,
+			ClientID:     mockClientID,
+			ClientSecret: mockClientSecret,
+			RedirectURL:  mockRedirectURL,
+			HTTPClient:   provider.server.Client(),
+		},
+	}
+	identityRepo := repositories.NewIdentityRepository(db, rdb)
+	tokenRepo := repositories.NewTokenRepository(db, rdb)
+
+	app := fiber.New()
+	app.Get("/users/oidc/:provider/login", OIDCLoginHandler(providers, identityRepo))
+	app.Get("/users/oidc/:provider/callback", OIDCCallbackHandler(providers, identityRepo, tokenRepo))
+
+	return &oidcTestEnv{
+		app:      app,
+		db:       db,
+		provider: provider,
+		browser: &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}},
+	}
+}
+
+// Starts a login and returns the provider URL we are redirected to
+func (e *oidcTestEnv) startLogin(t *testing.T) *url.URL {
+	t.Helper()
+	resp, err := e.app.Test(httptest.NewRequest(http.MethodGet, "/users/oidc/mock/login", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusFound {
+		t.Fatalf("login status = %d, want %d", resp.StatusCode, fiber.StatusFound)
+	}
+	authURL, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(authURL.String(), e.provider.server.URL+"/authorize") {
+		t.Fatalf("redirected to %s, not to the provider", authURL)
+	}
+	return authURL
+}
+
+// Signs in at the provider and returns the callback path it sends the user back to
+func (e *oidcTestEnv) authorize(t *testing.T, authURL *url.URL) string {
+	t.Helper()
+	resp, err := e.browser.Get(authURL.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || resp.StatusCode != http.StatusFound {
+		t.Fatalf("provider did not redirect back: status %d, %v", resp.StatusCode, err)
+	}
+	return callback.RequestURI()
+}
+
+func (e *oidcTestEnv) callback(t *testing.T, path string) (int, UserLoginResponse) {
+	t.Helper()
+	resp, err := e.app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body UserLoginResponse
+	json.NewDecoder(resp.Body).Decode(&body)
+	return resp.StatusCode, body
+}
+
+func (e *oidcTestEnv) userCount(t *testing.T) int64 {
+	t.Helper()
+	var count int64
+	if err := e.db.Model(&models.User{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestOIDCLoginCreatesUserAndIssuesTokens(t *testing.T) {
+	env := newOIDCTestEnv(t)
+
+	authURL := env.startLogin(t)
+	query := authURL.Query()
+	for _, param := range []string{"state", "nonce", "code_challenge"} {
+		if query.Get(param) == "" {
+			t.Errorf("authorization request has no %s", param)
+		}
+	}
+	if query.Get("redirect_uri") != mockRedirectURL {
+		t.Errorf("redirect_uri = %q, want %q", query.Get("redirect_uri"), mockRedirectURL)
+	}
+
+	callback := env.authorize(t, authURL)
+	status, body := env.callback(t, callback)
+	if status != fiber.StatusOK {
+		t.Fatalf("callback status = %d, want %d (%s)", status, fiber.StatusOK, body.Message)
+	}
+	if body.Token == "" || body.RefreshToken == "" {
+		t.Fatal("callback returned no tokens")
+	}
+
+	var identity models.Identity
+	if err := env.db.Preload("User").Where("provider = ? AND subject = ?", "mock", "mock-user-1").First(&identity).Error; err != nil {
+		t.Fatalf("identity was not linked: %v", err)
+	}
+	if identity.User.Email != "jane@example.com" {
+		t.Errorf("user email = %q, want jane@example.com", identity.User.Email)
+	}
+
+	// The state is used up, the same callback can not log in twice
+	if status, _ := env.callback(t, callback); status != fiber.StatusBadRequest {
+		t.Errorf("replayed callback status = %d, want %d", status, fiber.StatusBadRequest)
+	}
+
+	// A second login finds the same user
+	status, _ = env.callback(t, env.authorize(t, env.startLogin(t)))
+	if status != fiber.StatusOK {
+		t.Fatalf("second login status = %d, want %d", status, fiber.StatusOK)
+	}
+	if count := env.userCount(t); count != 1 {
+		t.Errorf("%d users after two logins, want 1", count)
+	}
+}
+
+func TestOIDCCallbackRejectsStateMismatch(t *testing.T) {
+	env := newOIDCTestEnv(t)
+
+	callback, err := url.Parse(env.authorize(t, env.startLogin(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := callback.Query()
+	query.Set("state", "forged-state")
+	callback.RawQuery = query.Encode()
+
+	if status, _ := env.callback(t, callback.RequestURI()); status != fiber.StatusBadRequest {
+		t.Fatalf("callback status = %d, want %d", status, fiber.StatusBadRequest)
+	}
+	if env.provider.tokenRequests != 0 {
+		t.Errorf("code was exchanged %d times for an unknown state", env.provider.tokenRequests)
+	}
+	if count := env.userCount(t); count != 0 {
+		t.Errorf("%d users created, want 0", count)
+	}
+}
+
+func TestOIDCCallbackRejectsNonceMismatch(t *testing.T) {
+	env := newOIDCTestEnv(t)
+	env.provider.claims = func(claims map[string]interface{}) {
+		claims["nonce"] = "nonce-of-another-login"
+	}
+
+	if status, _ := env.callback(t, env.authorize(t, env.startLogin(t))); status != fiber.StatusBadRequest {
+		t.Fatalf("callback status = %d, want %d", status, fiber.StatusBadRequest)
+	}
+	if count := env.userCount(t); count != 0 {
+		t.Errorf("%d users created, want 0", count)
+	}
+}
+
+func TestOIDCCallbackRejectsPKCEVerifierMismatch(t *testing.T) {
+	env := newOIDCTestEnv(t)
+
+	// The code is bound to a challenge whose verifier we do not have, as with a code injected from another login
+	authURL := env.startLogin(t)
+	query := authURL.Query()
+	query.Set("code_challenge", utils.PKCEChallenge("verifier-of-another-login"))
+	authURL.RawQuery = query.Encode()
+
+	if status, _ := env.callback(t, env.authorize(t, authURL)); status != fiber.StatusBadRequest {
+		t.Fatalf("callback status = %d, want %d", status, fiber.StatusBadRequest)
+	}
+	if env.provider.pkceFailures != 1 {
+		t.Errorf("provider refused %d verifiers, want 1", env.provider.pkceFailures)
+	}
+	if count := env.userCount(t); count != 0 {
+		t.Errorf("%d users created, want 0", count)
+	}
+}
+
+func TestOIDCCallbackRejectsInvalidIDTokenClaims(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		change func(claims map[string]interface{})
+	}{
+		{"wrong issuer", func(claims map[string]interface{}) { claims["iss"] = "https://attacker.example.com" }},
+		{"wrong audience", func(claims map[string]interface{}) { claims["aud"] = "another-client" }},
+		{"audience list without our client", func(claims map[string]interface{}) {
+			claims["aud"] = []string{"another-client", "third-client"}
+		}},
+		{"expired", func(claims map[string]interface{}) {
+			claims["iat"] = time.Now().Add(-2 * time.Hour).Unix()
+			claims["exp"] = time.Now().Add(-time.Hour).Unix()
+		}},
+		{"issued in the future", func(claims map[string]interface{}) {
+			claims["iat"] = time.Now().Add(time.Hour).Unix()
+			claims["exp"] = time.Now().Add(2 * time.Hour).Unix()
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			env := newOIDCTestEnv(t)
+			env.provider.claims = tc.change
+
+			status, body := env.callback(t, env.authorize(t, env.startLogin(t)))
+			if status != fiber.StatusBadRequest {
+				t.Fatalf("callback status = %d, want %d", status, fiber.StatusBadRequest)
+			}
+			if body.Token != "" {
+				t.Error("tokens were issued for an invalid id token")
+			}
+			if count := env.userCount(t); count != 0 {
+				t.Errorf("%d users created, want 0", count)
+			}
+		})
+	}
+}
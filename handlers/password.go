@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"golang_task/repositories"
+	"golang_task/utils"
+	"log"
+	"os"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ForgotPasswordRequest represents the request body for requesting a reset link
+type ForgotPasswordRequest struct {
+	Email string `json:"email" example:"john@example.com"`
+}
+
+// ResetPasswordRequest represents the request body for setting a new password
+type ResetPasswordRequest struct {
+	Token    string `json:"token" example:"reset_token_from_email"`
+	Password string `json:"password" example:"newpassword123"`
+}
+
+// Answers a rejected password with the reason, or 503 if the check itself failed
+func passwordPolicyResponse(c *fiber.Ctx, err error) error {
+	var policyErr *utils.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+			Error:   "invalid input",
+			Message: policyErr.Reason,
+		})
+	}
+	log.Printf("[ERROR] Failed to check password policy: %v", err)
+	return c.Status(fiber.StatusServiceUnavailable).JSON(UserErrorResponse{
+		Error:   "password check unavailable",
+		Message: "please try again later",
+	})
+}
+
+// Stores a new hash of the password when the stored one uses an older algorithm or settings
+//
+// The plain password is only known at login, so this is the only time hashes can be upgraded.
+func upgradePasswordHash(repo repositories.UserRepositoryInterface, userID uint, password, hash string) {
+	if !utils.PasswordNeedsRehash(hash) {
+		return
+	}
+	newHash, err := utils.HashPassword(password)
+	if err != nil {
+		log.Printf("[ERROR] Failed to rehash password of user %d: %v", userID, err)
+		return
+	}
+	if err := repo.Update(userID, map[string]interface{}{"password": newHash}); err != nil {
+		log.Printf("[ERROR] Failed to save rehashed password of user %d: %v", userID, err)
+		return
+	}
+	log.Printf("[INFO] Password hash of user %d upgraded", userID)
+}
+
+// ForgotPasswordHandler godoc
+// @Summary Request password reset
+// @Description Send a single-use password reset link to the email. The response is the same whether the email exists or not.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param input body ForgotPasswordRequest true "Account email"
+// @Success 200 {object} UserMessageResponse "Reset link sent if the account exists"
+// @Failure 400 {object} UserErrorResponse "Validation Error."
+// @Router /users/password/forgot [post]
+func ForgotPasswordHandler(repo repositories.UserRepositoryInterface, resetRepo repositories.PasswordResetRepositoryInterface, mailer utils.Mailer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var input ForgotPasswordRequest
+		if err := utils.BodyParse(c, &input); err != nil {
+			return err
+		}
+		if input.Email == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "invalid input",
+				Message: "email must be provided",
+			})
+		}
+
+		// Same answer for unknown emails so accounts can not be enumerated
+		response := UserMessageResponse{
+			Message: "if an account with this email exists, a reset link has been sent",
+		}
+
+		user, err := repo.GetByEmail(input.Email)
+		if err != nil {
+			return c.Status(fiber.StatusOK).JSON(response)
+		}
+		// An account being erased must not get a password back
+		if user.ErasingAt != nil {
+			log.Printf("[ERROR] Password reset requested for user %d while the account is being erased", user.ID)
+			return c.Status(fiber.StatusOK).JSON(response)
+		}
+
+		token, err := resetRepo.CreateToken(user.ID)
+		if err != nil {
+			log.Printf("[ERROR] Failed to create reset token for user %d: %v", user.ID, err)
+			return c.Status(fiber.StatusOK).JSON(response)
+		}
+
+		resetURL := os.Getenv("PASSWORD_RESET_URL")
+		if resetURL == "" {
+			resetURL = utils.AppBaseURL() + "/users/password/reset"
+		}
+		err = mailer.Send(utils.Mail{
+			To:      user.Email,
+			Subject: "Reset your password",
+			Body: fmt.Sprintf("Hi %s,\n\nUse the link below to choose a new password:\n\n%s?token=%s\n\nThe link can be used once and expires in %s. If you did not ask for it, ignore this email.\n",
+				user.Username, resetURL, token, repositories.PasswordResetTTL()),
+		})
+		if err != nil {
+			log.Printf("[ERROR] Failed to send reset email to user %d: %v", user.ID, err)
+		}
+
+		return c.Status(fiber.StatusOK).JSON(response)
+	}
+}
+
+// ResetPasswordHandler godoc
+// @Summary Reset password
+// @Description Set a new password with a reset token. The token works once and every existing session is logged out.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param input body ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} UserMessageResponse "Password changed successfully"
+// @Failure 400 {object} UserErrorResponse "Token is invalid or expired, or the password does not meet the policy"
+// @Failure 503 {object} UserErrorResponse "Existing sessions could not be logged out"
+// @Router /users/password/reset [post]
+func ResetPasswordHandler(repo repositories.UserRepositoryInterface, resetRepo repositories.PasswordResetRepositoryInterface, tokenRepo repositories.TokenRepositoryInterface, policy utils.PasswordPolicy) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var input ResetPasswordRequest
+		if err := utils.BodyParse(c, &input); err != nil {
+			return err
+		}
+		if input.Password == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "invalid input",
+				Message: "password must be provided",
+			})
+		}
+
+		userID, err := resetRepo.LookupToken(input.Token)
+		if err != nil {
+			log.Printf("[ERROR] Failed to reset password: %v", err)
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to reset password",
+				Message: err.Error(),
+			})
+		}
+		user, err := repo.GetByID(userID)
+		if err != nil || user.ErasingAt != nil {
+			log.Printf("[ERROR] Failed to reset password of user %d: account is gone or being erased", userID)
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to reset password",
+				Message: repositories.ErrInvalidResetToken.Error(),
+			})
+		}
+
+		// Checked before the token is used up, so a rejected password can be retried with the same link
+		if err := policy.Validate(input.Password, user.Username, user.Email, user.Firstname, user.Lastname); err != nil {
+			return passwordPolicyResponse(c, err)
+		}
+
+		if _, err := resetRepo.ConsumeToken(input.Token); err != nil {
+			log.Printf("[ERROR] Failed to reset password of user %d: %v", userID, err)
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to reset password",
+				Message: err.Error(),
+			})
+		}
+
+		hashedPassword, err := utils.HashPassword(input.Password)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to reset password",
+				Message: err.Error(),
+			})
+		}
+		if err := repo.Update(userID, map[string]interface{}{"password": hashedPassword}); err != nil {
+			log.Printf("[ERROR] Failed to save new password of user %d: %v", userID, err)
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to reset password",
+				Message: err.Error(),
+			})
+		}
+
+		// Whoever had the old password may still hold tokens
+		if err := tokenRepo.RevokeAllForUser(userID); err != nil {
+			log.Printf("[ERROR] Failed to revoke sessions of user %d after password reset: %v", userID, err)
+			return c.Status(fiber.StatusServiceUnavailable).JSON(UserErrorResponse{
+				Error:   "failed to reset password",
+				Message: "password was changed but existing sessions could not be logged out, please try again later",
+			})
+		}
+
+		log.Printf("[INFO] Password of user %d reset successfully", userID)
+		return c.Status(fiber.StatusOK).JSON(UserMessageResponse{
+			Message: "password changed successfully",
+		})
+	}
+}
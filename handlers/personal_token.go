@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"errors"
+	"golang_task/models"
+	"golang_task/repositories"
+	"golang_task/utils"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Default and longest lifetime of a personal access token in days
+const (
+	defaultPersonalTokenDays = 30
+	maxPersonalTokenDays     = 365
+)
+
+// CreatePersonalTokenRequest represents the request body for creating a personal access token
+type CreatePersonalTokenRequest struct {
+	Name          string   `json:"name" example:"deploy bot"`
+	Scopes        []string `json:"scopes" example:"posts:read,posts:write"`
+	ExpiresInDays int      `json:"expires_in_days" example:"30"`
+}
+
+// PersonalTokenResponse represents a personal access token without its secret
+type PersonalTokenResponse struct {
+	*models.PersonalAccessToken
+	Scopes []string `json:"scopes" example:"posts:read,posts:write"`
+}
+
+// CreatePersonalTokenResponse represents a new personal access token, the token is only shown once
+type CreatePersonalTokenResponse struct {
+	PersonalTokenResponse
+	Token string `json:"token" example:"pat_abc"`
+}
+
+func newPersonalTokenResponse(token *models.PersonalAccessToken) PersonalTokenResponse {
+	return PersonalTokenResponse{
+		PersonalAccessToken: token,
+		Scopes:              repositories.PersonalTokenScopes(token),
+	}
+}
+
+// ListPersonalTokensHandler godoc
+// @Summary List my personal access tokens
+// @Description List the personal access tokens of the authenticated user, newest first. Secrets are never shown again.
+// @Tags Users
+// @Produce json
+// @Success 200 {array} PersonalTokenResponse
+// @Failure 400 {object} UserErrorResponse "Failed to list tokens"
+// @Security ApiKeyAuth
+// @Router /users/me/tokens [get]
+func ListPersonalTokensHandler(personalTokenRepo repositories.PersonalTokenRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		tokens, err := personalTokenRepo.List(userID)
+		if err != nil {
+			log.Printf("[ERROR] Failed to list personal access tokens of user %d: %v", userID, err)
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to list tokens",
+				Message: err.Error(),
+			})
+		}
+
+		response := make([]PersonalTokenResponse, 0, len(tokens))
+		for i := range tokens {
+			response = append(response, newPersonalTokenResponse(&tokens[i]))
+		}
+		return c.Status(fiber.StatusOK).JSON(response)
+	}
+}
+
+// CreatePersonalTokenHandler godoc
+// @Summary Create a personal access token
+// @Description Create a token for scripts and integrations. Send it as "Authorization: Bearer pat_...". It can only call routes its scopes allow (profile:read, users:read, posts:read, posts:write, follows:read, follows:write) and expires after expires_in_days (default 30, at most 365). The token is only shown in this response.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param input body CreatePersonalTokenRequest true "Token name, scopes and lifetime"
+// @Success 201 {object} CreatePersonalTokenResponse "Token created"
+// @Failure 400 {object} UserErrorResponse "Invalid input"
+// @Security ApiKeyAuth
+// @Router /users/me/tokens [post]
+func CreatePersonalTokenHandler(personalTokenRepo repositories.PersonalTokenRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		var input CreatePersonalTokenRequest
+		if err := utils.BodyParse(c, &input); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "invalid input",
+				Message: err.Error(),
+			})
+		}
+
+		input.Name = strings.TrimSpace(input.Name)
+		if input.Name == "" || len(input.Name) > 100 {
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "invalid input",
+				Message: "name is required and can be at most 100 characters",
+			})
+		}
+		if len(input.Scopes) == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "invalid input",
+				Message: "at least one scope is required",
+			})
+		}
+		if input.ExpiresInDays == 0 {
+			input.ExpiresInDays = defaultPersonalTokenDays
+		}
+		if input.ExpiresInDays < 1 || input.ExpiresInDays > maxPersonalTokenDays {
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "invalid input",
+				Message: "expires_in_days must be between 1 and 365",
+			})
+		}
+
+		token, record, err := personalTokenRepo.Create(userID, input.Name, input.Scopes, time.Duration(input.ExpiresInDays)*24*time.Hour)
+		if err != nil {
+			if errors.Is(err, repositories.ErrInvalidScope) {
+				return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+					Error:   "invalid input",
+					Message: err.Error(),
+				})
+			}
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to create token",
+				Message: err.Error(),
+			})
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(CreatePersonalTokenResponse{
+			PersonalTokenResponse: newPersonalTokenResponse(record),
+			Token:                 token,
+		})
+	}
+}
+
+// DeletePersonalTokenHandler godoc
+// @Summary Delete a personal access token
+// @Description Delete a personal access token of the authenticated user, it stops working at once.
+// @Tags Users
+// @Produce json
+// @Param id path int true "Token ID"
+// @Success 200 {object} UserMessageResponse "Token deleted"
+// @Failure 400 {object} UserErrorResponse "Invalid token id"
+// @Failure 404 {object} UserErrorResponse "Token not found"
+// @Security ApiKeyAuth
+// @Router /users/me/tokens/{id} [delete]
+func DeletePersonalTokenHandler(personalTokenRepo repositories.PersonalTokenRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		tokenID, err := strconv.ParseUint(c.Params("id"), 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to delete token",
+				Message: "invalid token id",
+			})
+		}
+
+		if err := personalTokenRepo.Delete(uint(tokenID), userID); err != nil {
+			status := fiber.StatusBadRequest
+			if errors.Is(err, repositories.ErrPersonalTokenNotFound) {
+				status = fiber.StatusNotFound
+			}
+			return c.Status(status).JSON(UserErrorResponse{
+				Error:   "failed to delete token",
+				Message: err.Error(),
+			})
+		}
+
+		return c.Status(fiber.StatusOK).JSON(UserMessageResponse{
+			Message: "token deleted",
+		})
+	}
+}
@@ -1,16 +1,15 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"golang_task/models"
 	"golang_task/repositories"
 	"golang_task/utils"
 	"log"
 	"os"
-	"slices"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -18,8 +17,9 @@ import (
 type PostCreateInput struct {
 	Title     string `json:"title" example:"My first post"`
 	Content   string `json:"content" example:"Hello world!"`
-	MediaPath string `json:"media_path,omitempty" example:"/uploads/abc.png"`
-	AuthorID  uint   `json:"author_id" example:"1"`
+	// Set by the server, never read from the request
+	MediaPath string `json:"-" form:"-"`
+	AuthorID  uint   `json:"-" form:"-"`
 }
 
 // PostSuccessfullResponse represents successful creation response
@@ -69,76 +69,226 @@ func PostCreate(repo repositories.PostRepositoryInterface) fiber.Handler {
 		}
 	
 		if file != nil {
-			// Check file size
-		maxFileSize, err := strconv.ParseUint(os.Getenv("MAX_FILE_SIZE"), 10, 64)
-		if err != nil {
-			// Default file size
-			maxFileSize = 50
+			path, err := utils.SaveUpload(c, file, userID, utils.PostMediaTypes, utils.MaxFileSize())
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+					Error:   "failed to create post",
+					Message: err.Error(),
+				})
+			}
+			input.MediaPath = path
 		}
-		if file.Size > int64(maxFileSize*1024*1024) {
-
+				if err := utils.BodyParse(c, &input); err != nil {
+			log.Printf("[ERROR] Post creation failed for user %d: %v", userID, err)
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "failed to create post",
+				Message: err.Error(),
+			})
+		}
+		// Create Post model in db
+		var post = models.Post{
+			Title:     input.Title,
+			Content:   input.Content,
+			MediaPath: input.MediaPath,
+			AuthorID:  input.AuthorID,
+		}
+		log.Printf("[INFO] User %d is creating a post with title: %s", userID, input.Title)
+		if err := repo.Create(&post); err != nil {
+			log.Printf("[ERROR] Post creation failed for user %d: %v", userID, err)
 			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
 				Error:   "failed to create post",
-				Message: "file size exceeds 50MB",
+				Message: err.Error(),
 			})
 		}
+		log.Printf("[INFO] Post created successfully by user %d: post_id=%d", userID, post.ID)
+
+		return c.Status(fiber.StatusCreated).JSON(PostSuccessfullResponse{
+			Message: "post created successfully",
+		})
+	}
+}
 
-		// Check Prefixes
-		allowSuffix := []string{"png", "jpg", "jpeg", "gif", "bmp", "webp", "mp4", "mov", "avi", "mkv", "flv", "wmv", "webm"}
+// PostThreadResponse is one page of a conversation
+type PostThreadResponse struct {
+	Root       *models.Post  `json:"root"`
+	Replies    []models.Post `json:"replies"`
+	NextCursor string        `json:"next_cursor,omitempty" example:"42"`
+}
 
-		fileNameSplited := strings.Split(file.Filename, ".")
-		fileSuffix := fileNameSplited[len(fileNameSplited)-1]
-		if !slices.Contains(allowSuffix, fileSuffix) {
+// PostReply godoc
+// @Summary Reply to a post
+// @Description Reply to a post with optional media file. The reply joins the conversation of the post and is shown in the timelines of the author's followers.
+// @Tags Posts
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Post ID"
+// @Param title formData string false "Reply title"
+// @Param content formData string true "Reply content"
+// @Param media formData file false "Media file (image/video)"
+// @Success 201 {object} models.Post "Reply created"
+// @Failure 400 {object} ErrorResponse "Bad request or validation error"
+// @Failure 404 {object} ErrorResponse "Post not found"
+// @Failure 409 {object} ErrorResponse "Post was deleted"
+// @Security ApiKeyAuth
+// @Router /posts/{id}/replies [post]
+func PostReply(repo repositories.PostRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
 
+		postIdParams, err := strconv.ParseUint(c.Params("id"), 10, 64)
+		if err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
-				Error:   "failed to create post",
-				Message: "invalid file type",
+				Error:   "failed to reply",
+				Message: "invalid post id",
 			})
 		}
 
-		// Uploading file
-		filename := strings.ReplaceAll(fmt.Sprintf("%d_%d_%s", time.Now().Unix(), userID, file.Filename), " ", "-")
-
-		path := fmt.Sprintf("./uploads/%s", filename)
+		// Replying needs the same access as reading the post
+		parent, err := getSharedTarget(repo, uint(postIdParams), userID)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+				Error:   "failed to reply",
+				Message: "post not found",
+			})
+		}
+		if parent.TombstonedAt != nil {
+			return c.Status(fiber.StatusConflict).JSON(ErrorResponse{
+				Error:   "failed to reply",
+				Message: repositories.ErrPostDeleted.Error(),
+			})
+		}
 
-		if err := c.SaveFile(file, path); err != nil {
+		reply, err := postFromForm(c, userID)
+		if err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
-				Error:   "failed to create post",
+				Error:   "failed to reply",
+				Message: err.Error(),
+			})
+		}
+
+		if err := repo.CreateReply(parent, reply); err != nil {
+			if reply.MediaPath != "" {
+				_ = os.Remove(reply.MediaPath)
+			}
+			status := fiber.StatusBadRequest
+			if errors.Is(err, repositories.ErrPostDeleted) {
+				status = fiber.StatusConflict
+			}
+			return c.Status(status).JSON(ErrorResponse{
+				Error:   "failed to reply",
 				Message: err.Error(),
 			})
 		}
-		input.MediaPath = path
+		log.Printf("[INFO] User %d replied to post_id=%d: reply_id=%d", userID, parent.ID, reply.ID)
 
+		return c.Status(fiber.StatusCreated).JSON(reply)
+	}
+}
 
+// Reads the title, content and optional media of a reply or quote, the content is required
+func postFromForm(c *fiber.Ctx, userID uint) (*models.Post, error) {
+	var input PostCreateInput
+	if err := utils.BodyParse(c, &input); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(input.Content) == "" {
+		return nil, errors.New("content must be provided")
+	}
 
+	post := models.Post{
+		Title:    input.Title,
+		Content:  input.Content,
+		AuthorID: userID,
+	}
+	if file, err := c.FormFile("media"); err == nil {
+		path, err := utils.SaveUpload(c, file, userID, utils.PostMediaTypes, utils.MaxFileSize())
+		if err != nil {
+			return nil, err
 		}
-				if err := utils.BodyParse(c, &input); err != nil {
-			log.Printf("[ERROR] Post creation failed for user %d: %v", userID, err)
+		post.MediaPath = path
+	}
+	return &post, nil
+}
+
+// Replies, reactions and threads of a repost belong to the post it shares
+func getSharedTarget(repo repositories.PostRepositoryInterface, id, viewerID uint) (*models.Post, error) {
+	post, err := repo.GetVisibleByID(id, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if post.RepostOfID != nil && post.Original != nil {
+		return post.Original, nil
+	}
+	return post, nil
+}
+
+// PostThread godoc
+// @Summary Get the conversation of a post
+// @Description Get the first post of the conversation the post belongs to and its replies, oldest first. Replies are flattened, use parent_id to build the tree. Deleted posts that have replies are shown as tombstones without content.
+// @Tags Posts
+// @Produce json
+// @Param id path int true "Post ID, any post of the conversation"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Replies per page, at most 100" default(20)
+// @Success 200 {object} PostThreadResponse
+// @Failure 400 {object} ErrorResponse "Invalid post id or cursor"
+// @Failure 404 {object} ErrorResponse "Post not found"
+// @Security ApiKeyAuth
+// @Router /posts/{id}/thread [get]
+func PostThread(repo repositories.PostRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		postIdParams, err := strconv.ParseUint(c.Params("id"), 10, 64)
+		if err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
-				Error:   "failed to create post",
+				Error:   "failed to get thread",
+				Message: "invalid post id",
+			})
+		}
+		cursor, limit, err := utils.CursorQuery(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "failed to get thread",
 				Message: err.Error(),
 			})
 		}
-		// Create Post model in db
-		var post = models.Post{
-			Title:     input.Title,
-			Content:   input.Content,
-			MediaPath: input.MediaPath,
-			AuthorID:  input.AuthorID,
+
+		post, err := getSharedTarget(repo, uint(postIdParams), userID)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+				Error:   "failed to get thread",
+				Message: "post not found",
+			})
 		}
-		log.Printf("[INFO] User %d is creating a post with title: %s", userID, input.Title)
-		if err := repo.Create(&post); err != nil {
-			log.Printf("[ERROR] Post creation failed for user %d: %v", userID, err)
+		rootID := post.ID
+		if post.RootID != nil {
+			rootID = *post.RootID
+		}
+
+		root, replies, next, err := repo.GetThread(rootID, userID, cursor, limit)
+		if errors.Is(err, repositories.ErrPostNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+				Error:   "failed to get thread",
+				Message: "post not found",
+			})
+		}
+		if err != nil {
+			log.Printf("[ERROR] Failed to get thread of post %d for user %d: %v", rootID, userID, err)
 			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
-				Error:   "failed to create post",
+				Error:   "failed to get thread",
 				Message: err.Error(),
 			})
 		}
-		log.Printf("[INFO] Post created successfully by user %d: post_id=%d", userID, post.ID)
 
-		return c.Status(fiber.StatusCreated).JSON(PostSuccessfullResponse{
-			Message: "post created successfully",
-		})
+		response := PostThreadResponse{
+			Root:    root,
+			Replies: replies,
+		}
+		if next != 0 {
+			response.NextCursor = fmt.Sprint(next)
+		}
+		return c.JSON(response)
 	}
 }
 
@@ -199,7 +349,8 @@ func PostGetByID(repo repositories.PostRepositoryInterface) fiber.Handler {
 			})
 		}
 
-		post, err := repo.GetByID(uint(postIdParams))
+		userID := c.Locals("user_id").(uint)
+		post, err := repo.GetVisibleByID(uint(postIdParams), userID)
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
 				Error:   "failed to get post",
@@ -213,7 +364,7 @@ func PostGetByID(repo repositories.PostRepositoryInterface) fiber.Handler {
 
 // DeletePost godoc
 // @Summary Delete a post
-// @Description Delete a post by ID (only author can delete)
+// @Description Move a post to the trash by ID (only author can delete). It can be restored until it is purged after the retention period. A purged post with replies or quotes is kept as a tombstone without content so the conversation and quotes stay whole, reposts of it are removed. Deleting a repost removes it right away.
 // @Tags Posts
 // @Produce json
 // @Param id path int true "Post ID"
@@ -242,7 +393,6 @@ func DeletePost(repo repositories.PostRepositoryInterface) fiber.Handler {
 
 		// get post from db
 		post, err := repo.GetByID(uint(postIdParams))
-		postMediaPath := post.MediaPath
 		if err != nil {
 			log.Printf("[ERROR] Failed to delete by user %d: %s", userID, err.Error())
 
@@ -251,7 +401,6 @@ func DeletePost(repo repositories.PostRepositoryInterface) fiber.Handler {
 				Message: err.Error(),
 			})
 		}
-
 		err = repo.DeletePost(post, uint(userID))
 		if err != nil {
 			log.Printf("[ERROR] Failed to delete post_id=%d by user %d: %v", post.ID, userID, err)
@@ -262,7 +411,6 @@ func DeletePost(repo repositories.PostRepositoryInterface) fiber.Handler {
 		}
 
 		log.Printf("[INFO] Post deleted successfully post_id=%d by user %d", post.ID, userID)
-		_=	os.Remove(postMediaPath)
 		return c.JSON(PostSuccessfullResponse{
 			Message: "post deleted successfully",
 		})
@@ -291,7 +439,7 @@ func PostEdit(repo repositories.PostRepositoryInterface) fiber.Handler {
 		var input struct {
 			Title     string `json:"title,omitempty"`
 			Content   string `json:"content,omitempty"`
-			MediaPath string `json:"media_path,omitempty"`
+			MediaPath string `json:"-" form:"-"`
 		}
 
 		// Get Post id
@@ -312,67 +460,49 @@ func PostEdit(repo repositories.PostRepositoryInterface) fiber.Handler {
 			})
 		}
 
+		if post.TombstonedAt != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "failed to update post",
+				Message: repositories.ErrPostDeleted.Error(),
+			})
+		}
+		if post.RepostOfID != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "failed to update post",
+				Message: repositories.ErrRepostNotEditable.Error(),
+			})
+		}
+
 		// get user id from context
 		userID := c.Locals("user_id").(uint)
 
+		// Checked before any file is touched, so nobody can replace the media of someone else's post
+		if post.AuthorID != userID {
+			log.Printf("[ERROR] User %d is not the author of post_id=%d", userID, post.ID)
+			return c.Status(fiber.StatusForbidden).JSON(ErrorResponse{
+				Error:   "failed to update post",
+				Message: "you are not the author of this post",
+			})
+		}
+
 		// get media file
+		oldFilePath := post.MediaPath
 		file, err := c.FormFile("media")
 		if err == nil { // user send a file
-			oldFilePath := post.MediaPath
-
-			// Check file size
-			maxFileSize, err := strconv.ParseUint(os.Getenv("MAX_FILE_SIZE"), 10, 64)
+			path, err := utils.SaveUpload(c, file, userID, utils.PostMediaTypes, utils.MaxFileSize())
 			if err != nil {
-				fmt.Println(err.Error())
-				// Default file size
-				maxFileSize = 50
-			}
-			if file.Size > int64(maxFileSize*1024*1024) {
-				return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
-					Error:   "failed to update post",
-					Message: "file size exceeds 50MB",
-				})
-			}
-
-			// Check Prefixes
-			allowSuffix := []string{"png", "jpg", "jpeg", "gif", "bmp", "webp", "mp4", "mov", "avi", "mkv", "flv", "wmv", "webm"}
-
-			fileNameSplited := strings.Split(file.Filename, ".")
-			fileSuffix := fileNameSplited[len(fileNameSplited)-1]
-			if !slices.Contains(allowSuffix, fileSuffix) {
-				return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
-					Error:   "failed to update post",
-					Message: "invalid file type",
-				})
-			}
-
-			// Uploading file
-			filename := strings.ReplaceAll(fmt.Sprintf("%d_%d_%s", time.Now().Unix(), userID, file.Filename), " ", "-")
-
-			path := fmt.Sprintf("./uploads/%s", filename)
-
-			if err := c.SaveFile(file, path); err != nil {
 				return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
 					Error:   "failed to update post",
 					Message: err.Error(),
 				})
 			}
 			input.MediaPath = path
-
-			if oldFilePath != "" {
-				if err := os.Remove(oldFilePath); err != nil {
-					// remove new file
-					os.Remove(path)
-					return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
-						Error:   "failed to update post",
-						Message: err.Error(),
-					})
-				}
-			}
-
 		}
 
 		if err := utils.BodyParse(c, &input); err != nil {
+			if input.MediaPath != "" {
+				os.Remove(input.MediaPath)
+			}
 			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
 				Error:   "failed to update post",
 				Message: err.Error(),
@@ -381,6 +511,10 @@ func PostEdit(repo repositories.PostRepositoryInterface) fiber.Handler {
 		log.Printf("[INFO] User %d is editing post_id=%d", userID, post.ID)
 		if err := repo.UpdatePost(post, userID, input); err != nil {
 			log.Printf("[ERROR] Failed to update post_id=%d by user %d: %v", post.ID, userID, err)
+			// remove new file
+			if input.MediaPath != "" {
+				os.Remove(input.MediaPath)
+			}
 
 			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
 				Error:   "failed to update post",
@@ -389,6 +523,13 @@ func PostEdit(repo repositories.PostRepositoryInterface) fiber.Handler {
 
 		}
 
+		// The old media is only removed once the post points to the new one
+		if input.MediaPath != "" && utils.IsUploadPath(oldFilePath) {
+			if err := os.Remove(oldFilePath); err != nil && !os.IsNotExist(err) {
+				log.Printf("[ERROR] Failed to remove old media of post_id=%d: %v", post.ID, err)
+			}
+		}
+
 		log.Printf("[INFO] Post updated successfully post_id=%d by user %d", post.ID, userID)
 		return c.Status(fiber.StatusCreated).JSON(PostSuccessfullResponse{
 			Message: "post updated successfully",
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"errors"
+	"golang_task/models"
+	"golang_task/repositories"
+	"golang_task/utils"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// PostSearchResponse is one page of search results
+type PostSearchResponse struct {
+	Posts []models.Post `json:"posts"`
+	Page  int           `json:"page" example:"1"`
+	Limit int           `json:"limit" example:"20"`
+}
+
+// SearchPostsHandler godoc
+// @Summary Search posts
+// @Description Search the title and content of posts, most relevant first. All words must match; use "quotes" for a phrase and a trailing * for a prefix, for example go* "hello world".
+// @Tags Posts
+// @Produce json
+// @Param q query string true "Search query"
+// @Param author query string false "Username of the author"
+// @Param from query string false "Posts created on or after, YYYY-MM-DD or RFC 3339"
+// @Param to query string false "Posts created before, a date includes that whole day"
+// @Param has_media query bool false "Only posts with or without media"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Posts per page, at most 100" default(20)
+// @Success 200 {object} PostSearchResponse
+// @Failure 400 {object} ErrorResponse "Invalid query or filter"
+// @Security ApiKeyAuth
+// @Router /posts/search [get]
+func SearchPostsHandler(repo repositories.PostRepositoryInterface, userRepo repositories.UserRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		page, limit := utils.PageQuery(c)
+
+		query := repositories.ParsePostSearchQuery(c.Query("q"))
+		if query.IsEmpty() {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "failed to search posts",
+				Message: "q must have at least one word",
+			})
+		}
+		query.Offset = (page - 1) * limit
+		query.Limit = limit
+
+		response := PostSearchResponse{
+			Posts: []models.Post{},
+			Page:  page,
+			Limit: limit,
+		}
+
+		if username := strings.TrimPrefix(strings.TrimSpace(c.Query("author")), "@"); username != "" {
+			author, err := userRepo.GetByUsername(username)
+			if errors.Is(err, repositories.ErrUserNotFound) {
+				return c.JSON(response)
+			}
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+					Error:   "failed to search posts",
+					Message: err.Error(),
+				})
+			}
+			query.AuthorID = author.ID
+		}
+
+		var err error
+		if query.From, err = searchDate(c.Query("from"), false); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "failed to search posts",
+				Message: "invalid from date",
+			})
+		}
+		if query.To, err = searchDate(c.Query("to"), true); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "failed to search posts",
+				Message: "invalid to date",
+			})
+		}
+		if value := c.Query("has_media"); value != "" {
+			hasMedia, err := strconv.ParseBool(value)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+					Error:   "failed to search posts",
+					Message: "has_media must be true or false",
+				})
+			}
+			query.HasMedia = &hasMedia
+		}
+
+		posts, err := repo.Search(query, userID)
+		if err != nil {
+			log.Printf("[ERROR] Post search failed for user %d: %v", userID, err)
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "failed to search posts",
+				Message: err.Error(),
+			})
+		}
+		response.Posts = posts
+		return c.JSON(response)
+	}
+}
+
+// Reads a date or a time, a date given as the end of a range includes that day
+func searchDate(value string, end bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if date, err := time.Parse(time.DateOnly, value); err == nil {
+		if end {
+			date = date.AddDate(0, 0, 1)
+		}
+		return &date, nil
+	}
+	moment, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &moment, nil
+}
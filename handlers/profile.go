@@ -0,0 +1,575 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"golang_task/models"
+	"golang_task/repositories"
+	"golang_task/utils"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// UpdateProfileRequest represents the fields a user can change, missing fields are left as they are
+type UpdateProfileRequest struct {
+	FirstName *string `json:"first_name,omitempty" example:"John"`
+	LastName  *string `json:"last_name,omitempty" example:"Doe"`
+	Username  *string `json:"username,omitempty" example:"johndoe"`
+	Email     *string `json:"email,omitempty" example:"john@example.com"`
+	Bio       *string `json:"bio,omitempty" example:"Backend developer"`
+	Website   *string `json:"website,omitempty" example:"https://johndoe.dev"`
+	Location  *string `json:"location,omitempty" example:"Tehran"`
+	Pronouns  *string `json:"pronouns,omitempty" example:"he/him"`
+	IsPrivate *bool   `json:"is_private,omitempty" example:"true"`
+}
+
+// ChangePasswordRequest represents the request body for changing the password
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" example:"password123"`
+	NewPassword string `json:"new_password" example:"newpassword123"`
+}
+
+// DeleteAccountRequest represents the request body for deleting the account
+type DeleteAccountRequest struct {
+	Password string `json:"password" example:"password123"`
+}
+
+// GetProfileHandler godoc
+// @Summary Get my profile
+// @Description Get the profile of the authenticated user
+// @Tags Users
+// @Produce json
+// @Success 200 {object} models.User
+// @Failure 404 {object} UserErrorResponse "User not found"
+// @Security ApiKeyAuth
+// @Router /users/me [get]
+func GetProfileHandler(repo repositories.UserRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		user, err := repo.GetByID(userID)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(UserErrorResponse{
+				Error:   "failed to get profile",
+				Message: err.Error(),
+			})
+		}
+
+		return c.Status(fiber.StatusOK).JSON(user)
+	}
+}
+
+// UpdateProfileHandler godoc
+// @Summary Update my profile
+// @Description Update first name, last name, username, email, privacy, bio (160 characters), website (an http or https URL, 200 characters), location (100 characters) or pronouns (40 characters) of the authenticated user. Send an empty string to clear bio, website, location or pronouns. Changing the email requires verifying it again. Followers of a private account (is_private) must be approved, making it public approves every pending request.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param input body UpdateProfileRequest true "Fields to update"
+// @Success 200 {object} models.User
+// @Failure 400 {object} UserErrorResponse "Validation Error."
+// @Failure 409 {object} UserErrorResponse "Username or email already exists"
+// @Security ApiKeyAuth
+// @Router /users/me [patch]
+func UpdateProfileHandler(repo repositories.UserRepositoryInterface, followRepo repositories.FollowRepositoryInterface, mailer utils.Mailer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		var input UpdateProfileRequest
+		if err := utils.BodyParse(c, &input); err != nil {
+			return err
+		}
+
+		user, err := repo.GetByID(userID)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(UserErrorResponse{
+				Error:   "failed to update profile",
+				Message: err.Error(),
+			})
+		}
+
+		updates := map[string]interface{}{}
+		fields := []struct {
+			column string
+			value  *string
+		}{
+			{"firstname", input.FirstName},
+			{"lastname", input.LastName},
+			{"username", input.Username},
+			{"email", input.Email},
+		}
+		for _, field := range fields {
+			if field.value == nil {
+				continue
+			}
+			value := strings.TrimSpace(*field.value)
+			if value == "" {
+				return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+					Error:   "invalid input",
+					Message: field.column + " can not be empty",
+				})
+			}
+			updates[field.column] = value
+		}
+
+		// Optional fields, an empty string clears them
+		optionalFields := []struct {
+			column    string
+			value     *string
+			maxLength int
+		}{
+			{"bio", input.Bio, models.MaxBioLength},
+			{"website", input.Website, models.MaxWebsiteLength},
+			{"location", input.Location, models.MaxLocationLength},
+			{"pronouns", input.Pronouns, models.MaxPronounsLength},
+		}
+		for _, field := range optionalFields {
+			if field.value == nil {
+				continue
+			}
+			value := strings.TrimSpace(*field.value)
+			if utf8.RuneCountInString(value) > field.maxLength {
+				return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+					Error:   "invalid input",
+					Message: fmt.Sprintf("%s can be at most %d characters", field.column, field.maxLength),
+				})
+			}
+			updates[field.column] = value
+		}
+		if website, ok := updates["website"].(string); ok && website != "" && !validWebsite(website) {
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "invalid input",
+				Message: "website must be an http or https URL",
+			})
+		}
+
+		if input.IsPrivate != nil {
+			updates["is_private"] = *input.IsPrivate
+		}
+
+		emailChanged := input.Email != nil && updates["email"] != user.Email
+		if emailChanged {
+			updates["email_verified_at"] = nil
+		}
+
+		if len(updates) == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "invalid input",
+				Message: "nothing to update",
+			})
+		}
+
+		if err := repo.Update(userID, updates); err != nil {
+			log.Printf("[ERROR] Failed to update profile of user %d: %v", userID, err)
+			status := fiber.StatusBadRequest
+			if errors.Is(err, repositories.ErrUserAlreadyExists) {
+				status = fiber.StatusConflict
+			}
+			return c.Status(status).JSON(UserErrorResponse{
+				Error:   "failed to update profile",
+				Message: err.Error(),
+			})
+		}
+
+		user, err = repo.GetByID(userID)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(UserErrorResponse{
+				Error:   "failed to update profile",
+				Message: err.Error(),
+			})
+		}
+
+		if emailChanged {
+			if err := utils.SendVerificationEmail(mailer, user.ID, user.Email, user.Username); err != nil {
+				log.Printf("[ERROR] Failed to send verification email to user %d: %v", user.ID, err)
+			}
+		}
+
+		// Nobody has to be approved anymore once the account is public
+		if input.IsPrivate != nil && !*input.IsPrivate {
+			if err := followRepo.ApproveAllRequests(userID); err != nil {
+				log.Printf("[ERROR] Failed to approve follow requests of user %d: %v", userID, err)
+			}
+		}
+
+		log.Printf("[INFO] User %d updated profile", userID)
+		return c.Status(fiber.StatusOK).JSON(user)
+	}
+}
+
+// Websites are shown as links, so only absolute http and https URLs are accepted
+func validWebsite(website string) bool {
+	parsed, err := url.Parse(website)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+// ChangePasswordHandler godoc
+// @Summary Change my password
+// @Description Change the password of the authenticated user. Every other session is logged out and a new token pair is returned.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param input body ChangePasswordRequest true "Old and new password"
+// @Success 200 {object} UserLoginResponse "Password changed successfully"
+// @Failure 400 {object} UserErrorResponse "Validation Error or the new password does not meet the policy"
+// @Failure 401 {object} UserErrorResponse "Old password is wrong"
+// @Failure 503 {object} UserErrorResponse "Existing sessions could not be logged out"
+// @Security ApiKeyAuth
+// @Router /users/me/password [put]
+func ChangePasswordHandler(repo repositories.UserRepositoryInterface, tokenRepo repositories.TokenRepositoryInterface, policy utils.PasswordPolicy) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		var input ChangePasswordRequest
+		if err := utils.BodyParse(c, &input); err != nil {
+			return err
+		}
+		if input.NewPassword == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "invalid input",
+				Message: "new_password must be provided",
+			})
+		}
+
+		user, err := repo.GetByID(userID)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(UserErrorResponse{
+				Error:   "failed to change password",
+				Message: err.Error(),
+			})
+		}
+		if err := utils.CheckPasswordHash(input.OldPassword, user.Password); err != nil {
+			log.Printf("[ERROR] User %d sent a wrong old password", userID)
+			return c.Status(fiber.StatusUnauthorized).JSON(UserErrorResponse{
+				Error:   "unauthorized",
+				Message: "old password is wrong",
+			})
+		}
+
+		if err := policy.Validate(input.NewPassword, user.Username, user.Email, user.Firstname, user.Lastname); err != nil {
+			return passwordPolicyResponse(c, err)
+		}
+
+		hashedPassword, err := utils.HashPassword(input.NewPassword)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to change password",
+				Message: err.Error(),
+			})
+		}
+		if err := repo.Update(userID, map[string]interface{}{"password": hashedPassword}); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to change password",
+				Message: err.Error(),
+			})
+		}
+
+		// Log out everywhere, then give the current client a fresh login
+		if err := tokenRepo.RevokeAllForUser(userID); err != nil {
+			log.Printf("[ERROR] Failed to revoke sessions of user %d after password change: %v", userID, err)
+			return c.Status(fiber.StatusServiceUnavailable).JSON(UserErrorResponse{
+				Error:   "failed to change password",
+				Message: "password was changed but existing sessions could not be logged out, please try again later",
+			})
+		}
+		tokens, err := tokenRepo.IssuePair(userID, clientInfo(c))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to create JWT",
+				Message: err.Error(),
+			})
+		}
+
+		log.Printf("[INFO] User %d changed password", userID)
+		return c.Status(fiber.StatusOK).JSON(UserLoginResponse{
+			Message:      "password changed successfully",
+			Token:        tokens.AccessToken,
+			RefreshToken: tokens.RefreshToken,
+			ExpiresIn:    tokens.ExpiresIn,
+		})
+	}
+}
+
+// DeleteAccountHandler godoc
+// @Summary Delete my account
+// @Description Erase the authenticated user's account with their posts, media, follows and timelines. The password is required. The erasure runs in the background, every session is logged out immediately.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param input body DeleteAccountRequest true "Current password"
+// @Success 202 {object} models.ErasureJob "Erasure started"
+// @Failure 400 {object} UserErrorResponse "Failed to delete account"
+// @Failure 401 {object} UserErrorResponse "Password is wrong"
+// @Security ApiKeyAuth
+// @Router /users/me [delete]
+func DeleteAccountHandler(repo repositories.UserRepositoryInterface, tokenRepo repositories.TokenRepositoryInterface, erasureRepo repositories.ErasureRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		var input DeleteAccountRequest
+		if err := utils.BodyParse(c, &input); err != nil {
+			return err
+		}
+
+		user, err := repo.GetByID(userID)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(UserErrorResponse{
+				Error:   "failed to delete account",
+				Message: err.Error(),
+			})
+		}
+		if err := utils.CheckPasswordHash(input.Password, user.Password); err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(UserErrorResponse{
+				Error:   "unauthorized",
+				Message: "password is wrong",
+			})
+		}
+
+		job, err := erasureRepo.Request(userID, userID)
+		if err != nil {
+			log.Printf("[ERROR] Failed to start erasure of user %d: %v", userID, err)
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to delete account",
+				Message: err.Error(),
+			})
+		}
+		// The job does this too, but the caller must be logged out before the response
+		if err := tokenRepo.RevokeAllForUser(userID); err != nil {
+			log.Printf("[ERROR] Failed to revoke sessions of deleted user %d: %v", userID, err)
+		}
+
+		log.Printf("[INFO] User %d requested erasure of their account, job %d", userID, job.ID)
+		return c.Status(fiber.StatusAccepted).JSON(job)
+	}
+}
+
+// PublicProfile is what other users can see of an account
+type PublicProfile struct {
+	ID              uint      `json:"id" example:"1"`
+	Username        string    `json:"username" example:"johndoe"`
+	Firstname       string    `json:"firstname" example:"John"`
+	Lastname        string    `json:"lastname" example:"Doe"`
+	Bio             string    `json:"bio" example:"Backend developer"`
+	Website         string    `json:"website" example:"https://johndoe.dev"`
+	Location        string    `json:"location" example:"Tehran"`
+	Pronouns        string    `json:"pronouns" example:"he/him"`
+	IsPrivate       bool      `json:"is_private" example:"false"`
+	AvatarPath      string    `json:"avatar_path" example:"./uploads/1700000000_1_avatar.png"`
+	BannerPath      string    `json:"banner_path" example:"./uploads/1700000000_1_banner.png"`
+	CreatedAt       time.Time `json:"created_at"`
+	FollowersCount  int64     `json:"followers_count" example:"10"`
+	FollowingsCount int64     `json:"followings_count" example:"5"`
+	PostsCount      int64     `json:"posts_count" example:"42"`
+	IsFollowing     bool      `json:"is_following" example:"true"`
+}
+
+// UserSummary is a user in search results
+type UserSummary struct {
+	ID         uint   `json:"id" example:"1"`
+	Username   string `json:"username" example:"johndoe"`
+	Firstname  string `json:"firstname" example:"John"`
+	Lastname   string `json:"lastname" example:"Doe"`
+	AvatarPath string `json:"avatar_path" example:"./uploads/1700000000_1_avatar.png"`
+}
+
+// UserPostsResponse represents a page of a user's posts
+type UserPostsResponse struct {
+	Posts []models.Post `json:"posts"`
+	Page  int           `json:"page" example:"1"`
+	Limit int           `json:"limit" example:"20"`
+}
+
+// GetPublicProfileHandler godoc
+// @Summary Get user profile
+// @Description Get the public profile of a user with follower, following and post counts and whether the caller follows them
+// @Tags Users
+// @Produce json
+// @Param username path string true "Username"
+// @Success 200 {object} PublicProfile
+// @Failure 404 {object} UserErrorResponse "User not found"
+// @Security ApiKeyAuth
+// @Router /users/{username} [get]
+func GetPublicProfileHandler(repo repositories.UserRepositoryInterface, followRepo repositories.FollowRepositoryInterface, postRepo repositories.PostRepositoryInterface, blockRepo repositories.BlockRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		callerID := c.Locals("user_id").(uint)
+
+		user, err := getUnblockedUser(repo, blockRepo, callerID, c.Params("username"))
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(UserErrorResponse{
+				Error:   "failed to get profile",
+				Message: err.Error(),
+			})
+		}
+
+		profile := PublicProfile{
+			ID:         user.ID,
+			Username:   user.Username,
+			Firstname:  user.Firstname,
+			Lastname:   user.Lastname,
+			Bio:        user.Bio,
+			Website:    user.Website,
+			Location:   user.Location,
+			Pronouns:   user.Pronouns,
+			IsPrivate:  user.IsPrivate,
+			AvatarPath: user.AvatarPath,
+			BannerPath: user.BannerPath,
+			CreatedAt:  user.CreatedAt,
+		}
+		if err := fillProfileCounts(&profile, callerID, followRepo, postRepo); err != nil {
+			log.Printf("[ERROR] Failed to build profile of user %d: %v", user.ID, err)
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to get profile",
+				Message: err.Error(),
+			})
+		}
+
+		return c.Status(fiber.StatusOK).JSON(profile)
+	}
+}
+
+func fillProfileCounts(profile *PublicProfile, callerID uint, followRepo repositories.FollowRepositoryInterface, postRepo repositories.PostRepositoryInterface) error {
+	var err error
+	if profile.FollowersCount, err = followRepo.CountFollowers(profile.ID); err != nil {
+		return err
+	}
+	if profile.FollowingsCount, err = followRepo.CountFollowings(profile.ID); err != nil {
+		return err
+	}
+	if profile.PostsCount, err = postRepo.CountByAuthorID(profile.ID); err != nil {
+		return err
+	}
+	if callerID != profile.ID {
+		profile.IsFollowing, err = followRepo.IsFollowing(callerID, profile.ID)
+	}
+	return err
+}
+
+// Users who blocked each other can not see each other, a block looks like a missing account
+func getUnblockedUser(repo repositories.UserRepositoryInterface, blockRepo repositories.BlockRepositoryInterface, callerID uint, username string) (*models.User, error) {
+	user, err := repo.GetByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	blocked, err := blockRepo.IsBlockedEither(callerID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if blocked {
+		return nil, repositories.ErrUserNotFound
+	}
+	return user, nil
+}
+
+// GetUserPostsHandler godoc
+// @Summary Get user posts
+// @Description Get the posts of a user, newest first. Posts of private accounts are only shown to their followers.
+// @Tags Users
+// @Produce json
+// @Param username path string true "Username"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Posts per page, at most 100" default(20)
+// @Success 200 {object} UserPostsResponse
+// @Failure 403 {object} UserErrorResponse "Account is private"
+// @Failure 404 {object} UserErrorResponse "User not found"
+// @Security ApiKeyAuth
+// @Router /users/{username}/posts [get]
+func GetUserPostsHandler(repo repositories.UserRepositoryInterface, postRepo repositories.PostRepositoryInterface, followRepo repositories.FollowRepositoryInterface, blockRepo repositories.BlockRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		callerID := c.Locals("user_id").(uint)
+
+		username := c.Params("username")
+		user, err := getUnblockedUser(repo, blockRepo, callerID, username)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(UserErrorResponse{
+				Error:   "failed to get posts",
+				Message: err.Error(),
+			})
+		}
+
+		visible, err := followRepo.CanView(callerID, user.ID)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to get posts",
+				Message: err.Error(),
+			})
+		}
+		if !visible {
+			return c.Status(fiber.StatusForbidden).JSON(UserErrorResponse{
+				Error:   "failed to get posts",
+				Message: "this account is private, follow it to see its posts",
+			})
+		}
+
+		page, limit := utils.PageQuery(c)
+		posts, err := postRepo.GetByAuthorUsername(username, callerID, (page-1)*limit, limit)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to get posts",
+				Message: err.Error(),
+			})
+		}
+
+		return c.Status(fiber.StatusOK).JSON(UserPostsResponse{
+			Posts: posts,
+			Page:  page,
+			Limit: limit,
+		})
+	}
+}
+
+// SearchUsersHandler godoc
+// @Summary Search users
+// @Description Find users whose username, first name or last name starts with the query, for example to autocomplete @mentions. Exact matches come first, then prefix matches.
+// @Tags Users
+// @Produce json
+// @Param q query string true "Search query, a leading @ is ignored"
+// @Param limit query int false "Number of results, at most 100" default(10)
+// @Success 200 {array} UserSummary
+// @Failure 400 {object} UserErrorResponse "Query is missing"
+// @Security ApiKeyAuth
+// @Router /users/search [get]
+func SearchUsersHandler(repo repositories.UserRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		query := strings.TrimPrefix(strings.TrimSpace(c.Query("q")), "@")
+		if query == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "invalid input",
+				Message: "q must be provided",
+			})
+		}
+
+		limit := c.QueryInt("limit", 10)
+		if limit < 1 {
+			limit = 10
+		}
+		if limit > utils.MaxPageSize {
+			limit = utils.MaxPageSize
+		}
+
+		users, err := repo.Search(query, limit)
+		if err != nil {
+			log.Printf("[ERROR] Failed to search users for %q: %v", query, err)
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to search users",
+				Message: err.Error(),
+			})
+		}
+
+		results := make([]UserSummary, 0, len(users))
+		for _, user := range users {
+			results = append(results, UserSummary{
+				ID:         user.ID,
+				Username:   user.Username,
+				Firstname:  user.Firstname,
+				Lastname:   user.Lastname,
+				AvatarPath: user.AvatarPath,
+			})
+		}
+		return c.Status(fiber.StatusOK).JSON(results)
+	}
+}
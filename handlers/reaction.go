@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"errors"
+	"golang_task/models"
+	"golang_task/repositories"
+	"golang_task/utils"
+	"log"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ReactionRequest is the reaction to set
+type ReactionRequest struct {
+	Type string `json:"type" example:"like"`
+}
+
+// ReactionResponse has the reaction counts of a post after the change
+type ReactionResponse struct {
+	Reactions  map[string]int64 `json:"reactions"`
+	MyReaction string           `json:"my_reaction,omitempty" example:"like"`
+}
+
+// ReactToPostHandler godoc
+// @Summary React to a post
+// @Description Set your reaction to a post, an earlier reaction of yours is replaced. Types: like, love, laugh, wow, sad, angry.
+// @Tags Posts
+// @Accept json
+// @Produce json
+// @Param id path int true "Post ID"
+// @Param body body ReactionRequest true "Reaction"
+// @Success 200 {object} ReactionResponse
+// @Failure 400 {object} ErrorResponse "Invalid post id or reaction type"
+// @Failure 404 {object} ErrorResponse "Post not found"
+// @Failure 409 {object} ErrorResponse "Post was deleted"
+// @Security ApiKeyAuth
+// @Router /posts/{id}/reactions [post]
+func ReactToPostHandler(postRepo repositories.PostRepositoryInterface, reactionRepo repositories.ReactionRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		post, status, err := getReactablePost(postRepo, c.Params("id"), userID)
+		if err != nil {
+			return c.Status(status).JSON(ErrorResponse{
+				Error:   "failed to react",
+				Message: err.Error(),
+			})
+		}
+
+		var input ReactionRequest
+		if err := utils.BodyParse(c, &input); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "failed to react",
+				Message: err.Error(),
+			})
+		}
+
+		if err := reactionRepo.React(post.ID, userID, input.Type); err != nil {
+			log.Printf("[ERROR] User %d failed to react %q to post %d: %v", userID, input.Type, post.ID, err)
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "failed to react",
+				Message: err.Error(),
+			})
+		}
+
+		return reactionsResponse(c, reactionRepo, post, userID)
+	}
+}
+
+// RemoveReactionHandler godoc
+// @Summary Remove your reaction
+// @Description Remove your reaction to a post
+// @Tags Posts
+// @Produce json
+// @Param id path int true "Post ID"
+// @Success 200 {object} ReactionResponse
+// @Failure 400 {object} ErrorResponse "Invalid post id"
+// @Failure 404 {object} ErrorResponse "Post or reaction not found"
+// @Failure 409 {object} ErrorResponse "Post was deleted"
+// @Security ApiKeyAuth
+// @Router /posts/{id}/reactions [delete]
+func RemoveReactionHandler(postRepo repositories.PostRepositoryInterface, reactionRepo repositories.ReactionRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		post, status, err := getReactablePost(postRepo, c.Params("id"), userID)
+		if err != nil {
+			return c.Status(status).JSON(ErrorResponse{
+				Error:   "failed to remove reaction",
+				Message: err.Error(),
+			})
+		}
+
+		if err := reactionRepo.Unreact(post.ID, userID); err != nil {
+			status = fiber.StatusBadRequest
+			if errors.Is(err, repositories.ErrReactionNotFound) {
+				status = fiber.StatusNotFound
+			}
+			return c.Status(status).JSON(ErrorResponse{
+				Error:   "failed to remove reaction",
+				Message: err.Error(),
+			})
+		}
+
+		return reactionsResponse(c, reactionRepo, post, userID)
+	}
+}
+
+// Reactions need the same access as reading the post, a deleted post keeps no reactions
+//
+// On failure the status code to answer with is returned too.
+func getReactablePost(postRepo repositories.PostRepositoryInterface, idParam string, userID uint) (*models.Post, int, error) {
+	postID, err := strconv.ParseUint(idParam, 10, 64)
+	if err != nil {
+		return nil, fiber.StatusBadRequest, errors.New("invalid post id")
+	}
+
+	post, err := getSharedTarget(postRepo, uint(postID), userID)
+	if err != nil {
+		return nil, fiber.StatusNotFound, errors.New("post not found")
+	}
+	if post.TombstonedAt != nil {
+		return nil, fiber.StatusConflict, repositories.ErrPostDeleted
+	}
+	return post, fiber.StatusOK, nil
+}
+
+func reactionsResponse(c *fiber.Ctx, reactionRepo repositories.ReactionRepositoryInterface, post *models.Post, userID uint) error {
+	posts := []models.Post{*post}
+	if err := reactionRepo.Attach(posts, userID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Error:   "failed to get reactions",
+			Message: err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(ReactionResponse{
+		Reactions:  posts[0].Reactions,
+		MyReaction: posts[0].MyReaction,
+	})
+}
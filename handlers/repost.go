@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"errors"
+	"golang_task/repositories"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RepostHandler godoc
+// @Summary Repost a post
+// @Description Share a post as it is with your followers, reposting a repost shares the original post. Posts of private accounts can not be shared.
+// @Tags Posts
+// @Produce json
+// @Param id path int true "Post ID"
+// @Success 201 {object} models.Post "Repost created"
+// @Failure 400 {object} ErrorResponse "Invalid post id"
+// @Failure 403 {object} ErrorResponse "Post of a private account"
+// @Failure 404 {object} ErrorResponse "Post not found"
+// @Failure 409 {object} ErrorResponse "Already reposted or post was deleted"
+// @Security ApiKeyAuth
+// @Router /posts/{id}/repost [post]
+func RepostHandler(repo repositories.PostRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		postID, err := strconv.ParseUint(c.Params("id"), 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "failed to repost",
+				Message: "invalid post id",
+			})
+		}
+
+		original, err := repo.GetVisibleByID(uint(postID), userID)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+				Error:   "failed to repost",
+				Message: "post not found",
+			})
+		}
+
+		repost, err := repo.Repost(original, userID)
+		if err != nil {
+			return c.Status(shareErrorStatus(err)).JSON(ErrorResponse{
+				Error:   "failed to repost",
+				Message: err.Error(),
+			})
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(repost)
+	}
+}
+
+// UndoRepostHandler godoc
+// @Summary Undo a repost
+// @Description Remove your repost of a post, it is also removed from your followers' timelines
+// @Tags Posts
+// @Produce json
+// @Param id path int true "ID of the reposted post"
+// @Success 200 {object} PostSuccessfullResponse "Repost removed"
+// @Failure 400 {object} ErrorResponse "Invalid post id"
+// @Failure 404 {object} ErrorResponse "Post not found or not reposted"
+// @Security ApiKeyAuth
+// @Router /posts/{id}/repost [delete]
+func UndoRepostHandler(repo repositories.PostRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		postID, err := strconv.ParseUint(c.Params("id"), 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "failed to undo repost",
+				Message: "invalid post id",
+			})
+		}
+
+		// The repost can be undone even if the original is hidden from the user now
+		original, err := repo.GetByID(uint(postID))
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+				Error:   "failed to undo repost",
+				Message: "post not found",
+			})
+		}
+
+		if err := repo.Unrepost(original, userID); err != nil {
+			status := fiber.StatusBadRequest
+			if errors.Is(err, repositories.ErrNotReposted) {
+				status = fiber.StatusNotFound
+			}
+			return c.Status(status).JSON(ErrorResponse{
+				Error:   "failed to undo repost",
+				Message: err.Error(),
+			})
+		}
+
+		log.Printf("[INFO] User %d removed repost of post_id=%d", userID, original.ID)
+		return c.JSON(PostSuccessfullResponse{
+			Message: "repost removed successfully",
+		})
+	}
+}
+
+// QuotePostHandler godoc
+// @Summary Quote a post
+// @Description Share a post with your own commentary. When the quoted post is deleted the quote shows a tombstone in its place. Posts of private accounts can not be quoted.
+// @Tags Posts
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Post ID"
+// @Param title formData string false "Quote title"
+// @Param content formData string true "Quote content"
+// @Param media formData file false "Media file (image/video)"
+// @Success 201 {object} models.Post "Quote created"
+// @Failure 400 {object} ErrorResponse "Bad request or validation error"
+// @Failure 403 {object} ErrorResponse "Post of a private account"
+// @Failure 404 {object} ErrorResponse "Post not found"
+// @Failure 409 {object} ErrorResponse "Post was deleted"
+// @Security ApiKeyAuth
+// @Router /posts/{id}/quote [post]
+func QuotePostHandler(repo repositories.PostRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		postID, err := strconv.ParseUint(c.Params("id"), 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "failed to quote",
+				Message: "invalid post id",
+			})
+		}
+
+		original, err := repo.GetVisibleByID(uint(postID), userID)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+				Error:   "failed to quote",
+				Message: "post not found",
+			})
+		}
+
+		quote, err := postFromForm(c, userID)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "failed to quote",
+				Message: err.Error(),
+			})
+		}
+
+		if err := repo.Quote(original, quote); err != nil {
+			if quote.MediaPath != "" {
+				_ = os.Remove(quote.MediaPath)
+			}
+			return c.Status(shareErrorStatus(err)).JSON(ErrorResponse{
+				Error:   "failed to quote",
+				Message: err.Error(),
+			})
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(quote)
+	}
+}
+
+func shareErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, repositories.ErrCannotSharePrivate):
+		return fiber.StatusForbidden
+	case errors.Is(err, repositories.ErrPostDeleted), errors.Is(err, repositories.ErrAlreadyReposted):
+		return fiber.StatusConflict
+	default:
+		return fiber.StatusBadRequest
+	}
+}
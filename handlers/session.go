@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"golang_task/repositories"
+	"golang_task/utils"
+	"log"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ListSessionsHandler godoc
+// @Summary List my sessions
+// @Description List the devices the authenticated user is logged in on, most recently used first. The session of the current token is marked with current.
+// @Tags Users
+// @Produce json
+// @Success 200 {array} models.Session
+// @Failure 400 {object} UserErrorResponse "Failed to list sessions"
+// @Security ApiKeyAuth
+// @Router /users/me/sessions [get]
+func ListSessionsHandler(sessionRepo repositories.SessionRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		claims := c.Locals("token_claims").(*utils.JwtClaims)
+
+		sessions, err := sessionRepo.ListActive(userID)
+		if err != nil {
+			log.Printf("[ERROR] Failed to list sessions of user %d: %v", userID, err)
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to list sessions",
+				Message: err.Error(),
+			})
+		}
+		for i := range sessions {
+			sessions[i].Current = sessions[i].FamilyID == claims.FamilyID
+		}
+
+		return c.Status(fiber.StatusOK).JSON(sessions)
+	}
+}
+
+// RevokeSessionHandler godoc
+// @Summary Log out a session
+// @Description Revoke a session of the authenticated user, e.g. on a lost device. Its access and refresh tokens stop working at once.
+// @Tags Users
+// @Produce json
+// @Param id path int true "Session ID"
+// @Success 200 {object} UserMessageResponse "Session revoked"
+// @Failure 400 {object} UserErrorResponse "Invalid session id"
+// @Failure 404 {object} UserErrorResponse "Session not found"
+// @Security ApiKeyAuth
+// @Router /users/me/sessions/{id} [delete]
+func RevokeSessionHandler(sessionRepo repositories.SessionRepositoryInterface, tokenRepo repositories.TokenRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		sessionID, err := strconv.ParseUint(c.Params("id"), 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to revoke session",
+				Message: "invalid session id",
+			})
+		}
+
+		session, err := sessionRepo.GetForUser(uint(sessionID), userID)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(UserErrorResponse{
+				Error:   "failed to revoke session",
+				Message: err.Error(),
+			})
+		}
+
+		if err := tokenRepo.RevokeFamily(session.FamilyID); err != nil {
+			log.Printf("[ERROR] Failed to revoke session %d of user %d: %v", session.ID, userID, err)
+			return c.Status(fiber.StatusServiceUnavailable).JSON(UserErrorResponse{
+				Error:   "failed to revoke session",
+				Message: "please try again later",
+			})
+		}
+
+		log.Printf("[INFO] User %d revoked session %d", userID, session.ID)
+		return c.Status(fiber.StatusOK).JSON(UserMessageResponse{
+			Message: "session revoked",
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"fmt"
+	"golang_task/models"
+	"golang_task/repositories"
+	"golang_task/utils"
+	"log"
+	"net/url"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TagPostsResponse is one page of posts with a hashtag
+type TagPostsResponse struct {
+	Tag        string        `json:"tag" example:"golang"`
+	Posts      []models.Post `json:"posts"`
+	NextCursor string        `json:"next_cursor,omitempty" example:"42"`
+}
+
+// GetTagPostsHandler godoc
+// @Summary Get posts with a hashtag
+// @Description Get posts whose content has the hashtag, newest first. Tags are matched case-insensitively, a leading # is ignored.
+// @Tags Posts
+// @Produce json
+// @Param tag path string true "Hashtag"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Posts per page, at most 100" default(20)
+// @Success 200 {object} TagPostsResponse
+// @Failure 400 {object} ErrorResponse "Invalid tag or cursor"
+// @Security ApiKeyAuth
+// @Router /tags/{tag}/posts [get]
+func GetTagPostsHandler(repo repositories.PostRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		tag, err := url.PathUnescape(c.Params("tag"))
+		if err == nil {
+			tag = utils.NormalizeHashtag(tag)
+		}
+		if err != nil || tag == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "failed to get posts",
+				Message: "invalid tag",
+			})
+		}
+
+		cursor, limit, err := utils.CursorQuery(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "failed to get posts",
+				Message: err.Error(),
+			})
+		}
+
+		posts, next, err := repo.GetByHashtag(tag, userID, cursor, limit)
+		if err != nil {
+			log.Printf("[ERROR] Failed to get posts with hashtag %s for user %d: %v", tag, userID, err)
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "failed to get posts",
+				Message: err.Error(),
+			})
+		}
+
+		response := TagPostsResponse{
+			Tag:   tag,
+			Posts: posts,
+		}
+		if next != 0 {
+			response.NextCursor = fmt.Sprint(next)
+		}
+		return c.JSON(response)
+	}
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"errors"
+	"golang_task/models"
+	"golang_task/repositories"
+	"golang_task/utils"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TrashedPost is a post in the trash and when it was deleted
+type TrashedPost struct {
+	models.Post
+	DeletedAt time.Time `json:"deleted_at"`
+	// The post is deleted for good after this time
+	PurgeAt time.Time `json:"purge_at"`
+}
+
+// TrashResponse is one page of the trash
+type TrashResponse struct {
+	Posts []TrashedPost `json:"posts"`
+	Page  int           `json:"page" example:"1"`
+	Limit int           `json:"limit" example:"20"`
+}
+
+// GetTrashHandler godoc
+// @Summary Get your deleted posts
+// @Description Get your posts that are in the trash, the last deleted first. They can be restored until purge_at.
+// @Tags Posts
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Posts per page, at most 100" default(20)
+// @Success 200 {object} TrashResponse
+// @Failure 400 {object} ErrorResponse "Failed to get trash"
+// @Security ApiKeyAuth
+// @Router /posts/trash [get]
+func GetTrashHandler(repo repositories.PostRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		page, limit := utils.PageQuery(c)
+
+		posts, err := repo.GetTrash(userID, (page-1)*limit, limit)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "failed to get trash",
+				Message: err.Error(),
+			})
+		}
+
+		retention := repositories.PostTrashRetention()
+		trashed := make([]TrashedPost, 0, len(posts))
+		for _, post := range posts {
+			trashed = append(trashed, TrashedPost{
+				Post:      post,
+				DeletedAt: post.DeletedAt.Time,
+				PurgeAt:   post.DeletedAt.Time.Add(retention),
+			})
+		}
+
+		return c.JSON(TrashResponse{
+			Posts: trashed,
+			Page:  page,
+			Limit: limit,
+		})
+	}
+}
+
+// RestorePostHandler godoc
+// @Summary Restore a deleted post
+// @Description Take your post out of the trash, it goes back to your followers' timelines
+// @Tags Posts
+// @Produce json
+// @Param id path int true "Post ID"
+// @Success 200 {object} models.Post "Post restored"
+// @Failure 400 {object} ErrorResponse "Invalid post id"
+// @Failure 404 {object} ErrorResponse "Post not in the trash"
+// @Security ApiKeyAuth
+// @Router /posts/{id}/restore [post]
+func RestorePostHandler(repo repositories.PostRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		postID, err := strconv.ParseUint(c.Params("id"), 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Error:   "failed to restore post",
+				Message: "invalid post id",
+			})
+		}
+
+		post, err := repo.GetTrashedByID(uint(postID))
+		if err != nil {
+			status := fiber.StatusBadRequest
+			if errors.Is(err, repositories.ErrPostNotInTrash) {
+				status = fiber.StatusNotFound
+			}
+			return c.Status(status).JSON(ErrorResponse{
+				Error:   "failed to restore post",
+				Message: err.Error(),
+			})
+		}
+		// Others can not tell a post in the trash from a missing one
+		if post.AuthorID != userID {
+			return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+				Error:   "failed to restore post",
+				Message: repositories.ErrPostNotInTrash.Error(),
+			})
+		}
+
+		if err := repo.RestorePost(post, userID); err != nil {
+			status := fiber.StatusBadRequest
+			if errors.Is(err, repositories.ErrPostNotInTrash) {
+				status = fiber.StatusNotFound
+			}
+			return c.Status(status).JSON(ErrorResponse{
+				Error:   "failed to restore post",
+				Message: err.Error(),
+			})
+		}
+
+		restored, err := repo.GetVisibleByID(post.ID, userID)
+		if err != nil {
+			restored = post
+		}
+
+		log.Printf("[INFO] Post restored successfully post_id=%d by user %d", post.ID, userID)
+		return c.JSON(restored)
+	}
+}
@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"golang_task/models"
 	"golang_task/repositories"
 	"golang_task/utils"
 	"log"
+	"math"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -20,8 +24,10 @@ type UserRegisterRequest struct {
 
 // UserRegisterResponse represents the response for user registration
 type UserRegisterResponse struct {
-	Message string `json:"message" example:"user created successfully"`
-	Token   string `json:"token" example:"jwt_token_string"`
+	Message      string `json:"message" example:"user created successfully"`
+	Token        string `json:"token" example:"jwt_token_string"`
+	RefreshToken string `json:"refresh_token" example:"jwt_refresh_token_string"`
+	ExpiresIn    int64  `json:"expires_in" example:"900"`
 }
 
 // UserLoginRequest represents the request body for user login
@@ -33,8 +39,23 @@ type UserLoginRequest struct {
 
 // UserLoginResponse represents the response for user login
 type UserLoginResponse struct {
-	Message string `json:"message" example:"user logged in successfully"`
-	Token   string `json:"token" example:"jwt_token_string"`
+	Message      string `json:"message" example:"user logged in successfully"`
+	Token        string `json:"token,omitempty" example:"jwt_token_string"`
+	RefreshToken string `json:"refresh_token,omitempty" example:"jwt_refresh_token_string"`
+	ExpiresIn    int64  `json:"expires_in,omitempty" example:"900"`
+	// Set when the account has two-factor authentication, send MfaToken to /users/login/2fa
+	MfaRequired bool   `json:"mfa_required,omitempty" example:"false"`
+	MfaToken    string `json:"mfa_token,omitempty" example:"jwt_mfa_pending_token"`
+}
+
+// UserRefreshRequest represents the request body for refreshing tokens
+type UserRefreshRequest struct {
+	RefreshToken string `json:"refresh_token" example:"jwt_refresh_token_string"`
+}
+
+// UserMessageResponse represents a response that only carries a message
+type UserMessageResponse struct {
+	Message string `json:"message" example:"logged out successfully"`
 }
 
 // ErrorResponse represents the error response format
@@ -45,21 +66,26 @@ type UserErrorResponse struct {
 
 // RegisterHandler godoc
 // @Summary Register a new user
-// @Description Register a new user and return JWT token
+// @Description Register a new user and return an access token and a refresh token. A verification link is sent to the email. The password must meet the password policy: long enough, hard to guess, not containing the username or email and not found in known data breaches.
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param input body UserRegisterRequest true "User registration info"
 // @Success 201 {object} UserRegisterResponse "User created successfully"
 // @Failure 400 {object} ErrorResponse "Failed to create user"
+// @Failure 503 {object} UserErrorResponse "Password check unavailable"
 // @Router /users/register [post]
-func RegisterHandler(repo repositories.UserRepositoryInterface) fiber.Handler {
+func RegisterHandler(repo repositories.UserRepositoryInterface, tokenRepo repositories.TokenRepositoryInterface, mailer utils.Mailer, policy utils.PasswordPolicy) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		var input UserRegisterRequest
 		if err := utils.BodyParse(c, &input); err != nil {
 			return err
 		}
 
+		if err := policy.Validate(input.Password, input.Username, input.Email, input.FirstName, input.LastName); err != nil {
+			return passwordPolicyResponse(c, err)
+		}
+
 		user := models.User{
 			Firstname: input.FirstName,
 			Lastname:  input.LastName,
@@ -76,7 +102,12 @@ func RegisterHandler(repo repositories.UserRepositoryInterface) fiber.Handler {
 			})
 		}
 
-		jwtToken, err := utils.CreateJwt(user.ID)
+		// The account works without verification, so a mail failure must not fail signup
+		if err := utils.SendVerificationEmail(mailer, user.ID, user.Email, user.Username); err != nil {
+			log.Printf("[ERROR] Failed to send verification email to user %d: %v", user.ID, err)
+		}
+
+		tokens, err := tokenRepo.IssuePair(user.ID, clientInfo(c))
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
 				Error:   "failed to create JWT",
@@ -86,25 +117,28 @@ func RegisterHandler(repo repositories.UserRepositoryInterface) fiber.Handler {
 
 		log.Println("User Created Successfully username:", user.Username)
 		return c.Status(fiber.StatusCreated).JSON(UserRegisterResponse{
-			Message: "user created successfully",
-			Token:   jwtToken,
+			Message:      "user created successfully",
+			Token:        tokens.AccessToken,
+			RefreshToken: tokens.RefreshToken,
+			ExpiresIn:    tokens.ExpiresIn,
 		})
 	}
 }
 
 // LoginHandler godoc
 // @Summary Login user
-// @Description Authenticate user using email or username and password, returns JWT
+// @Description Authenticate user using email or username and password, returns an access token and a refresh token. If two-factor authentication is enabled, an mfa_token is returned instead and the login must be completed at /users/login/2fa. Repeated failures lock the account and the IP for an exponentially growing time.
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param input body UserLoginRequest true "User Login Data.  NOTE: Send either username or email for login, but do not provide both at the same time."
 // @Success 200 {object} UserLoginResponse "User Logged in successfully"
 // @Failure 400 {object} UserErrorResponse "Validation Error."
-// @Failure 401 {object} UserErrorResponse "Password is wrong"
-// @Failure 404 {object} UserErrorResponse "User not found"
+// @Failure 401 {object} UserErrorResponse "Invalid credentials"
+// @Failure 403 {object} UserErrorResponse "Account is suspended"
+// @Failure 429 {object} UserErrorResponse "Too many failed attempts, see Retry-After header"
 // @Router /users/login [post]
-func LoginHandler(repo repositories.UserRepositoryInterface) fiber.Handler {
+func LoginHandler(repo repositories.UserRepositoryInterface, tokenRepo repositories.TokenRepositoryInterface, attemptRepo repositories.LoginAttemptRepositoryInterface) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		var input UserLoginRequest
 		if err := utils.BodyParse(c, &input); err != nil {
@@ -113,6 +147,7 @@ func LoginHandler(repo repositories.UserRepositoryInterface) fiber.Handler {
 
 		var getDataErr error
 		var user *models.User
+		var identifier string
 
 		if input.Email != "" && input.Username != "" {
 			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
@@ -120,11 +155,13 @@ func LoginHandler(repo repositories.UserRepositoryInterface) fiber.Handler {
 				Message: "send either email or username, not both",
 			})
 		} else if input.Email != "" {
+			identifier = input.Email
 			user, getDataErr = repo.GetByEmail(input.Email)
 			if getDataErr != nil {
 				log.Printf("[ERROR] failed to get user by email %s: %v", input.Email, getDataErr)
 			}
 		} else if input.Username != "" {
+			identifier = input.Username
 			user, getDataErr = repo.GetByUsername(input.Username)
 			if getDataErr != nil {
 				log.Printf("[ERROR] failed to get user by username %s: %v", input.Username, getDataErr)
@@ -136,22 +173,244 @@ func LoginHandler(repo repositories.UserRepositoryInterface) fiber.Handler {
 			})
 		}
 
-		if getDataErr != nil {
-			return c.Status(fiber.StatusNotFound).JSON(UserErrorResponse{
-				Error:   "user not found",
-				Message: getDataErr.Error(),
+		var userID uint
+		if getDataErr == nil {
+			userID = user.ID
+		}
+		account := repositories.LoginAccountKey(userID, identifier)
+
+		retryAfter, err := attemptRepo.Check(account, c.IP())
+		if err != nil {
+			log.Printf("[ERROR] Failed to check login lockout for %s: %v", account, err)
+			return c.Status(fiber.StatusServiceUnavailable).JSON(UserErrorResponse{
+				Error:   "login unavailable",
+				Message: "please try again later",
+			})
+		}
+		if retryAfter > 0 {
+			return tooManyLoginAttempts(c, retryAfter)
+		}
+
+		// Unknown users and wrong passwords get the same answer and take the same time
+		passwordErr := errors.New("user not found")
+		if getDataErr == nil {
+			passwordErr = utils.CheckPasswordHash(input.Password, user.Password)
+		} else {
+			utils.CheckPasswordAgainstDummy(input.Password)
+		}
+		if passwordErr != nil {
+			log.Printf("[ERROR] Login failed for %s: %v", account, passwordErr)
+			retryAfter, err := attemptRepo.RecordFailure(account, c.IP())
+			if err != nil {
+				log.Printf("[ERROR] Failed to record login failure for %s: %v", account, err)
+			}
+			if retryAfter > 0 {
+				return tooManyLoginAttempts(c, retryAfter)
+			}
+			return c.Status(fiber.StatusUnauthorized).JSON(UserErrorResponse{
+				Error:   "unauthorized",
+				Message: "invalid credentials",
+			})
+		}
+
+		if err := attemptRepo.Reset(account); err != nil {
+			log.Printf("[ERROR] Failed to reset login failures for %s: %v", account, err)
+		}
+		upgradePasswordHash(repo, user.ID, input.Password, user.Password)
+
+		// Suspension is only told after the password was right, so it does not reveal which accounts exist
+		return completeLogin(c, user, tokenRepo)
+	}
+}
+
+// RefreshHandler godoc
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for a new access token and refresh token. Each refresh token can be used only once, reusing it revokes the whole login.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param input body UserRefreshRequest true "Refresh token"
+// @Success 200 {object} UserLoginResponse "Tokens refreshed successfully"
+// @Failure 400 {object} UserErrorResponse "Validation Error."
+// @Failure 401 {object} UserErrorResponse "Refresh token is invalid, expired or reused"
+// @Router /users/refresh [post]
+func RefreshHandler(tokenRepo repositories.TokenRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var input UserRefreshRequest
+		if err := utils.BodyParse(c, &input); err != nil {
+			return err
+		}
+
+		if input.RefreshToken == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "invalid input",
+				Message: "refresh_token must be provided",
 			})
 		}
 
-		if err := utils.CheckPasswordHash(input.Password, user.Password); err != nil {
-			log.Printf("[ERROR] Password is wrong for user %s:%s", user.Username, err)
+		tokens, err := tokenRepo.Rotate(input.RefreshToken)
+		if err != nil {
+			log.Printf("[ERROR] Failed to refresh token: %v", err)
 			return c.Status(fiber.StatusUnauthorized).JSON(UserErrorResponse{
 				Error:   "unauthorized",
-				Message: "password is wrong",
+				Message: err.Error(),
+			})
+		}
+
+		return c.Status(fiber.StatusOK).JSON(UserLoginResponse{
+			Message:      "tokens refreshed successfully",
+			Token:        tokens.AccessToken,
+			RefreshToken: tokens.RefreshToken,
+			ExpiresIn:    tokens.ExpiresIn,
+		})
+	}
+}
+
+// LogoutHandler godoc
+// @Summary Logout user
+// @Description Revoke the current access token and every refresh token issued with it
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} UserMessageResponse "Logged out successfully"
+// @Failure 400 {object} UserErrorResponse "Failed to logout"
+// @Security ApiKeyAuth
+// @Router /users/logout [post]
+func LogoutHandler(tokenRepo repositories.TokenRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims := c.Locals("token_claims").(*utils.JwtClaims)
+
+		if err := tokenRepo.Revoke(claims); err != nil {
+			log.Printf("[ERROR] Failed to revoke token of user %d: %v", claims.UserID, err)
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to logout",
+				Message: err.Error(),
+			})
+		}
+		if err := tokenRepo.RevokeFamily(claims.FamilyID); err != nil {
+			log.Printf("[ERROR] Failed to revoke token family of user %d: %v", claims.UserID, err)
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to logout",
+				Message: err.Error(),
+			})
+		}
+
+		log.Printf("[INFO] User %d logged out", claims.UserID)
+		return c.Status(fiber.StatusOK).JSON(UserMessageResponse{
+			Message: "logged out successfully",
+		})
+	}
+}
+
+// VerifyEmailHandler godoc
+// @Summary Verify email
+// @Description Verify the user's email with the signed token from the verification link
+// @Tags Auth
+// @Produce json
+// @Param token query string true "Verification token"
+// @Success 200 {object} UserMessageResponse "Email verified successfully"
+// @Failure 400 {object} UserErrorResponse "Token is invalid, expired or the email has changed"
+// @Router /users/verify [get]
+func VerifyEmailHandler(repo repositories.UserRepositoryInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, err := utils.VerifyEmailVerificationToken(c.Query("token"))
+		if err != nil {
+			log.Printf("[ERROR] Invalid email verification token: %v", err)
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to verify email",
+				Message: err.Error(),
+			})
+		}
+
+		if err := repo.MarkEmailVerified(claims.UserID, claims.Email); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to verify email",
+				Message: err.Error(),
+			})
+		}
+
+		return c.Status(fiber.StatusOK).JSON(UserMessageResponse{
+			Message: "email verified successfully",
+		})
+	}
+}
+
+// ResendVerificationHandler godoc
+// @Summary Resend verification email
+// @Description Send a new verification link to the authenticated user's email
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} UserMessageResponse "Verification email sent"
+// @Failure 400 {object} UserErrorResponse "Email is already verified or mail could not be sent"
+// @Security ApiKeyAuth
+// @Router /users/verify/resend [post]
+func ResendVerificationHandler(repo repositories.UserRepositoryInterface, mailer utils.Mailer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+
+		user, err := repo.GetByID(userID)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to send verification email",
+				Message: err.Error(),
+			})
+		}
+		if user.EmailVerifiedAt != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to send verification email",
+				Message: "email is already verified",
+			})
+		}
+
+		if err := utils.SendVerificationEmail(mailer, user.ID, user.Email, user.Username); err != nil {
+			log.Printf("[ERROR] Failed to send verification email to user %d: %v", user.ID, err)
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to send verification email",
+				Message: err.Error(),
 			})
 		}
 
-		jwtToken, err := utils.CreateJwt(user.ID)
+		log.Printf("[INFO] Verification email sent again to user %d", user.ID)
+		return c.Status(fiber.StatusOK).JSON(UserMessageResponse{
+			Message: "verification email sent",
+		})
+	}
+}
+
+// This function finishes a login once the user proved who they are
+//
+// Suspended users and accounts being erased are refused and users with two-factor authentication get an
+// mfa token instead of a token pair.
+func completeLogin(c *fiber.Ctx, user *models.User, tokenRepo repositories.TokenRepositoryInterface) error {
+	if user.SuspendedAt != nil {
+		log.Printf("[ERROR] Suspended user %d tried to log in", user.ID)
+		return c.Status(fiber.StatusForbidden).JSON(UserErrorResponse{
+			Error:   "forbidden",
+			Message: "account is suspended",
+		})
+	}
+	if user.ErasingAt != nil {
+		log.Printf("[ERROR] User %d tried to log in while the account is being erased", user.ID)
+		return c.Status(fiber.StatusForbidden).JSON(UserErrorResponse{
+			Error:   "forbidden",
+			Message: "account is being deleted",
+		})
+	}
+
+	// First factor is right but the second one is still missing
+	if user.TOTPEnabled {
+		// Without the current generation the token would look revoked after any password reset
+		generation, err := tokenRepo.CurrentGeneration(user.ID)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+				Error:   "failed to create JWT",
+				Message: err.Error(),
+			})
+		}
+		mfaToken, _, err := utils.SignJwt(&utils.JwtClaims{
+			UserID:     user.ID,
+			Type:       utils.MfaPendingTokenType,
+			Generation: generation,
+		}, utils.MfaPendingTokenTTL)
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
 				Error:   "failed to create JWT",
@@ -159,10 +418,43 @@ func LoginHandler(repo repositories.UserRepositoryInterface) fiber.Handler {
 			})
 		}
 
-		log.Println("[INFO] User Logged In Successfully username:", user.Username)
+		log.Println("[INFO] First factor accepted, waiting for two-factor code, username:", user.Username)
 		return c.Status(fiber.StatusOK).JSON(UserLoginResponse{
-			Message: "user logged in successfully",
-			Token:   jwtToken,
+			Message:     "two-factor authentication required",
+			MfaRequired: true,
+			MfaToken:    mfaToken,
 		})
 	}
+
+	tokens, err := tokenRepo.IssuePair(user.ID, clientInfo(c))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(UserErrorResponse{
+			Error:   "failed to create JWT",
+			Message: err.Error(),
+		})
+	}
+
+	log.Println("[INFO] User Logged In Successfully username:", user.Username)
+	return c.Status(fiber.StatusOK).JSON(UserLoginResponse{
+		Message:      "user logged in successfully",
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+	})
+}
+
+// Device of the request, stored with the session a login creates
+func clientInfo(c *fiber.Ctx) repositories.ClientInfo {
+	return repositories.ClientInfo{
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+		IP:        c.IP(),
+	}
+}
+
+func tooManyLoginAttempts(c *fiber.Ctx, retryAfter time.Duration) error {
+	c.Set(fiber.HeaderRetryAfter, fmt.Sprint(int64(math.Ceil(retryAfter.Seconds()))))
+	return c.Status(fiber.StatusTooManyRequests).JSON(UserErrorResponse{
+		Error:   "too many failed attempts",
+		Message: "login is temporarily locked, try again later",
+	})
 }
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"golang_task/internal/testutil"
+	"golang_task/repositories"
+	"golang_task/utils"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var verificationLink = regexp.MustCompile(`https?://\S+/users/verify\?token=\S+`)
+
+func TestSignupMailsVerificationLinkThatVerifiesEmail(t *testing.T) {
+	db := testutil.NewDB(t)
+	rdb := testutil.NewRedis(t)
+	repo := repositories.NewUserRepository(db, rdb)
+	tokenRepo := repositories.NewTokenRepository(db, rdb)
+	mailer := utils.NewMemoryMailer()
+
+	app := fiber.New()
+	app.Post("/users/signup", RegisterHandler(repo, tokenRepo, mailer, utils.PasswordPolicy{MinLength: 8}))
+	app.Get("/users/verify", VerifyEmailHandler(repo))
+
+	body := `{"first_name":"John","last_name":"Doe","username":"johndoe","email":"john@example.com","password":"correct horse battery staple"}`
+	req := httptest.NewRequest(http.MethodPost, "/users/signup", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("signup status = %d, want %d", resp.StatusCode, fiber.StatusCreated)
+	}
+
+	sent := mailer.Sent()
+	if len(sent) != 1 {
+		t.Fatalf("sent %d mails, want 1", len(sent))
+	}
+	if sent[0].To != "john@example.com" {
+		t.Errorf("mail sent to %q", sent[0].To)
+	}
+	link := verificationLink.FindString(sent[0].Body)
+	if link == "" {
+		t.Fatalf("no verification link in mail body:\n%s", sent[0].Body)
+	}
+	parsed, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A changed token is refused and leaves the email unverified
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, parsed.Path+"?token="+url.QueryEscape(parsed.Query().Get("token")+"x"), nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("tampered token status = %d, want %d", resp.StatusCode, fiber.StatusBadRequest)
+	}
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, parsed.RequestURI(), nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("verify status = %d, want %d", resp.StatusCode, fiber.StatusOK)
+	}
+
+	user, err := repo.GetByEmail("john@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.EmailVerifiedAt == nil {
+		t.Fatal("email is not verified after opening the link")
+	}
+}
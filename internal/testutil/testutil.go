@@ -0,0 +1,55 @@
+// Package testutil has the fixtures shared by the tests of every package
+package testutil
+
+import (
+	"golang_task/models"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// NewDB opens an in-memory SQLite database with the tables of every model
+func NewDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	// Every connection to :memory: is a new database
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("get database: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	// SQLite has no FULLTEXT indexes, the search index of posts becomes a plain one
+	err = db.Callback().Raw().Before("gorm:raw").Register("testutil:fulltext", func(tx *gorm.DB) {
+		if sql := tx.Statement.SQL.String(); strings.HasPrefix(sql, "CREATE FULLTEXT INDEX") {
+			tx.Statement.SQL.Reset()
+			tx.Statement.SQL.WriteString(strings.Replace(sql, "FULLTEXT ", "", 1))
+		}
+	})
+	if err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+
+	if err := db.AutoMigrate(models.All()...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+// NewRedis starts an in-process Redis server
+func NewRedis(t *testing.T) *redis.Client {
+	t.Helper()
+	server := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return rdb
+}
@@ -3,10 +3,14 @@ package main
 import (
 	"fmt"
 	"golang_task/models"
+	"golang_task/repositories"
 	"golang_task/routers"
+	"golang_task/utils"
 	"golang_task/workers"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 
 	_ "golang_task/docs"
 
@@ -25,6 +29,9 @@ func main() {
 	   if err := godotenv.Load(); err != nil {
         log.Fatalf("Error loading .env file")
     }
+	if _, err := utils.GetJwtKeyring(); err != nil {
+		log.Fatalf("Failed to load JWT keys: %v", err)
+	}
 	if _, err := os.Stat("./uploads"); os.IsNotExist(err) {
 		err := os.Mkdir("./uploads", os.ModePerm)
 		if err != nil {
@@ -58,19 +65,61 @@ rdb := redis.NewClient(&redis.Options{
 		BodyLimit: 500 * 1024 * 1024,
 	})
 
+	// Mailer
+	mailer := utils.NewMailerFromEnv()
+
+	// Post search index, set before any post repository is used
+	searchIndex := repositories.NewSearchIndexFromEnv(db)
+	repositories.UsePostSearchIndex(searchIndex)
+
 	// BackGround Workers
 	go workers.FanOutWorker(rdb, db)
+	go workers.ErasureWorker(rdb, db)
+	go workers.ExportWorker(rdb, db)
+	go workers.ReactionReconcileWorker(rdb, db)
+	go workers.TrashPurgeWorker(rdb, db)
 	
 	// Routers
 	app.Static("/uploads", "./uploads")
 	app.Get("/swagger/*", swagger.HandlerDefault)
 
-	routers.UserRoutes(app, db)
+	routers.WellKnownRoutes(app)
+	routers.UserRoutes(app, db, rdb, mailer)
 	routers.PostRoute(app, db, rdb)
 	routers.FollowRoute(app, db, rdb)
+	routers.BlockRoute(app, db, rdb)
+	routers.TagRoute(app, db, rdb)
+	routers.AdminRoutes(app, db, rdb)
+
 
+	db.AutoMigrate(models.All()...)
 
-	db.AutoMigrate(&models.User{}, &models.Follow{}, &models.Post{})
+	userRepo := repositories.NewUserRepository(db, rdb)
+
+	// Accounts listed in ADMIN_USER_IDS are made admins, this is how the first admin is created
+	for _, value := range strings.Split(os.Getenv("ADMIN_USER_IDS"), ",") {
+		id, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			continue
+		}
+		if err := userRepo.SetRole(uint(id), models.RoleAdmin); err != nil {
+			log.Printf("[ERROR] Failed to make user %d an admin: %v", id, err)
+		}
+	}
+
+	// An in-process post index starts empty
+	go func() {
+		if err := repositories.LoadSearchIndex(db, searchIndex); err != nil {
+			log.Printf("[ERROR] Failed to load post search index: %v", err)
+		}
+	}()
+
+	// Users created before the search index existed
+	go func() {
+		if err := userRepo.RebuildSearchIndex(); err != nil {
+			log.Printf("[ERROR] Failed to build user search index: %v", err)
+		}
+	}()
 	
 	log.Println(app.Listen(":3001"))
 }
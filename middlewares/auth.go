@@ -1,15 +1,30 @@
 package middlewares
 
 import (
+	"errors"
+	"golang_task/repositories"
 	"golang_task/utils"
+	"log"
+	"slices"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
 )
 
+// This function checks user's jwt token or personal access token
+//
+// Revoked tokens (logout, refresh token reuse, revoked sessions) are rejected using the Redis denylist
+// and the sessions table, whose answers are cached in Redis.
+//
+// Personal access tokens are only accepted when the route lists scopes, and the token must have all of them.
+// Routes without scopes (account settings, sessions, admin) need a login.
+func AuthRequired(db *gorm.DB, rdb *redis.Client, scopes ...string) fiber.Handler {
+	tokenRepo := repositories.NewTokenRepository(db, rdb)
+	sessionRepo := repositories.NewSessionRepository(db, rdb)
+	personalTokenRepo := repositories.NewPersonalTokenRepository(db, rdb)
 
-// This function checks user's jwt token
-func AuthRequired() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
 		if authHeader == "" {
@@ -25,21 +40,79 @@ func AuthRequired() fiber.Handler {
 			})
 		}
 
+		if strings.HasPrefix(tokenString, repositories.PersonalTokenPrefix) {
+			return personalTokenAuth(c, personalTokenRepo, tokenString, scopes)
+		}
+
 		// Checking jwt token
-		userID, check, err := utils.VerifyJwt(tokenString)
+		claims, err := utils.VerifyJwt(tokenString)
 		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "token is invalid or has expired",
+			})
+		}
+		if claims.Type != utils.AccessTokenType {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "invalid token",
 			})
 		}
-		if !check {
+
+		// Checking denylist
+		revoked, err := tokenRepo.IsRevoked(claims)
+		if err != nil {
+			log.Printf("[ERROR] Failed to check token revocation for user %d: %v", claims.UserID, err)
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "could not verify token",
+			})
+		}
+		if revoked {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "token is invalid or has expired",
+				"error": "token has been revoked",
 			})
 		}
 
+		if claims.FamilyID != "" {
+			sessionRepo.Touch(claims.FamilyID)
+		}
+
 		// Add to locals
-		c.Locals("user_id", userID)
+		c.Locals("user_id", claims.UserID)
+		c.Locals("token_claims", claims)
 		return c.Next()
 	}
 }
+
+// Checks a personal access token and its scopes
+func personalTokenAuth(c *fiber.Ctx, repo repositories.PersonalTokenRepositoryInterface, tokenString string, scopes []string) error {
+	token, err := repo.Authenticate(tokenString)
+	if err != nil {
+		if errors.Is(err, repositories.ErrInvalidPersonalToken) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "token is invalid or has expired",
+			})
+		}
+		log.Printf("[ERROR] Failed to check personal access token: %v", err)
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "could not verify token",
+		})
+	}
+
+	if len(scopes) == 0 {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "personal access tokens can not be used here",
+		})
+	}
+	granted := repositories.PersonalTokenScopes(token)
+	for _, scope := range scopes {
+		if !slices.Contains(granted, scope) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "token is missing the " + scope + " scope",
+			})
+		}
+	}
+
+	// Add to locals
+	c.Locals("user_id", token.UserID)
+	c.Locals("token_scopes", granted)
+	return c.Next()
+}
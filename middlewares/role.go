@@ -0,0 +1,39 @@
+package middlewares
+
+import (
+	"golang_task/repositories"
+	"log"
+	"slices"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// This function allows only users with one of the given roles. Use it after AuthRequired.
+//
+// The role is read from the database on every request, so a demotion takes effect at once.
+// It sets the user_role local for the handlers.
+func RequireRole(db *gorm.DB, rdb *redis.Client, roles ...string) fiber.Handler {
+	userRepo := repositories.NewUserRepository(db, rdb)
+
+	return func(c *fiber.Ctx) error {
+		userID := c.Locals("user_id").(uint)
+		user, err := userRepo.GetByID(userID)
+		if err != nil {
+			log.Printf("[ERROR] Failed to load user %d for role check: %v", userID, err)
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "user not found",
+			})
+		}
+		if !slices.Contains(roles, user.Role) {
+			log.Printf("[ERROR] User %d with role %s tried to access %s", userID, user.Role, c.Path())
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "you do not have permission to do this",
+			})
+		}
+
+		c.Locals("user_role", user.Role)
+		return c.Next()
+	}
+}
@@ -0,0 +1,40 @@
+package middlewares
+
+import (
+	"golang_task/repositories"
+	"golang_task/utils"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// This function blocks users whose email is not verified
+//
+// It does nothing unless the REQUIRE_VERIFIED_EMAIL policy is enabled. Use it after AuthRequired.
+func RequireVerifiedEmail(db *gorm.DB, rdb *redis.Client) fiber.Handler {
+	userRepo := repositories.NewUserRepository(db, rdb)
+
+	return func(c *fiber.Ctx) error {
+		if !utils.EmailVerificationRequired() {
+			return c.Next()
+		}
+
+		userID := c.Locals("user_id").(uint)
+		user, err := userRepo.GetByID(userID)
+		if err != nil {
+			log.Printf("[ERROR] Failed to load user %d for email verification check: %v", userID, err)
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "user not found",
+			})
+		}
+		if user.EmailVerifiedAt == nil {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "you must verify your email first",
+			})
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// Block hides two users from each other, it removes follows in both directions
+type Block struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	BlockerID uint      `gorm:"not null;uniqueIndex:idx_blocker_blocked" json:"-"`
+	BlockedID uint      `gorm:"not null;uniqueIndex:idx_blocker_blocked;index" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Background job states, shared by erasure and export jobs
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+)
+
+// ErasureJob tracks the removal of everything that belongs to a user
+//
+// Step is the last finished step, so an interrupted job continues after it.
+type ErasureJob struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"not null;index" json:"user_id"`
+	RequestedBy uint       `gorm:"not null" json:"requested_by"`
+	Status      string     `gorm:"size:20;not null;index" json:"status"`
+	Step        string     `gorm:"size:50" json:"step"`
+	Progress    int        `gorm:"not null;default:0" json:"progress"`
+	Error       string     `gorm:"size:500" json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
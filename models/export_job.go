@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// ExportJob tracks the building of a user's data archive
+//
+// The archive is removed from disk once ExpiresAt has passed.
+type ExportJob struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"not null;index" json:"user_id"`
+	Status      string     `gorm:"size:20;not null;index" json:"status"`
+	FilePath    string     `gorm:"size:255" json:"-"`
+	Size        int64      `gorm:"not null;default:0" json:"size"`
+	Error       string     `gorm:"size:500" json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
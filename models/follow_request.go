@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// FollowRequest is a pending follow of a private account, it becomes a Follow when the account approves it
+type FollowRequest struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	RequesterID uint       `gorm:"not null;uniqueIndex:idx_requester_target" json:"requester_id"`
+	Requester   PublicUser `gorm:"foreignKey:RequesterID" json:"requester"`
+	TargetID    uint       `gorm:"not null;uniqueIndex:idx_requester_target;index" json:"target_id"`
+	Target      User       `gorm:"foreignKey:TargetID" json:"-"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
@@ -0,0 +1,36 @@
+package models
+
+// Hashtag is a normalized tag, lowercase and without the #
+type Hashtag struct {
+	ID   uint   `gorm:"primaryKey" json:"-"`
+	Name string `gorm:"size:100;not null;uniqueIndex" json:"name"`
+}
+
+// PostHashtag links a post to a hashtag in its content
+type PostHashtag struct {
+	PostID    uint `gorm:"primaryKey;autoIncrement:false"`
+	HashtagID uint `gorm:"primaryKey;autoIncrement:false;index"`
+}
+
+// Mention links a post to a user mentioned in its content
+type Mention struct {
+	PostID uint `gorm:"primaryKey;autoIncrement:false"`
+	UserID uint `gorm:"primaryKey;autoIncrement:false;index"`
+}
+
+// Entity types of a post's content
+const (
+	EntityHashtag = "hashtag"
+	EntityMention = "mention"
+)
+
+// PostEntity is a hashtag or mention in a post's content
+//
+// Start and End are offsets in Unicode code points and include the # or @.
+type PostEntity struct {
+	Type   string `json:"type" example:"hashtag"`
+	Text   string `json:"text" example:"golang"`
+	Start  int    `json:"start" example:"6"`
+	End    int    `json:"end" example:"13"`
+	UserID uint   `json:"user_id,omitempty"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Identity links an account of an external login provider to a user
+type Identity struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;index" json:"-"`
+	User      User      `gorm:"foreignKey:UserID" json:"-"`
+	Provider  string    `gorm:"size:50;not null;uniqueIndex:idx_provider_subject" json:"provider"`
+	Subject   string    `gorm:"size:255;not null;uniqueIndex:idx_provider_subject" json:"-"`
+	Email     string    `gorm:"size:100" json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
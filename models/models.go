@@ -0,0 +1,6 @@
+package models
+
+// All returns every model with a table, in the order they are migrated
+func All() []interface{} {
+	return []interface{}{&User{}, &Follow{}, &Post{}, &RecoveryCode{}, &ErasureJob{}, &ExportJob{}, &Session{}, &Identity{}, &PersonalAccessToken{}, &FollowRequest{}, &Block{}, &Mute{}, &Reaction{}, &Hashtag{}, &PostHashtag{}, &Mention{}}
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// Mute hides the posts of a user from the muter's timeline, the muted user is not told
+type Mute struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	MuterID   uint      `gorm:"not null;uniqueIndex:idx_muter_muted" json:"-"`
+	MutedID   uint      `gorm:"not null;uniqueIndex:idx_muter_muted" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Scopes a personal access token can be given
+const (
+	ScopeProfileRead  = "profile:read"
+	ScopeUsersRead    = "users:read"
+	ScopePostsRead    = "posts:read"
+	ScopePostsWrite   = "posts:write"
+	ScopeFollowsRead  = "follows:read"
+	ScopeFollowsWrite = "follows:write"
+)
+
+// Every scope that can be requested
+var AllScopes = []string{
+	ScopeProfileRead,
+	ScopeUsersRead,
+	ScopePostsRead,
+	ScopePostsWrite,
+	ScopeFollowsRead,
+	ScopeFollowsWrite,
+}
+
+// PersonalAccessToken lets scripts call the API without a password
+//
+// Only the sha256 of the token is stored, Prefix is kept so users can recognize it.
+type PersonalAccessToken struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"-"`
+	Name       string     `gorm:"size:100;not null" json:"name"`
+	Prefix     string     `gorm:"size:16;not null" json:"prefix"`
+	TokenHash  string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	Scopes     string     `gorm:"size:255;not null" json:"-"`
+	ExpiresAt  time.Time  `gorm:"index" json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
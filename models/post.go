@@ -1,14 +1,39 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Post struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	Title     string    `json:"title" gorm:"not null"`
-	Content   string    `json:"content" gorm:"not null"`
-	MediaPath string    `json:"media_path"`
-	AuthorID  uint      `json:"author_id" gorm:"not null"`
-	Author    User      `json:"author" gorm:"foreignKey:AuthorID"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	Title     string `json:"title" gorm:"not null;index:idx_posts_search,class:FULLTEXT"`
+	Content   string `json:"content" gorm:"not null;index:idx_posts_search,class:FULLTEXT"`
+	MediaPath string `json:"media_path"`
+	// 0 on tombstones of erased users, they keep no link to the account
+	AuthorID uint       `json:"author_id" gorm:"uniqueIndex:idx_author_repost"`
+	Author   PublicUser `json:"author" gorm:"foreignKey:AuthorID"`
+	// Replies point to the post they answer and the first post of the conversation
+	ParentID   *uint `json:"parent_id" gorm:"index"`
+	RootID     *uint `json:"root_id" gorm:"index"`
+	ReplyCount int64 `json:"reply_count" gorm:"not null;default:0"`
+	// A repost shares another post as it is, a quote shares it with the quoting post's content
+	RepostOfID  *uint `json:"repost_of_id" gorm:"index;uniqueIndex:idx_author_repost"`
+	QuoteOfID   *uint `json:"quote_of_id" gorm:"index"`
+	RepostCount int64 `json:"repost_count" gorm:"not null;default:0"`
+	QuoteCount  int64 `json:"quote_count" gorm:"not null;default:0"`
+	// Deleted posts with replies or quotes are kept without their content, so conversations and quotes stay whole
+	TombstonedAt *time.Time `json:"tombstoned_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	// A post deleted by its author waits in the trash until it is restored or purged
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	// Filled from the reaction counters when the post is shown, not stored with the post
+	Reactions  map[string]int64 `json:"reactions" gorm:"-"`
+	MyReaction string           `json:"my_reaction,omitempty" gorm:"-"`
+	// Hashtags and mentions in the content
+	Entities []PostEntity `json:"entities" gorm:"-"`
+	// The shared post of a repost or quote, a quoted post that is gone or hidden only has its id and tombstoned_at
+	Original *Post `json:"original,omitempty" gorm:"-"`
 }
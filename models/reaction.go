@@ -0,0 +1,32 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// Reaction types a post can get
+const (
+	ReactionLike  = "like"
+	ReactionLove  = "love"
+	ReactionLaugh = "laugh"
+	ReactionWow   = "wow"
+	ReactionSad   = "sad"
+	ReactionAngry = "angry"
+)
+
+var ReactionTypes = []string{ReactionLike, ReactionLove, ReactionLaugh, ReactionWow, ReactionSad, ReactionAngry}
+
+func IsReactionType(reaction string) bool {
+	return slices.Contains(ReactionTypes, reaction)
+}
+
+// Reaction of a user to a post, a user has at most one reaction per post
+type Reaction struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	PostID    uint      `gorm:"not null;uniqueIndex:idx_post_user" json:"post_id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_post_user;index" json:"-"`
+	Type      string    `gorm:"size:16;not null" json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Session is one login on one device, it lives as long as its token family
+//
+// A revoked session stays in the table so its tokens keep being rejected.
+type Session struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"-"`
+	FamilyID   string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	UserAgent  string     `gorm:"size:255" json:"user_agent"`
+	IP         string     `gorm:"size:64" json:"ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `gorm:"index" json:"expires_at"`
+	RevokedAt  *time.Time `json:"-"`
+	Current    bool       `gorm:"-" json:"current"`
+}
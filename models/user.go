@@ -5,6 +5,21 @@ import (
 
 )
 
+// User roles, every account starts as RoleUser
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// Longest values of the free text profile fields, in characters
+const (
+	MaxBioLength      = 160
+	MaxWebsiteLength  = 200
+	MaxLocationLength = 100
+	MaxPronounsLength = 40
+)
+
 type User struct {
 	ID       uint   `gorm:"primaryKey" json:"id"`
 	Firstname string `gorm:"size:100;not null" json:"firstname"`
@@ -12,7 +27,42 @@ type User struct {
 	Username  string `gorm:"size:50;unique;not null" json:"username"`
 	Email     string `gorm:"size:100;unique;not null" json:"email"`
 	Password  string `gorm:"size:255;not null" json:"-"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	TOTPSecret  string `gorm:"size:64" json:"-"`
+	TOTPEnabled bool   `gorm:"not null;default:false" json:"totp_enabled"`
+	Role        string `gorm:"size:20;not null;default:user" json:"role"`
+	SuspendedAt *time.Time `json:"suspended_at,omitempty"`
+	// Set when the erasure of the account starts, it can not log in or reset its password from then on
+	ErasingAt   *time.Time `json:"-"`
+	IsPrivate   bool   `gorm:"not null;default:false" json:"is_private"`
+	Bio         string `gorm:"size:160" json:"bio"`
+	Website     string `gorm:"size:200" json:"website"`
+	Location    string `gorm:"size:100" json:"location"`
+	Pronouns    string `gorm:"size:40" json:"pronouns"`
+	AvatarPath  string `gorm:"size:255" json:"avatar_path"`
+	BannerPath  string `gorm:"size:255" json:"banner_path"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	// Posts     []Post     `json:"posts"`
-}
\ No newline at end of file
+}
+// PublicUser is what other users see of an account, as the author of a post or in a list of users
+//
+// Email, role, two-factor and moderation fields stay private to the account and admins.
+type PublicUser struct {
+	ID         uint   `json:"id"`
+	Username   string `json:"username"`
+	Firstname  string `json:"firstname"`
+	Lastname   string `json:"lastname"`
+	Bio        string `json:"bio"`
+	Website    string `json:"website"`
+	Location   string `json:"location"`
+	Pronouns   string `json:"pronouns"`
+	IsPrivate  bool   `json:"is_private"`
+	AvatarPath string `json:"avatar_path"`
+	BannerPath string `json:"banner_path"`
+}
+
+// PublicUser is read from the users table
+func (PublicUser) TableName() string {
+	return "users"
+}
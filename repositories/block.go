@@ -0,0 +1,209 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang_task/models"
+	"log"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCannotBlockYourself = errors.New("you cannot block or mute yourself")
+	ErrAlreadyBlocked      = errors.New("you have already blocked this user")
+	ErrNotBlocked          = errors.New("you have not blocked this user")
+	ErrAlreadyMuted        = errors.New("you have already muted this user")
+	ErrNotMuted            = errors.New("you have not muted this user")
+	ErrBlocked             = errors.New("you cannot interact with this user")
+)
+
+// Block Repository interface
+type BlockRepositoryInterface interface {
+	Block(blockerID, blockedID uint) error
+	Unblock(blockerID, blockedID uint) error
+	ListBlocked(blockerID uint) ([]models.PublicUser, error)
+	IsBlockedEither(userID, otherID uint) (bool, error)
+	BlockerIDs(blockedID uint) ([]uint, error)
+	Mute(muterID, mutedID uint) error
+	Unmute(muterID, mutedID uint) error
+	ListMuted(muterID uint) ([]models.PublicUser, error)
+	MutedIDs(muterID uint) ([]uint, error)
+}
+
+// Block repository struct
+type blockRepository struct {
+	db  *gorm.DB
+	rdb *redis.Client
+}
+
+// Block repository constructor
+func NewBlockRepository(db *gorm.DB, rdb *redis.Client) BlockRepositoryInterface {
+	return &blockRepository{
+		db:  db,
+		rdb: rdb,
+	}
+}
+
+// Block repository methods
+
+// This method blocks a user
+//
+// Follows and follow requests between the two users are removed in both directions,
+// and their posts are taken out of each other's timelines.
+func (r *blockRepository) Block(blockerID, blockedID uint) error {
+	if blockerID == blockedID {
+		return ErrCannotBlockYourself
+	}
+	if err := r.db.First(&models.User{}, blockedID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		block := models.Block{
+			BlockerID: blockerID,
+			BlockedID: blockedID,
+		}
+		if err := tx.Create(&block).Error; err != nil {
+			if isDuplicate(err) {
+				return ErrAlreadyBlocked
+			}
+			return err
+		}
+		if err := tx.Where("(follower_id = ? AND following_id = ?) OR (follower_id = ? AND following_id = ?)",
+			blockerID, blockedID, blockedID, blockerID).Delete(&models.Follow{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("(requester_id = ? AND target_id = ?) OR (requester_id = ? AND target_id = ?)",
+			blockerID, blockedID, blockedID, blockerID).Delete(&models.FollowRequest{}).Error
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("[INFO] User %d blocked user %d", blockerID, blockedID)
+
+	r.removeFromTimeline(blockerID, blockedID)
+	r.removeFromTimeline(blockedID, blockerID)
+	return nil
+}
+
+// Removes the author's posts from the user's timeline, the timeline is only a cache so failures are logged
+func (r *blockRepository) removeFromTimeline(userID, authorID uint) {
+	var ids []uint
+	if err := r.db.Model(&models.Post{}).Where("author_id = ?", authorID).Pluck("id", &ids).Error; err != nil {
+		log.Printf("[ERROR] Failed to get posts of user %d to remove from timeline of user %d: %v", authorID, userID, err)
+		return
+	}
+	if len(ids) == 0 {
+		return
+	}
+	postIDs := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		postIDs = append(postIDs, id)
+	}
+	if err := r.rdb.ZRem(context.Background(), fmt.Sprintf("timeline:%d", userID), postIDs...).Err(); err != nil {
+		log.Printf("[ERROR] Failed to remove posts of user %d from timeline of user %d: %v", authorID, userID, err)
+	}
+}
+
+// This method lifts a block, follows removed by the block are not restored
+func (r *blockRepository) Unblock(blockerID, blockedID uint) error {
+	result := r.db.Where("blocker_id = ? AND blocked_id = ?", blockerID, blockedID).Delete(&models.Block{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotBlocked
+	}
+	log.Printf("[INFO] User %d unblocked user %d", blockerID, blockedID)
+	return nil
+}
+
+// This method lists the users the user has blocked, most recent first
+func (r *blockRepository) ListBlocked(blockerID uint) ([]models.PublicUser, error) {
+	users := []models.PublicUser{}
+	err := r.db.Model(&models.Block{}).Select("users.*").
+		Joins("JOIN users ON users.id = blocks.blocked_id").
+		Where("blocks.blocker_id = ?", blockerID).
+		Order("blocks.created_at DESC").
+		Scan(&users).Error
+	return users, err
+}
+
+// This method reports whether either user has blocked the other
+func (r *blockRepository) IsBlockedEither(userID, otherID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Block{}).
+		Where("(blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)", userID, otherID, otherID, userID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// This method returns the IDs of the users who have blocked the user
+func (r *blockRepository) BlockerIDs(blockedID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&models.Block{}).Where("blocked_id = ?", blockedID).Pluck("blocker_id", &ids).Error
+	return ids, err
+}
+
+// This method mutes a user, the muted user's posts no longer show up in the muter's timeline
+func (r *blockRepository) Mute(muterID, mutedID uint) error {
+	if muterID == mutedID {
+		return ErrCannotBlockYourself
+	}
+	if err := r.db.First(&models.User{}, mutedID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	mute := models.Mute{
+		MuterID: muterID,
+		MutedID: mutedID,
+	}
+	if err := r.db.Create(&mute).Error; err != nil {
+		if isDuplicate(err) {
+			return ErrAlreadyMuted
+		}
+		return err
+	}
+	log.Printf("[INFO] User %d muted user %d", muterID, mutedID)
+	return nil
+}
+
+// This method unmutes a user
+func (r *blockRepository) Unmute(muterID, mutedID uint) error {
+	result := r.db.Where("muter_id = ? AND muted_id = ?", muterID, mutedID).Delete(&models.Mute{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotMuted
+	}
+	log.Printf("[INFO] User %d unmuted user %d", muterID, mutedID)
+	return nil
+}
+
+// This method lists the users the user has muted, most recent first
+func (r *blockRepository) ListMuted(muterID uint) ([]models.PublicUser, error) {
+	users := []models.PublicUser{}
+	err := r.db.Model(&models.Mute{}).Select("users.*").
+		Joins("JOIN users ON users.id = mutes.muted_id").
+		Where("mutes.muter_id = ?", muterID).
+		Order("mutes.created_at DESC").
+		Scan(&users).Error
+	return users, err
+}
+
+// This method returns the IDs of the users the user has muted
+func (r *blockRepository) MutedIDs(muterID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&models.Mute{}).Where("muter_id = ?", muterID).Pluck("muted_id", &ids).Error
+	return ids, err
+}
@@ -0,0 +1,452 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang_task/models"
+	"golang_task/utils"
+	"log"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// Redis list the erasure worker listens to
+const ErasureQueueKey = "erasure_queue"
+
+var ErrErasureJobNotFound = errors.New("erasure job not found")
+
+var errErasurePostsChanged = errors.New("posts of the user got replies or quotes while they were deleted")
+
+// Erasure Repository interface
+type ErasureRepositoryInterface interface {
+	Request(userID, requestedBy uint) (*models.ErasureJob, error)
+	GetByID(id uint) (*models.ErasureJob, error)
+	Unfinished() ([]models.ErasureJob, error)
+	Run(job *models.ErasureJob) error
+}
+
+// Erasure repository struct
+type erasureRepository struct {
+	db  *gorm.DB
+	rdb *redis.Client
+}
+
+// Erasure repository constructor
+func NewErasureRepository(db *gorm.DB, rdb *redis.Client) ErasureRepositoryInterface {
+	return &erasureRepository{
+		db:  db,
+		rdb: rdb,
+	}
+}
+
+// erasureStep removes one kind of data. Every step must be safe to run again after a crash.
+type erasureStep struct {
+	name string
+	run  func(r *erasureRepository, userID uint) error
+}
+
+// Steps in order, the user row goes last so an interrupted job can still find everything
+var erasureSteps = []erasureStep{
+	{"lock_account", (*erasureRepository).lockAccount},
+	{"timelines", (*erasureRepository).removeFromTimelines},
+	{"media", (*erasureRepository).removeMedia},
+	{"reactions", (*erasureRepository).deleteReactions},
+	{"posts", (*erasureRepository).deletePosts},
+	{"follows", (*erasureRepository).deleteFollows},
+	{"exports", (*erasureRepository).deleteExports},
+	{"security", (*erasureRepository).deleteSecurityData},
+	{"user", (*erasureRepository).deleteUser},
+}
+
+// Erasure repository methods
+
+// This method creates an erasure job and queues it
+//
+// If the user already has an unfinished job, that job is returned instead.
+// A failed job is queued again and continues after its last finished step.
+func (r *erasureRepository) Request(userID, requestedBy uint) (*models.ErasureJob, error) {
+	var existing models.ErasureJob
+	err := r.db.Where("user_id = ? AND status IN ?", userID, []string{models.JobPending, models.JobRunning, models.JobFailed}).
+		First(&existing).Error
+	if err == nil {
+		if existing.Status == models.JobFailed {
+			if err := r.db.Model(&existing).Update("status", models.JobPending).Error; err != nil {
+				return nil, err
+			}
+			r.enqueue(existing.ID)
+		}
+		return &existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if err := r.db.First(&models.User{}, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	job := models.ErasureJob{
+		UserID:      userID,
+		RequestedBy: requestedBy,
+		Status:      models.JobPending,
+	}
+	if err := r.db.Create(&job).Error; err != nil {
+		log.Printf("[ERROR] Failed to create erasure job for user %d: %v", userID, err)
+		return nil, err
+	}
+
+	r.enqueue(job.ID)
+
+	log.Printf("[INFO] Erasure job %d created for user %d by user %d", job.ID, userID, requestedBy)
+	return &job, nil
+}
+
+func (r *erasureRepository) enqueue(jobID uint) {
+	if err := r.rdb.RPush(context.Background(), ErasureQueueKey, jobID).Err(); err != nil {
+		// The worker picks up unfinished jobs on start, so the job is not lost
+		log.Printf("[ERROR] Failed to queue erasure job %d: %v", jobID, err)
+	}
+}
+
+// This method retrieves an erasure job by ID
+func (r *erasureRepository) GetByID(id uint) (*models.ErasureJob, error) {
+	var job models.ErasureJob
+	if err := r.db.First(&job, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrErasureJobNotFound
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// This method returns jobs that are pending or were interrupted while running
+func (r *erasureRepository) Unfinished() ([]models.ErasureJob, error) {
+	var jobs []models.ErasureJob
+	err := r.db.Where("status IN ?", []string{models.JobPending, models.JobRunning}).
+		Order("id ASC").Find(&jobs).Error
+	return jobs, err
+}
+
+// This method runs the steps of the job that are not finished yet and records progress after each one
+func (r *erasureRepository) Run(job *models.ErasureJob) error {
+	if job.Status == models.JobCompleted {
+		return nil
+	}
+
+	// Find where the job stopped last time
+	next := 0
+	for i, step := range erasureSteps {
+		if step.name == job.Step {
+			next = i + 1
+		}
+	}
+
+	if err := r.db.Model(job).Updates(map[string]interface{}{"status": models.JobRunning, "error": ""}).Error; err != nil {
+		return err
+	}
+	log.Printf("[INFO] Erasure job %d for user %d running from step %d", job.ID, job.UserID, next)
+
+	for i := next; i < len(erasureSteps); i++ {
+		step := erasureSteps[i]
+		if err := step.run(r, job.UserID); err != nil {
+			log.Printf("[ERROR] Erasure job %d failed at step %s: %v", job.ID, step.name, err)
+			r.db.Model(job).Updates(map[string]interface{}{
+				"status": models.JobFailed,
+				"error":  fmt.Sprintf("%s: %v", step.name, err),
+			})
+			return err
+		}
+
+		if err := r.db.Model(job).Updates(map[string]interface{}{
+			"step":     step.name,
+			"progress": (i + 1) * 100 / len(erasureSteps),
+		}).Error; err != nil {
+			return err
+		}
+		log.Printf("[INFO] Erasure job %d finished step %s", job.ID, step.name)
+	}
+
+	now := time.Now()
+	if err := r.db.Model(job).Updates(map[string]interface{}{
+		"status":       models.JobCompleted,
+		"completed_at": &now,
+	}).Error; err != nil {
+		return err
+	}
+	log.Printf("[INFO] Erasure job %d completed, user %d erased", job.ID, job.UserID)
+	return nil
+}
+
+// Erasure steps
+
+// Makes login impossible and logs out every session before any data disappears
+//
+// The account is marked as erasing, so a job that stops halfway does not leave it open to password resets or OIDC logins.
+func (r *erasureRepository) lockAccount(userID uint) error {
+	if err := r.db.Model(&models.User{}).Where("id = ?", userID).
+		Updates(map[string]interface{}{"password": "", "totp_enabled": false, "totp_secret": "", "erasing_at": time.Now()}).Error; err != nil {
+		return err
+	}
+	if err := r.db.Where("user_id = ?", userID).Delete(&models.PersonalAccessToken{}).Error; err != nil {
+		return err
+	}
+	return NewTokenRepository(r.db, r.rdb).RevokeAllForUser(userID)
+}
+
+// Removes the user's posts from followers' timelines and drops the user's own timeline
+func (r *erasureRepository) removeFromTimelines(userID uint) error {
+	ctx := context.Background()
+
+	var ids []uint
+	if err := r.db.Model(&models.Post{}).Where("author_id = ?", userID).Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	postIDs := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		postIDs = append(postIDs, id)
+	}
+
+	if len(postIDs) > 0 {
+		followers, err := NewFollowRepository(r.db, r.rdb).GetFollowers(userID)
+		if err != nil {
+			return err
+		}
+		for _, follower := range followers {
+			if err := r.rdb.ZRem(ctx, fmt.Sprintf("timeline:%d", follower.ID), postIDs...).Err(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return r.rdb.Del(ctx, fmt.Sprintf("timeline:%d", userID)).Err()
+}
+
+// Deletes uploaded files of the user's posts and profile, paths outside the uploads directory are left alone
+func (r *erasureRepository) removeMedia(userID uint) error {
+	var mediaPaths []string
+	if err := r.db.Unscoped().Model(&models.Post{}).
+		Where("author_id = ? AND media_path <> ''", userID).
+		Pluck("media_path", &mediaPaths).Error; err != nil {
+		return err
+	}
+
+	var user models.User
+	if err := r.db.Select("avatar_path", "banner_path").First(&user, userID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	for _, path := range []string{user.AvatarPath, user.BannerPath} {
+		if path != "" {
+			mediaPaths = append(mediaPaths, path)
+		}
+	}
+
+	for _, path := range mediaPaths {
+		if !utils.IsUploadPath(path) {
+			log.Printf("[ERROR] File %s is not in the uploads directory, not removed", path)
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Deletes reactions of the user and reactions to the user's posts
+func (r *erasureRepository) deleteReactions(userID uint) error {
+	ctx := context.Background()
+
+	// Counters of posts the user reacted to are recounted by the reconcile worker
+	var reactedIDs []uint
+	if err := r.db.Model(&models.Reaction{}).Where("user_id = ?", userID).Pluck("post_id", &reactedIDs).Error; err != nil {
+		return err
+	}
+	if err := r.db.Where("user_id = ?", userID).Delete(&models.Reaction{}).Error; err != nil {
+		return err
+	}
+	for _, id := range reactedIDs {
+		if err := r.rdb.SAdd(ctx, ReactionsDirtyKey, id).Err(); err != nil {
+			return err
+		}
+	}
+
+	var postIDs []uint
+	if err := r.db.Unscoped().Model(&models.Post{}).Where("author_id = ?", userID).Pluck("id", &postIDs).Error; err != nil {
+		return err
+	}
+	reactions := NewReactionRepository(r.db, r.rdb)
+	for _, id := range postIDs {
+		if err := reactions.DeleteForPost(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Deletes the user's posts with their hashtags and mentions, reposts others made of them and the user's replies, quotes and reposts from the counters of other posts
+//
+// Posts that have replies or quotes are turned into tombstones without content or author instead, so no conversation or quote
+// loses the post it points to. Posts in the trash are deleted too, and so are the counters of trashed posts lowered.
+func (r *erasureRepository) deletePosts(userID uint) error {
+	posts := &postRepository{db: r.db, rdb: r.rdb}
+	db := r.db.Unscoped().Session(&gorm.Session{})
+
+	var reposts []models.Post
+	if err := db.Where("author_id <> ? AND repost_of_id IN (?)", userID,
+		db.Model(&models.Post{}).Select("id").Where("author_id = ?", userID)).
+		Find(&reposts).Error; err != nil {
+		return err
+	}
+	for i := range reposts {
+		if err := posts.RemovePost(&reposts[i]); err != nil {
+			return err
+		}
+	}
+
+	// Tombstones are not searchable either, so every post leaves the index
+	var postIDs []uint
+	if err := db.Model(&models.Post{}).Where("author_id = ?", userID).Pluck("id", &postIDs).Error; err != nil {
+		return err
+	}
+	index := posts.searchIndex()
+	for _, id := range postIDs {
+		if err := index.Remove(id); err != nil {
+			return err
+		}
+	}
+
+	type reference struct {
+		TargetID uint
+		Total    int64
+	}
+	counters := map[string]string{"parent_id": "reply_count", "quote_of_id": "quote_count", "repost_of_id": "repost_count"}
+	var references map[string][]reference
+
+	// Counters and posts change together, so running the step again does not count anything twice
+	err := db.Transaction(func(tx *gorm.DB) error {
+		userPosts := tx.Model(&models.Post{}).Select("id").Where("author_id = ?", userID)
+		if err := tx.Where("post_id IN (?)", userPosts).Delete(&models.PostHashtag{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("post_id IN (?) OR user_id = ?", userPosts, userID).Delete(&models.Mention{}).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := tx.Model(&models.Post{}).Where("author_id = ? AND (reply_count > 0 OR quote_count > 0)", userID).
+			Updates(map[string]interface{}{
+				"title":         "",
+				"content":       "",
+				"media_path":    "",
+				"tombstoned_at": &now,
+				"deleted_at":    nil,
+				"author_id":     nil,
+			}).Error; err != nil {
+			return err
+		}
+
+		// Only the posts that are deleted leave the counters of the posts they point to
+		references = map[string][]reference{}
+		for column := range counters {
+			var rows []reference
+			if err := tx.Model(&models.Post{}).
+				Select(column+" AS target_id, COUNT(*) AS total").
+				Where("author_id = ? AND "+column+" IS NOT NULL", userID).
+				Group(column).Scan(&rows).Error; err != nil {
+				return err
+			}
+			references[column] = rows
+		}
+		for column, rows := range references {
+			counter := counters[column]
+			for _, row := range rows {
+				if err := tx.Model(&models.Post{}).Where("id = ?", row.TargetID).
+					UpdateColumn(counter, gorm.Expr("GREATEST("+counter+" - ?, 0)", row.Total)).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		// A reply or quote that came in after the tombstones were made rolls everything back, the step runs again
+		result := tx.Where("author_id = ? AND reply_count = 0 AND quote_count = 0", userID).Delete(&models.Post{})
+		if result.Error != nil {
+			return result.Error
+		}
+		var left int64
+		if err := tx.Model(&models.Post{}).Where("author_id = ?", userID).Count(&left).Error; err != nil {
+			return err
+		}
+		if left > 0 {
+			return errErasurePostsChanged
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, column := range []string{"parent_id", "quote_of_id"} {
+		for _, row := range references[column] {
+			if err := posts.pruneTombstone(row.TargetID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *erasureRepository) deleteFollows(userID uint) error {
+	if err := r.db.Where("requester_id = ? OR target_id = ?", userID, userID).Delete(&models.FollowRequest{}).Error; err != nil {
+		return err
+	}
+	if err := r.db.Where("blocker_id = ? OR blocked_id = ?", userID, userID).Delete(&models.Block{}).Error; err != nil {
+		return err
+	}
+	if err := r.db.Where("muter_id = ? OR muted_id = ?", userID, userID).Delete(&models.Mute{}).Error; err != nil {
+		return err
+	}
+	return r.db.Where("follower_id = ? OR following_id = ?", userID, userID).Delete(&models.Follow{}).Error
+}
+
+// Deletes data archives of the user, finished or not, paths outside the export directory are left alone
+func (r *erasureRepository) deleteExports(userID uint) error {
+	var paths []string
+	if err := r.db.Model(&models.ExportJob{}).
+		Where("user_id = ? AND file_path <> ''", userID).
+		Pluck("file_path", &paths).Error; err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if !utils.IsPathInside(exportDir(), path) {
+			log.Printf("[ERROR] File %s is not in the export directory, not removed", path)
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return r.db.Where("user_id = ?", userID).Delete(&models.ExportJob{}).Error
+}
+
+func (r *erasureRepository) deleteSecurityData(userID uint) error {
+	if err := r.db.Where("user_id = ?", userID).Delete(&models.Session{}).Error; err != nil {
+		return err
+	}
+	if err := r.db.Where("user_id = ?", userID).Delete(&models.Identity{}).Error; err != nil {
+		return err
+	}
+	return r.db.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+}
+
+// Goes through the user repository so the user also leaves the search index
+func (r *erasureRepository) deleteUser(userID uint) error {
+	return NewUserRepository(r.db, r.rdb).DeleteById(userID)
+}
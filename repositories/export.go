@@ -0,0 +1,435 @@
+package repositories
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang_task/models"
+	"golang_task/utils"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// Redis list the export worker listens to
+const ExportQueueKey = "export_queue"
+
+var (
+	ErrExportJobNotFound    = errors.New("export job not found")
+	ErrExportNotReady       = errors.New("export is not ready yet")
+	ErrInvalidDownloadToken = errors.New("download link is invalid or has expired")
+)
+
+// Export Repository interface
+type ExportRepositoryInterface interface {
+	Request(userID uint) (*models.ExportJob, error)
+	GetByID(id uint) (*models.ExportJob, error)
+	GetForUser(id, userID uint) (*models.ExportJob, error)
+	Unfinished() ([]models.ExportJob, error)
+	Run(job *models.ExportJob) error
+	CreateDownloadToken(job *models.ExportJob) (string, error)
+	ConsumeDownloadToken(token string) (*models.ExportJob, error)
+	PurgeExpired() error
+}
+
+// Export repository struct
+type exportRepository struct {
+	db  *gorm.DB
+	rdb *redis.Client
+}
+
+// Export repository constructor
+func NewExportRepository(db *gorm.DB, rdb *redis.Client) ExportRepositoryInterface {
+	return &exportRepository{
+		db:  db,
+		rdb: rdb,
+	}
+}
+
+// Directory the archives are written to, EXPORT_DIR (default ./exports)
+func exportDir() string {
+	dir := os.Getenv("EXPORT_DIR")
+	if dir == "" {
+		dir = "./exports"
+	}
+	return dir
+}
+
+// How long a finished archive is kept, EXPORT_TTL in hours (default 24)
+func ExportTTL() time.Duration {
+	return time.Duration(envInt64("EXPORT_TTL", 24)) * time.Hour
+}
+
+// How long a download link can be used, EXPORT_LINK_TTL in minutes (default 15)
+func ExportLinkTTL() time.Duration {
+	return time.Duration(envInt64("EXPORT_LINK_TTL", 15)) * time.Minute
+}
+
+// Maps the sha256 of a download token to the export job id
+func exportDownloadKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return fmt.Sprintf("export_download:%s", hex.EncodeToString(sum[:]))
+}
+
+// Only the public fields of other users end up in the archive
+type exportedUser struct {
+	ID        uint   `json:"id"`
+	Username  string `json:"username"`
+	Firstname string `json:"firstname"`
+	Lastname  string `json:"lastname"`
+}
+
+// Export repository methods
+
+// This method creates an export job and queues it
+//
+// If the user already has an unfinished export, that job is returned instead.
+func (r *exportRepository) Request(userID uint) (*models.ExportJob, error) {
+	var existing models.ExportJob
+	err := r.db.Where("user_id = ? AND status IN ?", userID, []string{models.JobPending, models.JobRunning}).
+		First(&existing).Error
+	if err == nil {
+		return &existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	job := models.ExportJob{
+		UserID: userID,
+		Status: models.JobPending,
+	}
+	if err := r.db.Create(&job).Error; err != nil {
+		log.Printf("[ERROR] Failed to create export job for user %d: %v", userID, err)
+		return nil, err
+	}
+
+	if err := r.rdb.RPush(context.Background(), ExportQueueKey, job.ID).Err(); err != nil {
+		// The worker picks up unfinished jobs on start, so the job is not lost
+		log.Printf("[ERROR] Failed to queue export job %d: %v", job.ID, err)
+	}
+
+	log.Printf("[INFO] Export job %d created for user %d", job.ID, userID)
+	return &job, nil
+}
+
+// This method retrieves an export job by ID
+func (r *exportRepository) GetByID(id uint) (*models.ExportJob, error) {
+	var job models.ExportJob
+	if err := r.db.First(&job, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrExportJobNotFound
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// This method retrieves an export job, only its owner can see it
+func (r *exportRepository) GetForUser(id, userID uint) (*models.ExportJob, error) {
+	var job models.ExportJob
+	if err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&job).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrExportJobNotFound
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// This method returns jobs that are pending or were interrupted while running
+func (r *exportRepository) Unfinished() ([]models.ExportJob, error) {
+	var jobs []models.ExportJob
+	err := r.db.Where("status IN ?", []string{models.JobPending, models.JobRunning}).
+		Order("id ASC").Find(&jobs).Error
+	return jobs, err
+}
+
+// This method builds the archive of the job
+//
+// The archive is written to a temporary file first, so a crash never leaves a half written export behind.
+func (r *exportRepository) Run(job *models.ExportJob) error {
+	if job.Status == models.JobCompleted {
+		return nil
+	}
+	if err := r.db.Model(job).Updates(map[string]interface{}{"status": models.JobRunning, "error": ""}).Error; err != nil {
+		return err
+	}
+	log.Printf("[INFO] Export job %d for user %d running", job.ID, job.UserID)
+
+	path, size, err := r.buildArchive(job)
+	if err != nil {
+		log.Printf("[ERROR] Export job %d failed: %v", job.ID, err)
+		r.db.Model(job).Updates(map[string]interface{}{
+			"status": models.JobFailed,
+			"error":  err.Error(),
+		})
+		return err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(ExportTTL())
+	if err := r.db.Model(job).Updates(map[string]interface{}{
+		"status":       models.JobCompleted,
+		"file_path":    path,
+		"size":         size,
+		"completed_at": &now,
+		"expires_at":   &expiresAt,
+	}).Error; err != nil {
+		os.Remove(path)
+		return err
+	}
+
+	log.Printf("[INFO] Export job %d completed, %d bytes", job.ID, size)
+	return nil
+}
+
+func (r *exportRepository) buildArchive(job *models.ExportJob) (string, int64, error) {
+	var user models.User
+	if err := r.db.First(&user, job.UserID).Error; err != nil {
+		return "", 0, err
+	}
+
+	var posts []models.Post
+	// Posts in the trash are still the user's
+	if err := r.db.Unscoped().Where("author_id = ?", job.UserID).Order("created_at ASC").Find(&posts).Error; err != nil {
+		return "", 0, err
+	}
+
+	followRepo := NewFollowRepository(r.db, r.rdb)
+	followers, err := followRepo.GetFollowers(job.UserID)
+	if err != nil {
+		return "", 0, err
+	}
+	followings, err := followRepo.GetFollowings(job.UserID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if err := os.MkdirAll(exportDir(), 0o700); err != nil {
+		return "", 0, err
+	}
+	path := filepath.Join(exportDir(), fmt.Sprintf("export-%d-%d.zip", job.UserID, job.ID))
+	tmpPath := path + ".tmp"
+
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmpPath)
+
+	archive := zip.NewWriter(file)
+	if err := writeArchive(archive, &user, posts, followers, followings); err != nil {
+		archive.Close()
+		file.Close()
+		return "", 0, err
+	}
+	if err := archive.Close(); err != nil {
+		file.Close()
+		return "", 0, err
+	}
+	if err := file.Close(); err != nil {
+		return "", 0, err
+	}
+
+	info, err := os.Stat(tmpPath)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return "", 0, err
+	}
+	return path, info.Size(), nil
+}
+
+func writeArchive(archive *zip.Writer, user *models.User, posts []models.Post, followers, followings []models.PublicUser) error {
+	if err := writeJSON(archive, "profile.json", user); err != nil {
+		return err
+	}
+	for _, image := range []struct{ kind, path string }{{"avatar", user.AvatarPath}, {"banner", user.BannerPath}} {
+		if image.path == "" {
+			continue
+		}
+		if err := copyFile(archive, image.path, fmt.Sprintf("profile/%s-%s", image.kind, filepath.Base(image.path))); err != nil {
+			return err
+		}
+	}
+
+	// One post per line, the author is the user so it is left out
+	postsFile, err := archive.Create("posts.ndjson")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(postsFile)
+	for _, post := range posts {
+		// Deleted posts kept for their replies have nothing of the user left
+		if post.TombstonedAt != nil {
+			continue
+		}
+		if err := encoder.Encode(map[string]interface{}{
+			"id":           post.ID,
+			"title":        post.Title,
+			"content":      post.Content,
+			"media_path":   post.MediaPath,
+			"parent_id":    post.ParentID,
+			"root_id":      post.RootID,
+			"repost_of_id": post.RepostOfID,
+			"quote_of_id":  post.QuoteOfID,
+			"created_at":   post.CreatedAt,
+			"updated_at":   post.UpdatedAt,
+			"deleted_at":   post.DeletedAt,
+		}); err != nil {
+			return err
+		}
+	}
+
+	for _, post := range posts {
+		if post.MediaPath == "" {
+			continue
+		}
+		if err := copyMedia(archive, post); err != nil {
+			return err
+		}
+	}
+
+	if err := writeJSON(archive, "followers.json", publicUsers(followers)); err != nil {
+		return err
+	}
+	return writeJSON(archive, "following.json", publicUsers(followings))
+}
+
+func writeJSON(archive *zip.Writer, name string, value interface{}) error {
+	file, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// Media is stored as media/{post id}-{file name}
+func copyMedia(archive *zip.Writer, post models.Post) error {
+	return copyFile(archive, post.MediaPath, fmt.Sprintf("media/%d-%s", post.ID, filepath.Base(post.MediaPath)))
+}
+
+// Copies an uploaded file into the archive, missing files and paths outside the uploads directory are skipped
+func copyFile(archive *zip.Writer, path, name string) error {
+	if !utils.IsUploadPath(path) {
+		log.Printf("[ERROR] File %s is not in the uploads directory, skipped in export", path)
+		return nil
+	}
+	source, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Printf("[ERROR] File %s is missing, skipped in export", path)
+			return nil
+		}
+		return err
+	}
+	defer source.Close()
+
+	file, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, source)
+	return err
+}
+
+func publicUsers(users []models.PublicUser) []exportedUser {
+	result := make([]exportedUser, 0, len(users))
+	for _, user := range users {
+		result = append(result, exportedUser{
+			ID:        user.ID,
+			Username:  user.Username,
+			Firstname: user.Firstname,
+			Lastname:  user.Lastname,
+		})
+	}
+	return result
+}
+
+// This method creates a download token for a finished export, it can be used only once
+func (r *exportRepository) CreateDownloadToken(job *models.ExportJob) (string, error) {
+	if job.Status != models.JobCompleted || job.ExpiresAt == nil || time.Now().After(*job.ExpiresAt) {
+		return "", ErrExportNotReady
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	// The link never outlives the archive
+	ttl := ExportLinkTTL()
+	if remaining := time.Until(*job.ExpiresAt); remaining < ttl {
+		ttl = remaining
+	}
+	if err := r.rdb.Set(context.Background(), exportDownloadKey(token), job.ID, ttl).Err(); err != nil {
+		log.Printf("[ERROR] Failed to store download token of export %d: %v", job.ID, err)
+		return "", err
+	}
+	return token, nil
+}
+
+// This method returns the export of a download token and invalidates the token
+func (r *exportRepository) ConsumeDownloadToken(token string) (*models.ExportJob, error) {
+	if token == "" {
+		return nil, ErrInvalidDownloadToken
+	}
+
+	value, err := r.rdb.GetDel(context.Background(), exportDownloadKey(token)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrInvalidDownloadToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	jobID, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidDownloadToken
+	}
+
+	var job models.ExportJob
+	if err := r.db.First(&job, jobID).Error; err != nil {
+		return nil, ErrInvalidDownloadToken
+	}
+	if job.Status != models.JobCompleted || job.ExpiresAt == nil || time.Now().After(*job.ExpiresAt) {
+		return nil, ErrInvalidDownloadToken
+	}
+	return &job, nil
+}
+
+// This method removes archives whose time is up
+func (r *exportRepository) PurgeExpired() error {
+	var jobs []models.ExportJob
+	if err := r.db.Where("file_path <> '' AND expires_at < ?", time.Now()).Find(&jobs).Error; err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		if err := os.Remove(job.FilePath); err != nil && !os.IsNotExist(err) {
+			log.Printf("[ERROR] Failed to remove expired export %d: %v", job.ID, err)
+			continue
+		}
+		if err := r.db.Model(&job).Update("file_path", "").Error; err != nil {
+			return err
+		}
+		log.Printf("[INFO] Expired export %d removed", job.ID)
+	}
+	return nil
+}
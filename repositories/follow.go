@@ -13,12 +13,24 @@ import (
 	"gorm.io/gorm"
 )
 
+var (
+	ErrFollowRequestNotFound = errors.New("follow request not found")
+	ErrAlreadyRequested      = errors.New("you have already requested to follow this user")
+)
+
 // Follow Repository interface
 type FollowRepositoryInterface interface {
-	Follow(followerID, followingID uint) error
+	Follow(followerID, followingID uint) (bool, error)
+	CanView(viewerID, authorID uint) (bool, error)
+	ListRequests(targetID uint) ([]models.FollowRequest, error)
+	ApproveRequest(id, targetID uint) error
+	RejectRequest(id, targetID uint) error
+	ApproveAllRequests(targetID uint) error
 	IsFollowing(followerID, followingID uint) (bool, error)
-	GetFollowers(followingID uint) ([]models.User, error)
-	GetFollowings(followerID uint) ([]models.User, error)
+	GetFollowers(followingID uint) ([]models.PublicUser, error)
+	GetFollowings(followerID uint) ([]models.PublicUser, error)
+	CountFollowers(followingID uint) (int64, error)
+	CountFollowings(followerID uint) (int64, error)
 	UnFollow(followerID, followingID uint) error
 }
 
@@ -38,8 +50,16 @@ func NewFollowRepository(db *gorm.DB, rdb *redis.Client) FollowRepositoryInterfa
 
 // Follow repository methods
 
+func isDuplicate(err error) bool {
+	return strings.Contains(err.Error(), "UNIQUE") ||
+		strings.Contains(err.Error(), "constraint failed") ||
+		strings.Contains(err.Error(), "Duplicate")
+}
+
 // This method allows a user to follow another user
-func (r *followRepository) Follow(followerID, followingID uint) error {
+//
+// Following a private account only creates a follow request, then the first return value is true.
+func (r *followRepository) Follow(followerID, followingID uint) (bool, error) {
 	var followObject = models.Follow{
 		FollowerID:  followerID,
 		FollowingID: followingID,
@@ -50,23 +70,64 @@ func (r *followRepository) Follow(followerID, followingID uint) error {
 	if err := r.db.First(&following, followObject.FollowingID).Error; err != nil {
 		log.Printf("[ERROR] User %d tried to follow user %d but the following user not found", followerID, followingID)
 
-		return fmt.Errorf("following user not found")
+		return false, fmt.Errorf("following user not found")
+	}
+
+	blocked, err := NewBlockRepository(r.db, r.rdb).IsBlockedEither(followerID, followingID)
+	if err != nil {
+		return false, err
+	}
+	if blocked {
+		log.Printf("[ERROR] User %d tried to follow user %d but one has blocked the other", followerID, followingID)
+		return false, ErrBlocked
+	}
+
+	if following.IsPrivate {
+		return true, r.requestFollow(followerID, followingID)
 	}
 
 	// Check follow situation
 	if err := r.db.Create(&followObject).Error; err != nil {
-		if strings.Contains(err.Error(), "UNIQUE") ||
-			strings.Contains(err.Error(), "constraint failed") ||
-			strings.Contains(err.Error(), "Duplicate") {
+		if isDuplicate(err) {
 			log.Printf("[ERROR] User %d already followed user %d", followerID, followingID)
 
-			return fmt.Errorf("you have already followed this user")
+			return false, fmt.Errorf("you have already followed this user")
 		}
 		log.Printf("[ERROR] Error while user %d tried to follow user %d: %v", followerID, followingID, err)
 
-		return err
+		return false, err
 	}
 	log.Printf("[INFO] User %d followed user %d successfully", followerID, followingID)
+	r.backfillTimeline(followerID, followingID)
+	return false, nil
+}
+
+func (r *followRepository) requestFollow(followerID, followingID uint) error {
+	following, err := r.IsFollowing(followerID, followingID)
+	if err != nil {
+		return err
+	}
+	if following {
+		return fmt.Errorf("you have already followed this user")
+	}
+
+	request := models.FollowRequest{
+		RequesterID: followerID,
+		TargetID:    followingID,
+	}
+	if err := r.db.Create(&request).Error; err != nil {
+		if isDuplicate(err) {
+			return ErrAlreadyRequested
+		}
+		log.Printf("[ERROR] Error while user %d requested to follow user %d: %v", followerID, followingID, err)
+		return err
+	}
+	log.Printf("[INFO] User %d requested to follow user %d", followerID, followingID)
+	return nil
+}
+
+// Queues the posts of the followed user, so they show up in the new follower's timeline
+func (r *followRepository) backfillTimeline(followerID, followingID uint) {
 	postRepo := NewPostRepository(r.db, r.rdb)
 	posts, err := postRepo.GetByAuthorID(followingID)
 	if err != nil {
@@ -92,6 +153,95 @@ func (r *followRepository) Follow(followerID, followingID uint) error {
 			}
 		}
 	}
+}
+
+// This method reports whether the viewer may see the posts of the author
+//
+// Posts of private accounts are only visible to the author and approved followers,
+// and users who blocked each other can not see each other's posts.
+func (r *followRepository) CanView(viewerID, authorID uint) (bool, error) {
+	if viewerID == authorID {
+		return true, nil
+	}
+	blocked, err := NewBlockRepository(r.db, r.rdb).IsBlockedEither(viewerID, authorID)
+	if err != nil || blocked {
+		return false, err
+	}
+	var author models.User
+	if err := r.db.Select("id", "is_private").First(&author, authorID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	if !author.IsPrivate {
+		return true, nil
+	}
+	return r.IsFollowing(viewerID, authorID)
+}
+
+// This method lists the pending follow requests of a user, oldest first
+func (r *followRepository) ListRequests(targetID uint) ([]models.FollowRequest, error) {
+	requests := []models.FollowRequest{}
+	err := r.db.Preload("Requester").Where("target_id = ?", targetID).Order("created_at ASC").Find(&requests).Error
+	return requests, err
+}
+
+// This method turns a follow request into a follow and fills the follower's timeline
+func (r *followRepository) ApproveRequest(id, targetID uint) error {
+	var request models.FollowRequest
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND target_id = ?", id, targetID).First(&request).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrFollowRequestNotFound
+			}
+			return err
+		}
+		if err := tx.Delete(&request).Error; err != nil {
+			return err
+		}
+		follow := models.Follow{
+			FollowerID:  request.RequesterID,
+			FollowingID: request.TargetID,
+		}
+		if err := tx.Create(&follow).Error; err != nil && !isDuplicate(err) {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] User %d approved the follow request of user %d", targetID, request.RequesterID)
+	r.backfillTimeline(request.RequesterID, request.TargetID)
+	return nil
+}
+
+// This method deletes a follow request without following
+func (r *followRepository) RejectRequest(id, targetID uint) error {
+	result := r.db.Where("id = ? AND target_id = ?", id, targetID).Delete(&models.FollowRequest{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrFollowRequestNotFound
+	}
+	log.Printf("[INFO] User %d rejected follow request %d", targetID, id)
+	return nil
+}
+
+// This method approves every pending request, used when an account becomes public
+func (r *followRepository) ApproveAllRequests(targetID uint) error {
+	requests, err := r.ListRequests(targetID)
+	if err != nil {
+		return err
+	}
+	for _, request := range requests {
+		if err := r.ApproveRequest(request.ID, targetID); err != nil && !errors.Is(err, ErrFollowRequestNotFound) {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -112,6 +262,17 @@ func (r *followRepository) UnFollow(followerID, followingID uint) error {
 
 	// Checking if the user is following the user with id followingID
 	result := r.db.Where("follower_id = ? AND following_id = ? ", followObject.FollowerID, followObject.FollowingID).Delete(&models.Follow{})
+	if result.Error == nil && result.RowsAffected == 0 {
+		// Unfollowing a private account that was only requested cancels the request
+		canceled := r.db.Where("requester_id = ? AND target_id = ?", followerID, followingID).Delete(&models.FollowRequest{})
+		if canceled.Error != nil {
+			return canceled.Error
+		}
+		if canceled.RowsAffected > 0 {
+			log.Printf("[INFO] User %d canceled the follow request to user %d", followerID, followingID)
+			return nil
+		}
+	}
 	if result.RowsAffected == 0 {
 		log.Printf("[ERROR] User %d tried to unfollow user %d but was not following", followerID, followingID)
 
@@ -154,8 +315,8 @@ func (r *followRepository) IsFollowing(followerID, followingID uint) (bool, erro
 }
 
 // This function get the user's followers
-func (r *followRepository) GetFollowers(followingID uint) ([]models.User, error) {
-	var followers []models.User
+func (r *followRepository) GetFollowers(followingID uint) ([]models.PublicUser, error) {
+	var followers []models.PublicUser
 	if err := r.db.Model(&models.Follow{}).Select("users.*").
 		Joins("JOIN users ON users.id = follows.follower_id").
 		Where("follows.following_id = ?", followingID).
@@ -170,8 +331,8 @@ func (r *followRepository) GetFollowers(followingID uint) ([]models.User, error)
 }
 
 // This function get the user's followings
-func (r *followRepository) GetFollowings(followerID uint) ([]models.User, error) {
-	var followings []models.User
+func (r *followRepository) GetFollowings(followerID uint) ([]models.PublicUser, error) {
+	var followings []models.PublicUser
 	if err := r.db.Model(&models.Follow{}).Select("users.*").
 		Joins("JOIN users ON users.id = follows.following_id").
 		Where("follows.follower_id = ?", followerID).
@@ -184,3 +345,17 @@ func (r *followRepository) GetFollowings(followerID uint) ([]models.User, error)
 
 	return followings, nil
 }
+
+// This function counts the user's followers
+func (r *followRepository) CountFollowers(followingID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Follow{}).Where("following_id = ?", followingID).Count(&count).Error
+	return count, err
+}
+
+// This function counts the users the user follows
+func (r *followRepository) CountFollowings(followerID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Follow{}).Where("follower_id = ?", followerID).Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,226 @@
+package repositories
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang_task/models"
+	"golang_task/utils"
+	"log"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// How long a started provider login can be finished
+const oidcStateTTL = 10 * time.Minute
+
+var (
+	ErrInvalidOIDCState   = errors.New("login has expired or was already used, please start again")
+	ErrIdentityNoEmail    = errors.New("the provider did not share an email address")
+	ErrIdentityEmailTaken = errors.New("an account with this email already exists, log in with your password first")
+)
+
+// OIDCLoginState is kept between redirecting to the provider and its callback
+type OIDCLoginState struct {
+	Provider     string `json:"provider"`
+	CodeVerifier string `json:"code_verifier"`
+	Nonce        string `json:"nonce"`
+}
+
+// Identity Repository interface
+type IdentityRepositoryInterface interface {
+	SaveState(state string, login OIDCLoginState) error
+	ConsumeState(state string) (*OIDCLoginState, error)
+	Resolve(provider string, claims *utils.IDTokenClaims) (*models.User, bool, error)
+}
+
+// Identity repository struct
+type identityRepository struct {
+	db    *gorm.DB
+	rdb   *redis.Client
+	users UserRepositoryInterface
+}
+
+// Identity repository constructor
+func NewIdentityRepository(db *gorm.DB, rdb *redis.Client) IdentityRepositoryInterface {
+	return &identityRepository{
+		db:    db,
+		rdb:   rdb,
+		users: NewUserRepository(db, rdb),
+	}
+}
+
+func oidcStateKey(state string) string {
+	return fmt.Sprintf("oidc_state:%s", state)
+}
+
+// Identity repository methods
+
+// This method remembers a started login under its state parameter
+func (r *identityRepository) SaveState(state string, login OIDCLoginState) error {
+	data, err := json.Marshal(login)
+	if err != nil {
+		return err
+	}
+	return r.rdb.Set(context.Background(), oidcStateKey(state), data, oidcStateTTL).Err()
+}
+
+// This method returns a started login and forgets it, so a callback can be used only once
+func (r *identityRepository) ConsumeState(state string) (*OIDCLoginState, error) {
+	if state == "" {
+		return nil, ErrInvalidOIDCState
+	}
+	data, err := r.rdb.GetDel(context.Background(), oidcStateKey(state)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrInvalidOIDCState
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var login OIDCLoginState
+	if err := json.Unmarshal(data, &login); err != nil {
+		return nil, ErrInvalidOIDCState
+	}
+	return &login, nil
+}
+
+// This method finds the user of a provider account, creating the user on first login
+//
+// A provider account is linked to an existing user only if both sides have verified
+// the email, otherwise anyone could take over an account through a provider that
+// does not check emails. The second return value reports whether the user is new.
+func (r *identityRepository) Resolve(provider string, claims *utils.IDTokenClaims) (*models.User, bool, error) {
+	var identity models.Identity
+	err := r.db.Where("provider = ? AND subject = ?", provider, claims.Subject).First(&identity).Error
+	if err == nil {
+		user, err := r.users.GetByID(identity.UserID)
+		return user, false, err
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, err
+	}
+
+	email := strings.ToLower(strings.TrimSpace(claims.Email))
+	if email == "" {
+		return nil, false, ErrIdentityNoEmail
+	}
+
+	user, err := r.users.GetByEmail(email)
+	switch {
+	case err == nil:
+		if !bool(claims.EmailVerified) || user.EmailVerifiedAt == nil {
+			return nil, false, ErrIdentityEmailTaken
+		}
+		if err := r.link(user.ID, provider, claims.Subject, email); err != nil {
+			return nil, false, err
+		}
+		log.Printf("[INFO] %s account linked to existing user %d", provider, user.ID)
+		return user, false, nil
+	case !errors.Is(err, ErrUserNotFound):
+		return nil, false, err
+	}
+
+	user, err = r.createUser(claims, email)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := r.link(user.ID, provider, claims.Subject, email); err != nil {
+		return nil, false, err
+	}
+	log.Printf("[INFO] User %d created from %s login", user.ID, provider)
+	return user, true, nil
+}
+
+func (r *identityRepository) link(userID uint, provider, subject, email string) error {
+	identity := models.Identity{
+		UserID:   userID,
+		Provider: provider,
+		Subject:  subject,
+		Email:    email,
+	}
+	if err := r.db.Create(&identity).Error; err != nil {
+		log.Printf("[ERROR] Failed to link %s account to user %d: %v", provider, userID, err)
+		return err
+	}
+	return nil
+}
+
+var usernameUnsafeChars = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// Username from the provider's preferred username or the email, made unique with a number if needed
+func usernameCandidate(claims *utils.IDTokenClaims, email string, attempt int) (string, error) {
+	base := claims.PreferredUsername
+	if base == "" || strings.Contains(base, "@") {
+		base = strings.SplitN(email, "@", 2)[0]
+	}
+	base = usernameUnsafeChars.ReplaceAllString(strings.ToLower(base), "")
+	if len(base) > 40 {
+		base = base[:40]
+	}
+	if base == "" {
+		base = "user"
+	}
+	if attempt == 0 {
+		return base, nil
+	}
+
+	suffix, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s_%d", base, suffix.Int64()), nil
+}
+
+func (r *identityRepository) createUser(claims *utils.IDTokenClaims, email string) (*models.User, error) {
+	// The account gets a random password, the user can set one with a password reset
+	password, err := utils.NewOIDCSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	firstname, lastname := claims.GivenName, claims.FamilyName
+	if firstname == "" && lastname == "" {
+		parts := strings.SplitN(strings.TrimSpace(claims.Name), " ", 2)
+		firstname = parts[0]
+		if len(parts) == 2 {
+			lastname = parts[1]
+		}
+	}
+
+	for attempt := 0; attempt < 5; attempt++ {
+		username, err := usernameCandidate(claims, email, attempt)
+		if err != nil {
+			return nil, err
+		}
+		user := models.User{
+			Firstname: firstname,
+			Lastname:  lastname,
+			Username:  username,
+			Email:     email,
+			Password:  password,
+		}
+		err = r.users.Create(&user)
+		if errors.Is(err, ErrUserAlreadyExists) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if claims.EmailVerified {
+			if err := r.users.MarkEmailVerified(user.ID, email); err != nil {
+				return nil, err
+			}
+		}
+		return r.users.GetByID(user.ID)
+	}
+	return nil, ErrUserAlreadyExists
+}
@@ -0,0 +1,179 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Login Attempt Repository interface
+type LoginAttemptRepositoryInterface interface {
+	Check(account, ip string) (time.Duration, error)
+	RecordFailure(account, ip string) (time.Duration, error)
+	Reset(account string) error
+	ClearLockout(account string) error
+}
+
+// Lockout policy, every value can be overridden from the environment
+type LoginAttemptPolicy struct {
+	// Failures allowed per account before it is locked, LOGIN_MAX_ATTEMPTS
+	MaxAccountAttempts int64
+	// Failures allowed per IP before it is locked, LOGIN_MAX_ATTEMPTS_PER_IP
+	MaxIPAttempts int64
+	// Failures are forgotten after this much time without a new one, LOGIN_FAILURE_WINDOW in minutes
+	Window time.Duration
+	// First lockout, doubled for every further failure, LOGIN_LOCKOUT_BASE in seconds
+	BaseLockout time.Duration
+	// Upper bound of a lockout, LOGIN_LOCKOUT_MAX in seconds
+	MaxLockout time.Duration
+}
+
+func envInt64(key string, fallback int64) int64 {
+	value, err := strconv.ParseInt(os.Getenv(key), 10, 64)
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// This function reads the lockout policy from the environment
+func LoginAttemptPolicyFromEnv() LoginAttemptPolicy {
+	return LoginAttemptPolicy{
+		MaxAccountAttempts: envInt64("LOGIN_MAX_ATTEMPTS", 5),
+		MaxIPAttempts:      envInt64("LOGIN_MAX_ATTEMPTS_PER_IP", 20),
+		Window:             time.Duration(envInt64("LOGIN_FAILURE_WINDOW", 60)) * time.Minute,
+		BaseLockout:        time.Duration(envInt64("LOGIN_LOCKOUT_BASE", 30)) * time.Second,
+		MaxLockout:         time.Duration(envInt64("LOGIN_LOCKOUT_MAX", 3600)) * time.Second,
+	}
+}
+
+// This method returns the lockout for the given number of failures, zero if it is still allowed
+func (p LoginAttemptPolicy) lockoutFor(failures, maxAttempts int64) time.Duration {
+	if failures < maxAttempts {
+		return 0
+	}
+	lockout := p.BaseLockout
+	for i := maxAttempts; i < failures && lockout < p.MaxLockout; i++ {
+		lockout *= 2
+	}
+	if lockout > p.MaxLockout {
+		lockout = p.MaxLockout
+	}
+	return lockout
+}
+
+// Login attempt repository struct
+type loginAttemptRepository struct {
+	rdb    *redis.Client
+	policy LoginAttemptPolicy
+}
+
+// Login attempt repository constructor
+func NewLoginAttemptRepository(rdb *redis.Client, policy LoginAttemptPolicy) LoginAttemptRepositoryInterface {
+	return &loginAttemptRepository{
+		rdb:    rdb,
+		policy: policy,
+	}
+}
+
+// Account key of a known user. Unknown identifiers get their own key so they are throttled the same way.
+func LoginAccountKey(userID uint, identifier string) string {
+	if userID != 0 {
+		return fmt.Sprintf("user:%d", userID)
+	}
+	return "unknown:" + strings.ToLower(strings.TrimSpace(identifier))
+}
+
+func loginFailuresKey(kind, value string) string {
+	return fmt.Sprintf("login_failures:%s:%s", kind, value)
+}
+
+func loginLockKey(kind, value string) string {
+	return fmt.Sprintf("login_lock:%s:%s", kind, value)
+}
+
+// Login attempt repository methods
+
+// This method returns how long the account or the IP is still locked, zero if login is allowed
+func (r *loginAttemptRepository) Check(account, ip string) (time.Duration, error) {
+	ctx := context.Background()
+
+	pipe := r.rdb.Pipeline()
+	accountTTL := pipe.PTTL(ctx, loginLockKey("account", account))
+	ipTTL := pipe.PTTL(ctx, loginLockKey("ip", ip))
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return 0, err
+	}
+
+	// PTTL is negative when the key does not exist
+	retryAfter := accountTTL.Val()
+	if ipTTL.Val() > retryAfter {
+		retryAfter = ipTTL.Val()
+	}
+	if retryAfter < 0 {
+		return 0, nil
+	}
+	return retryAfter, nil
+}
+
+// This method counts a failed login and locks the account or IP once the limit is reached
+//
+// It returns the new lockout, zero if the next attempt is still allowed.
+func (r *loginAttemptRepository) RecordFailure(account, ip string) (time.Duration, error) {
+	ctx := context.Background()
+
+	pipe := r.rdb.TxPipeline()
+	accountFailures := pipe.Incr(ctx, loginFailuresKey("account", account))
+	pipe.Expire(ctx, loginFailuresKey("account", account), r.policy.Window)
+	ipFailures := pipe.Incr(ctx, loginFailuresKey("ip", ip))
+	pipe.Expire(ctx, loginFailuresKey("ip", ip), r.policy.Window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+
+	accountLockout := r.policy.lockoutFor(accountFailures.Val(), r.policy.MaxAccountAttempts)
+	ipLockout := r.policy.lockoutFor(ipFailures.Val(), r.policy.MaxIPAttempts)
+
+	if accountLockout > 0 {
+		log.Printf("[ERROR] Login for account %s locked for %s after %d failures", account, accountLockout, accountFailures.Val())
+		if err := r.rdb.Set(ctx, loginLockKey("account", account), 1, accountLockout).Err(); err != nil {
+			return 0, err
+		}
+	}
+	if ipLockout > 0 {
+		log.Printf("[ERROR] Login from ip %s locked for %s after %d failures", ip, ipLockout, ipFailures.Val())
+		if err := r.rdb.Set(ctx, loginLockKey("ip", ip), 1, ipLockout).Err(); err != nil {
+			return 0, err
+		}
+	}
+
+	if ipLockout > accountLockout {
+		return ipLockout, nil
+	}
+	return accountLockout, nil
+}
+
+// This method forgets the failures of an account after a successful login
+//
+// IP counters are kept, one good password must not reset an attacker's budget.
+func (r *loginAttemptRepository) Reset(account string) error {
+	return r.rdb.Del(context.Background(), loginFailuresKey("account", account)).Err()
+}
+
+// This method removes the lockout and the failures of an account
+func (r *loginAttemptRepository) ClearLockout(account string) error {
+	ctx := context.Background()
+	if err := r.rdb.Del(ctx, loginFailuresKey("account", account), loginLockKey("account", account)).Err(); err != nil {
+		log.Printf("[ERROR] Failed to clear lockout of account %s: %v", account, err)
+		return err
+	}
+	log.Printf("[INFO] Lockout of account %s cleared", account)
+	return nil
+}
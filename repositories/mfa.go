@@ -0,0 +1,247 @@
+package repositories
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"golang_task/models"
+	"golang_task/utils"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// Number of recovery codes created when two-factor authentication is enabled
+const recoveryCodeCount = 10
+
+var (
+	ErrInvalidMfaCode     = errors.New("two-factor code is invalid")
+	ErrMfaAlreadyEnabled  = errors.New("two-factor authentication is already enabled")
+	ErrMfaNotEnabled      = errors.New("two-factor authentication is not enabled")
+	ErrMfaSetupNotStarted = errors.New("two-factor setup has not been started")
+)
+
+// MfaSetup is what the user needs to add the account to an authenticator app
+type MfaSetup struct {
+	Secret     string `json:"secret" example:"JBSWY3DPEHPK3PXP"`
+	OtpauthURL string `json:"otpauth_url" example:"otpauth://totp/SocialMedia:johndoe?secret=JBSWY3DPEHPK3PXP"`
+}
+
+// Mfa Repository interface
+type MfaRepositoryInterface interface {
+	BeginSetup(userID uint) (*MfaSetup, error)
+	ConfirmSetup(userID uint, code string) ([]string, error)
+	Disable(userID uint, code string) error
+	Verify(userID uint, code string) error
+}
+
+// Mfa repository struct
+type mfaRepository struct {
+	db    *gorm.DB
+	rdb   *redis.Client
+	clock utils.Clock
+}
+
+// Mfa repository constructor
+//
+// The clock decides which TOTP codes are valid, tests can pass a utils.FakeClock.
+func NewMfaRepository(db *gorm.DB, rdb *redis.Client, clock utils.Clock) MfaRepositoryInterface {
+	return &mfaRepository{
+		db:    db,
+		rdb:   rdb,
+		clock: clock,
+	}
+}
+
+// Issuer shown in authenticator apps, TOTP_ISSUER
+func totpIssuer() string {
+	issuer := os.Getenv("TOTP_ISSUER")
+	if issuer == "" {
+		issuer = "SocialMedia"
+	}
+	return issuer
+}
+
+// Remembers used time steps so a code can not be replayed in its validity window
+func usedTotpStepKey(userID uint, step int64) string {
+	return fmt.Sprintf("totp_used:%d:%d", userID, step)
+}
+
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateRecoveryCode() (string, error) {
+	buf := make([]byte, 10)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(buf))
+	return code[:8] + "-" + code[8:16], nil
+}
+
+// Mfa repository methods
+
+// This method creates a new secret for the user, it is not enforced until ConfirmSetup
+func (r *mfaRepository) BeginSetup(userID uint) (*MfaSetup, error) {
+	var user models.User
+	if err := r.db.First(&user, userID).Error; err != nil {
+		return nil, ErrUserNotFound
+	}
+	if user.TOTPEnabled {
+		return nil, ErrMfaAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.db.Model(&user).Update("totp_secret", secret).Error; err != nil {
+		log.Printf("[ERROR] Failed to store totp secret of user %d: %v", userID, err)
+		return nil, err
+	}
+
+	log.Printf("[INFO] Two-factor setup started for user %d", userID)
+	return &MfaSetup{
+		Secret:     secret,
+		OtpauthURL: utils.TOTPURI(totpIssuer(), user.Username, secret),
+	}, nil
+}
+
+// This method enables two-factor authentication once the user proves the app works
+//
+// It returns the recovery codes in plain text, only their hashes are stored.
+func (r *mfaRepository) ConfirmSetup(userID uint, code string) ([]string, error) {
+	var user models.User
+	if err := r.db.First(&user, userID).Error; err != nil {
+		return nil, ErrUserNotFound
+	}
+	if user.TOTPEnabled {
+		return nil, ErrMfaAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrMfaSetupNotStarted
+	}
+	if err := r.verifyTOTP(&user, code); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	recoveryCodes := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		recoveryCodes = append(recoveryCodes, models.RecoveryCode{UserID: userID, CodeHash: hashRecoveryCode(code)})
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&recoveryCodes).Error; err != nil {
+			return err
+		}
+		return tx.Model(&user).Update("totp_enabled", true).Error
+	})
+	if err != nil {
+		log.Printf("[ERROR] Failed to enable two-factor authentication for user %d: %v", userID, err)
+		return nil, err
+	}
+
+	log.Printf("[INFO] Two-factor authentication enabled for user %d", userID)
+	return codes, nil
+}
+
+// This method turns two-factor authentication off, it needs a valid code or recovery code
+func (r *mfaRepository) Disable(userID uint, code string) error {
+	if err := r.Verify(userID, code); err != nil {
+		return err
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).Where("id = ?", userID).
+			Updates(map[string]interface{}{"totp_enabled": false, "totp_secret": ""}).Error
+	})
+	if err != nil {
+		log.Printf("[ERROR] Failed to disable two-factor authentication for user %d: %v", userID, err)
+		return err
+	}
+
+	log.Printf("[INFO] Two-factor authentication disabled for user %d", userID)
+	return nil
+}
+
+// This method checks a TOTP code or, if that fails, a recovery code
+//
+// Both can be used only once.
+func (r *mfaRepository) Verify(userID uint, code string) error {
+	var user models.User
+	if err := r.db.First(&user, userID).Error; err != nil {
+		return ErrUserNotFound
+	}
+	if !user.TOTPEnabled {
+		return ErrMfaNotEnabled
+	}
+
+	if err := r.verifyTOTP(&user, code); err == nil {
+		return nil
+	} else if !errors.Is(err, ErrInvalidMfaCode) {
+		return err
+	}
+
+	return r.useRecoveryCode(userID, code)
+}
+
+func (r *mfaRepository) verifyTOTP(user *models.User, code string) error {
+	step, ok := utils.ValidateTOTP(user.TOTPSecret, code, r.clock.Now())
+	if !ok {
+		return ErrInvalidMfaCode
+	}
+
+	// The key lives as long as the accepted window around the step
+	fresh, err := r.rdb.SetNX(context.Background(), usedTotpStepKey(user.ID, step), 1, 3*30*time.Second).Result()
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		log.Printf("[ERROR] User %d tried to reuse a two-factor code", user.ID)
+		return ErrInvalidMfaCode
+	}
+	return nil
+}
+
+func (r *mfaRepository) useRecoveryCode(userID uint, code string) error {
+	if strings.TrimSpace(code) == "" {
+		return ErrInvalidMfaCode
+	}
+
+	// The update doubles as the check, so a code can not be used twice concurrently
+	result := r.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hashRecoveryCode(code)).
+		Update("used_at", r.clock.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidMfaCode
+	}
+
+	log.Printf("[INFO] User %d used a recovery code", userID)
+	return nil
+}
@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"errors"
+	"golang_task/internal/testutil"
+	"golang_task/models"
+	"golang_task/utils"
+	"testing"
+	"time"
+)
+
+func TestMfaVerifyRejectsReplayedCode(t *testing.T) {
+	db := testutil.NewDB(t)
+	rdb := testutil.NewRedis(t)
+	clock := utils.NewFakeClock(time.Unix(1700000010, 0))
+	repo := NewMfaRepository(db, rdb, clock)
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := models.User{
+		Firstname:   "John",
+		Lastname:    "Doe",
+		Username:    "johndoe",
+		Email:       "john@example.com",
+		Password:    "hash",
+		TOTPSecret:  secret,
+		TOTPEnabled: true,
+	}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	code, err := utils.TOTPCode(secret, utils.TOTPStep(clock.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Verify(user.ID, code); err != nil {
+		t.Fatalf("first use of the code: %v", err)
+	}
+	if err := repo.Verify(user.ID, code); !errors.Is(err, ErrInvalidMfaCode) {
+		t.Fatalf("replay in the same step: got %v, want %v", err, ErrInvalidMfaCode)
+	}
+
+	// The code is still inside the drift window of the next step, but its step was used
+	clock.Advance(30 * time.Second)
+	if err := repo.Verify(user.ID, code); !errors.Is(err, ErrInvalidMfaCode) {
+		t.Fatalf("replay in the next step: got %v, want %v", err, ErrInvalidMfaCode)
+	}
+
+	next, err := utils.TOTPCode(secret, utils.TOTPStep(clock.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Verify(user.ID, next); err != nil {
+		t.Fatalf("code of the next step: %v", err)
+	}
+}
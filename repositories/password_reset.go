@@ -0,0 +1,134 @@
+package repositories
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrInvalidResetToken = errors.New("reset token is invalid or has expired")
+
+// Password Reset Repository interface
+type PasswordResetRepositoryInterface interface {
+	CreateToken(userID uint) (string, error)
+	LookupToken(token string) (uint, error)
+	ConsumeToken(token string) (uint, error)
+}
+
+// Password reset repository struct
+//
+// Only the sha256 of a token is stored, so a Redis dump does not leak usable tokens.
+type passwordResetRepository struct {
+	rdb *redis.Client
+}
+
+// Password reset repository constructor
+func NewPasswordResetRepository(rdb *redis.Client) PasswordResetRepositoryInterface {
+	return &passwordResetRepository{
+		rdb: rdb,
+	}
+}
+
+// Reset token lifetime, PASSWORD_RESET_TTL in minutes (default 30)
+func PasswordResetTTL() time.Duration {
+	minutes, err := strconv.ParseUint(os.Getenv("PASSWORD_RESET_TTL"), 10, 64)
+	if err != nil || minutes == 0 {
+		minutes = 30
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Maps a token hash to the user id
+func passwordResetKey(tokenHash string) string {
+	return fmt.Sprintf("password_reset:%s", tokenHash)
+}
+
+// Holds the hash of the latest token of a user so older ones can be dropped
+func passwordResetUserKey(userID uint) string {
+	return fmt.Sprintf("password_reset_user:%d", userID)
+}
+
+// Password reset repository methods
+
+// This method creates a new reset token and invalidates the previous one of the user
+func (r *passwordResetRepository) CreateToken(userID uint) (string, error) {
+	ctx := context.Background()
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	tokenHash := hashResetToken(token)
+	ttl := PasswordResetTTL()
+
+	previousHash, err := r.rdb.GetSet(ctx, passwordResetUserKey(userID), tokenHash).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return "", err
+	}
+
+	pipe := r.rdb.TxPipeline()
+	if previousHash != "" {
+		pipe.Del(ctx, passwordResetKey(previousHash))
+	}
+	pipe.Expire(ctx, passwordResetUserKey(userID), ttl)
+	pipe.Set(ctx, passwordResetKey(tokenHash), userID, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("[ERROR] Failed to store password reset token for user %d: %v", userID, err)
+		return "", err
+	}
+
+	log.Printf("[INFO] Password reset token created for user %d", userID)
+	return token, nil
+}
+
+// This method returns the user of a reset token without using it up
+func (r *passwordResetRepository) LookupToken(token string) (uint, error) {
+	if token == "" {
+		return 0, ErrInvalidResetToken
+	}
+
+	userID, err := r.rdb.Get(context.Background(), passwordResetKey(hashResetToken(token))).Uint64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, ErrInvalidResetToken
+		}
+		return 0, err
+	}
+	return uint(userID), nil
+}
+
+// This method returns the user of a reset token and deletes it, so it works only once
+func (r *passwordResetRepository) ConsumeToken(token string) (uint, error) {
+	ctx := context.Background()
+	if token == "" {
+		return 0, ErrInvalidResetToken
+	}
+
+	userID, err := r.rdb.GetDel(ctx, passwordResetKey(hashResetToken(token))).Uint64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, ErrInvalidResetToken
+		}
+		return 0, err
+	}
+	r.rdb.Del(ctx, passwordResetUserKey(uint(userID)))
+
+	log.Printf("[INFO] Password reset token used for user %d", userID)
+	return uint(userID), nil
+}
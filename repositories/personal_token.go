@@ -0,0 +1,155 @@
+package repositories
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"golang_task/models"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// Personal access tokens start with this, so they can be told apart from JWTs and found by secret scanners
+const PersonalTokenPrefix = "pat_"
+
+// How often last used is written for a token
+const personalTokenUsePeriod = time.Minute
+
+var (
+	ErrInvalidPersonalToken  = errors.New("personal access token is invalid or has expired")
+	ErrPersonalTokenNotFound = errors.New("personal access token not found")
+	ErrInvalidScope          = errors.New("unknown scope")
+)
+
+// Personal Token Repository interface
+type PersonalTokenRepositoryInterface interface {
+	Create(userID uint, name string, scopes []string, ttl time.Duration) (string, *models.PersonalAccessToken, error)
+	List(userID uint) ([]models.PersonalAccessToken, error)
+	Delete(id, userID uint) error
+	Authenticate(token string) (*models.PersonalAccessToken, error)
+}
+
+// Personal token repository struct
+type personalTokenRepository struct {
+	db  *gorm.DB
+	rdb *redis.Client
+}
+
+// Personal token repository constructor
+func NewPersonalTokenRepository(db *gorm.DB, rdb *redis.Client) PersonalTokenRepositoryInterface {
+	return &personalTokenRepository{
+		db:  db,
+		rdb: rdb,
+	}
+}
+
+func hashPersonalToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Exists while last used was written recently
+func personalTokenUsedKey(id uint) string {
+	return fmt.Sprintf("pat_used:%d", id)
+}
+
+// This function splits the stored scopes of a token
+func PersonalTokenScopes(token *models.PersonalAccessToken) []string {
+	return strings.Fields(token.Scopes)
+}
+
+// Personal token repository methods
+
+// This method creates a token and returns it in plain text, it can not be shown again
+func (r *personalTokenRepository) Create(userID uint, name string, scopes []string, ttl time.Duration) (string, *models.PersonalAccessToken, error) {
+	granted := []string{}
+	for _, scope := range scopes {
+		if !slices.Contains(models.AllScopes, scope) {
+			return "", nil, fmt.Errorf("%w: %s", ErrInvalidScope, scope)
+		}
+		if !slices.Contains(granted, scope) {
+			granted = append(granted, scope)
+		}
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, err
+	}
+	token := PersonalTokenPrefix + base64.RawURLEncoding.EncodeToString(buf)
+
+	record := models.PersonalAccessToken{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    token[:len(PersonalTokenPrefix)+8],
+		TokenHash: hashPersonalToken(token),
+		Scopes:    strings.Join(granted, " "),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := r.db.Create(&record).Error; err != nil {
+		log.Printf("[ERROR] Failed to create personal access token for user %d: %v", userID, err)
+		return "", nil, err
+	}
+
+	log.Printf("[INFO] Personal access token %d created for user %d with scopes %s", record.ID, userID, record.Scopes)
+	return token, &record, nil
+}
+
+// This method lists the tokens of a user, newest first
+func (r *personalTokenRepository) List(userID uint) ([]models.PersonalAccessToken, error) {
+	tokens := []models.PersonalAccessToken{}
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// This method deletes a token of a user, it stops working at once
+func (r *personalTokenRepository) Delete(id, userID uint) error {
+	result := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.PersonalAccessToken{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrPersonalTokenNotFound
+	}
+	log.Printf("[INFO] Personal access token %d of user %d deleted", id, userID)
+	return nil
+}
+
+// This method finds the token, it must not be expired and its owner must not be suspended
+//
+// Last used is updated at most once a minute.
+func (r *personalTokenRepository) Authenticate(token string) (*models.PersonalAccessToken, error) {
+	if !strings.HasPrefix(token, PersonalTokenPrefix) {
+		return nil, ErrInvalidPersonalToken
+	}
+
+	var record models.PersonalAccessToken
+	err := r.db.Joins("JOIN users ON users.id = personal_access_tokens.user_id").
+		Where("personal_access_tokens.token_hash = ? AND personal_access_tokens.expires_at > ? AND users.suspended_at IS NULL",
+			hashPersonalToken(token), time.Now()).
+		First(&record).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidPersonalToken
+		}
+		return nil, err
+	}
+
+	fresh, err := r.rdb.SetNX(context.Background(), personalTokenUsedKey(record.ID), 1, personalTokenUsePeriod).Result()
+	if err == nil && fresh {
+		now := time.Now()
+		if err := r.db.Model(&record).Update("last_used_at", &now).Error; err != nil {
+			log.Printf("[ERROR] Failed to update last use of personal access token %d: %v", record.ID, err)
+		}
+	}
+	return &record, nil
+}
@@ -7,22 +7,51 @@ import (
 	"golang_task/models"
 	"golang_task/utils"
 	"log"
+	"os"
+	"slices"
 	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrPostDeleted        = errors.New("post was deleted")
+	ErrRepostNotEditable  = errors.New("a repost can not be edited")
+	ErrAlreadyReposted    = errors.New("you have already reposted this post")
+	ErrNotReposted        = errors.New("you have not reposted this post")
+	ErrCannotSharePrivate = errors.New("posts of private accounts can not be shared")
+	ErrPostNotInTrash     = errors.New("post is not in the trash")
+	ErrPostNotFound       = errors.New("post not found")
 )
 
 // Post Repository interface
 type PostRepositoryInterface interface {
 	Create(post *models.Post) error
+	CreateReply(parent *models.Post, reply *models.Post) error
+	GetThread(rootID, viewerID, afterID uint, limit int) (*models.Post, []models.Post, uint, error)
+	GetByHashtag(tag string, viewerID, beforeID uint, limit int) ([]models.Post, uint, error)
+	Search(query PostSearchQuery, viewerID uint) ([]models.Post, error)
 	GetByID(id uint) (*models.Post, error)
+	GetVisibleByID(id, viewerID uint) (*models.Post, error)
 	GetPostsByIDs(postIds []uint) ([]models.Post, error)
 	GetByAuthorID(authorID uint) ([]models.Post, error)
-	GetByAuthorUsername(username string) ([]models.Post, error)
+	GetByAuthorUsername(username string, viewerID uint, offset, limit int) ([]models.Post, error)
+	CountByAuthorID(authorID uint) (int64, error)
 	UpdatePost(post *models.Post, userID uint, updates interface{}) error
 	DeletePost(post *models.Post, userID uint) error
+	GetTrash(userID uint, offset, limit int) ([]models.Post, error)
+	GetTrashedByID(id uint) (*models.Post, error)
+	RestorePost(post *models.Post, userID uint) error
+	PurgeTrash(before time.Time) (int, error)
+	RemovePost(post *models.Post) error
+	Repost(original *models.Post, userID uint) (*models.Post, error)
+	Unrepost(original *models.Post, userID uint) error
+	Quote(original *models.Post, quote *models.Post) error
 	GetTimeline(userID uint, start, end int64) ([]models.Post, error)
 	GetFollowingsPosts(userID uint, start, end int64) ([]models.Post, error)
 }
@@ -47,13 +76,19 @@ func NewPostRepository(db *gorm.DB, rdb *redis.Client) PostRepositoryInterface {
 //
 // If the error is nil, the post was created successfully.
 func (r *postRepository) Create(post *models.Post) error {
-	var err error
-	if err = r.db.Create(post).Error; err != nil {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(post).Error; err != nil {
+			return err
+		}
+		return saveEntities(tx, post)
+	})
+	if err != nil {
 		log.Printf("[ERROR] Failed to create post for author %d: %v", post.AuthorID, err)
 
 		return err
 	}
 
+	r.index(post)
 	utils.PostQueue(post, r.rdb, true)
 	log.Printf("[INFO] Post added to queue successfully: ID=%d, AuthorID=%d", post.ID, post.AuthorID)
 
@@ -63,6 +98,198 @@ func (r *postRepository) Create(post *models.Post) error {
 	return nil
 }
 
+// This method creates a reply to a post
+//
+// The reply is put in the conversation of the parent and the parent's reply count goes up.
+func (r *postRepository) CreateReply(parent *models.Post, reply *models.Post) error {
+	if parent.TombstonedAt != nil {
+		return ErrPostDeleted
+	}
+	rootID := parent.ID
+	if parent.RootID != nil {
+		rootID = *parent.RootID
+	}
+	reply.ParentID = &parent.ID
+	reply.RootID = &rootID
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(reply).Error; err != nil {
+			return err
+		}
+		if err := saveEntities(tx, reply); err != nil {
+			return err
+		}
+		return tx.Model(&models.Post{}).Where("id = ?", parent.ID).
+			UpdateColumn("reply_count", gorm.Expr("reply_count + 1")).Error
+	})
+	if err != nil {
+		log.Printf("[ERROR] Failed to create reply to post %d for author %d: %v", parent.ID, reply.AuthorID, err)
+		return err
+	}
+
+	r.index(reply)
+	utils.PostQueue(reply, r.rdb, true)
+	log.Printf("[INFO] Reply created successfully: ID=%d, ParentID=%d, AuthorID=%d", reply.ID, parent.ID, reply.AuthorID)
+
+	return nil
+}
+
+// This method returns a conversation: its first post and a page of replies in the order they were written
+//
+// Replies carry parent_id so clients can build the tree, replies the viewer is not allowed to see are left out.
+// The first post is only returned with the first page, ErrPostNotFound is returned if it is gone or hidden from the viewer.
+// The returned cursor is 0 on the last page.
+func (r *postRepository) GetThread(rootID, viewerID, afterID uint, limit int) (*models.Post, []models.Post, uint, error) {
+	// A conversation is only as visible as its first post, on every page
+	root, err := r.GetVisibleByID(rootID, viewerID)
+	if err != nil {
+		log.Printf("[ERROR] User %d can not see the root of thread %d: %v", viewerID, rootID, err)
+		return nil, nil, 0, ErrPostNotFound
+	}
+	if afterID != 0 {
+		root = nil
+	}
+
+	var posts []models.Post
+	if err := r.db.Preload("Author").
+		Where("root_id = ? AND id > ?", rootID, afterID).
+		Order("id ASC").Limit(limit + 1).
+		Find(&posts).Error; err != nil {
+		log.Printf("[ERROR] Error fetching thread of post %d: %v", rootID, err)
+		return nil, nil, 0, err
+	}
+
+	var next uint
+	if len(posts) > limit {
+		posts = posts[:limit]
+		next = posts[limit-1].ID
+	}
+
+	replies, err := r.visibleTo(viewerID, posts)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if replies, err = r.decorate(viewerID, replies); err != nil {
+		return nil, nil, 0, err
+	}
+	return root, replies, next, nil
+}
+
+// This method returns posts with a hashtag, newest first, starting after the post with id beforeID
+//
+// Posts the viewer is not allowed to see and posts of muted users are left out. The returned cursor is 0 on the last page.
+func (r *postRepository) GetByHashtag(tag string, viewerID, beforeID uint, limit int) ([]models.Post, uint, error) {
+	query := r.db.Preload("Author").
+		Joins("JOIN post_hashtags ON post_hashtags.post_id = posts.id").
+		Joins("JOIN hashtags ON hashtags.id = post_hashtags.hashtag_id").
+		Where("hashtags.name = ? AND posts.tombstoned_at IS NULL", tag)
+	if beforeID != 0 {
+		query = query.Where("posts.id < ?", beforeID)
+	}
+
+	var posts []models.Post
+	if err := query.Order("posts.id DESC").Limit(limit + 1).Find(&posts).Error; err != nil {
+		log.Printf("[ERROR] Error fetching posts with hashtag %s: %v", tag, err)
+		return nil, 0, err
+	}
+
+	var next uint
+	if len(posts) > limit {
+		posts = posts[:limit]
+		next = posts[limit-1].ID
+	}
+
+	posts, err := r.visibleTo(viewerID, posts)
+	if err != nil {
+		return nil, 0, err
+	}
+	if posts, err = r.withoutMuted(viewerID, posts); err != nil {
+		return nil, 0, err
+	}
+	if posts, err = r.decorate(viewerID, posts); err != nil {
+		return nil, 0, err
+	}
+	return posts, next, nil
+}
+
+// This method searches posts by their title and content, most relevant first
+//
+// Posts the viewer is not allowed to see and posts of muted users are left out.
+func (r *postRepository) Search(query PostSearchQuery, viewerID uint) ([]models.Post, error) {
+	hits, err := r.searchIndex().Search(query)
+	if err != nil {
+		log.Printf("[ERROR] Post search failed: %v", err)
+		return nil, err
+	}
+	if len(hits) == 0 {
+		return []models.Post{}, nil
+	}
+
+	postIDs := make([]uint, 0, len(hits))
+	for _, hit := range hits {
+		postIDs = append(postIDs, hit.PostID)
+	}
+	posts, err := r.GetPostsByIDs(postIDs)
+	if err != nil {
+		return nil, err
+	}
+	order := make(map[uint]int, len(postIDs))
+	for i, id := range postIDs {
+		order[id] = i
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		return order[posts[i].ID] < order[posts[j].ID]
+	})
+
+	if posts, err = r.visibleTo(viewerID, posts); err != nil {
+		return nil, err
+	}
+	if posts, err = r.withoutMuted(viewerID, posts); err != nil {
+		return nil, err
+	}
+	return r.decorate(viewerID, posts)
+}
+
+func (r *postRepository) searchIndex() SearchIndex {
+	if postSearchIndex != nil {
+		return postSearchIndex
+	}
+	return NewMySQLSearchIndex(r.db)
+}
+
+// The posts table is the source of truth, an index that missed a change is fixed when it is loaded again
+func (r *postRepository) index(post *models.Post) {
+	if err := r.searchIndex().Index(post); err != nil {
+		log.Printf("[ERROR] Failed to index post %d for search: %v", post.ID, err)
+	}
+}
+
+// Leaves out posts of authors the viewer is not allowed to see
+func (r *postRepository) visibleTo(viewerID uint, posts []models.Post) ([]models.Post, error) {
+	followRepo := NewFollowRepository(r.db, r.rdb)
+	visibleAuthors := map[uint]bool{}
+	visiblePosts := make([]models.Post, 0, len(posts))
+	for _, post := range posts {
+		// Tombstones of erased users have no author left to hide them
+		if post.AuthorID == 0 {
+			visiblePosts = append(visiblePosts, post)
+			continue
+		}
+		visible, ok := visibleAuthors[post.AuthorID]
+		if !ok {
+			var err error
+			if visible, err = followRepo.CanView(viewerID, post.AuthorID); err != nil {
+				return nil, err
+			}
+			visibleAuthors[post.AuthorID] = visible
+		}
+		if visible {
+			visiblePosts = append(visiblePosts, post)
+		}
+	}
+	return visiblePosts, nil
+}
+
 // This method retrieves a post by ID
 //
 // If the post is found, it returns the post. If not, it returns an error.
@@ -82,6 +309,36 @@ func (r *postRepository) GetByID(id uint) (*models.Post, error) {
 	return &post, nil
 }
 
+// This method retrieves a post the viewer is allowed to see
+//
+// Posts of private accounts are reported as not found to anyone but the author and approved followers.
+// The post comes with its entities, reaction counts, the viewer's reaction and the post it shares.
+func (r *postRepository) GetVisibleByID(id, viewerID uint) (*models.Post, error) {
+	post, err := r.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	visible := post.AuthorID == 0
+	if !visible {
+		if visible, err = NewFollowRepository(r.db, r.rdb).CanView(viewerID, post.AuthorID); err != nil {
+			return nil, err
+		}
+	}
+	if !visible {
+		log.Printf("[ERROR] User %d tried to see post %d of a private account", viewerID, id)
+		return nil, fmt.Errorf("post not found")
+	}
+
+	posts, err := r.decorate(viewerID, []models.Post{*post})
+	if err != nil {
+		return nil, err
+	}
+	if len(posts) == 0 {
+		return nil, fmt.Errorf("post not found")
+	}
+	return &posts[0], nil
+}
+
 // This method retrieves posts by their IDs
 //
 // If the error is nil, the posts were retrieved successfully.
@@ -115,19 +372,27 @@ func (r *postRepository) GetByAuthorID(authorID uint) ([]models.Post, error) {
 	return posts, nil
 }
 
-// This method gets posts by their author username
+// This method gets posts by their author username, newest first, with reactions and entities as the viewer sees them
 //
 // If the error is nil, the posts were retrieved successfully.
-func (r *postRepository) GetByAuthorUsername(username string,) ([]models.Post, error) {
-	var posts []models.Post
-	if err := r.db.Where("author_username = ?", username).Find(&posts).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			log.Printf("[ERROR] Posts with author_username %s not found", username)
-			return nil, fmt.Errorf("posts not found")
-		}
+func (r *postRepository) GetByAuthorUsername(username string, viewerID uint, offset, limit int) ([]models.Post, error) {
+	posts := []models.Post{}
+	if err := r.db.Preload("Author").Joins("JOIN users ON users.id = posts.author_id").
+		Where("users.username = ? AND posts.tombstoned_at IS NULL", username).
+		Order("posts.created_at DESC").
+		Offset(offset).Limit(limit).
+		Find(&posts).Error; err != nil {
+		log.Printf("[ERROR] Error fetching posts of user %s: %v", username, err)
 		return nil, err
 	}
-	return posts, nil
+	return r.decorate(viewerID, posts)
+}
+
+// This method counts the posts of an author, tombstones are not counted as they are not listed either
+func (r *postRepository) CountByAuthorID(authorID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Post{}).Where("author_id = ? AND tombstoned_at IS NULL", authorID).Count(&count).Error
+	return count, err
 }
 
 // This method updates a post
@@ -140,19 +405,38 @@ func (r *postRepository) UpdatePost(post *models.Post, userID uint, updates inte
 
 		return fmt.Errorf("you are not the author of this post")
 	}
+	if post.TombstonedAt != nil {
+		return ErrPostDeleted
+	}
+	if post.RepostOfID != nil {
+		return ErrRepostNotEditable
+	}
 
-	if err := r.db.Model(post).Updates(updates).Error; err != nil {
+	var updated models.Post
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(post).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&updated, post.ID).Error; err != nil {
+			return err
+		}
+		return saveEntities(tx, &updated)
+	})
+	if err != nil {
 		log.Printf("[ERROR] Failed to update post %d by user %d: %v", post.ID, userID, err)
 
 		return err
 	}
+	r.index(&updated)
 	log.Printf("[INFO] Post %d updated successfully by user %d", post.ID, userID)
 
 	return nil
 }
 
-// This method deletes a post
+// This method moves a post to the trash
 //
+// The post is hidden and removed from the timelines of the author's followers, its replies, quotes, reactions and media are kept
+// until it is restored or purged. A repost has nothing to restore, so it is removed right away.
 // If the error is nil, the post was deleted successfully.
 func (r *postRepository) DeletePost(post *models.Post, userID uint) error {
 
@@ -161,20 +445,449 @@ func (r *postRepository) DeletePost(post *models.Post, userID uint) error {
 
 		return fmt.Errorf("you are not the author of this post")
 	}
+	if post.TombstonedAt != nil {
+		return ErrPostDeleted
+	}
+
+	if post.RepostOfID != nil {
+		if err := r.RemovePost(post); err != nil {
+			log.Printf("[ERROR] User %d tried to delete post %d error %v", userID, post.ID, err)
+
+			return err
+		}
+		log.Printf("[INFO] Repost %d deleted successfully by user %d", post.ID, userID)
+
+		return nil
+	}
 
 	if err := r.db.Delete(post).Error; err != nil {
 		log.Printf("[ERROR] User %d tried to delete post %d error %v", userID, post.ID, err)
 
 		return err
 	}
+	if err := r.searchIndex().Remove(post.ID); err != nil {
+		log.Printf("[ERROR] Failed to remove post %d from search index: %v", post.ID, err)
+	}
+
+	utils.PostQueue(post, r.rdb, false)
+	log.Printf("[INFO] Post %d moved to trash by user %d", post.ID, userID)
+
+	return nil
+}
+
+// This method returns the posts of a user that are in the trash, the last deleted first
+func (r *postRepository) GetTrash(userID uint, offset, limit int) ([]models.Post, error) {
+	posts := []models.Post{}
+	if err := r.db.Unscoped().Preload("Author").
+		Where("author_id = ? AND deleted_at IS NOT NULL", userID).
+		Order("deleted_at DESC").Order("id DESC").
+		Offset(offset).Limit(limit).
+		Find(&posts).Error; err != nil {
+		log.Printf("[ERROR] Error fetching trash of user %d: %v", userID, err)
+		return nil, err
+	}
+	return posts, nil
+}
+
+// This method returns a post that is in the trash
+func (r *postRepository) GetTrashedByID(id uint) (*models.Post, error) {
+	var post models.Post
+	err := r.db.Unscoped().Preload("Author").Where("deleted_at IS NOT NULL").First(&post, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPostNotInTrash
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+// This method takes a post out of the trash
+//
+// The post is searchable again and goes back to the timelines of the author's followers.
+func (r *postRepository) RestorePost(post *models.Post, userID uint) error {
+	if post.AuthorID != userID {
+		log.Printf("[ERROR] User %d tried to restore post %d but is not the author", userID, post.ID)
+
+		return fmt.Errorf("you are not the author of this post")
+	}
+
+	result := r.db.Unscoped().Model(post).Where("deleted_at IS NOT NULL").Update("deleted_at", nil)
+	if result.Error != nil {
+		log.Printf("[ERROR] User %d tried to restore post %d error %v", userID, post.ID, result.Error)
+
+		return result.Error
+	}
+	// Purged or restored at the same time
+	if result.RowsAffected == 0 {
+		return ErrPostNotInTrash
+	}
+	post.DeletedAt = gorm.DeletedAt{}
+
+	r.index(post)
+	utils.PostQueue(post, r.rdb, true)
+	log.Printf("[INFO] Post %d restored from trash by user %d", post.ID, userID)
+
+	return nil
+}
+
+// This method permanently deletes the posts that went to the trash before the given time, with their media
+//
+// It returns how many posts were purged.
+func (r *postRepository) PurgeTrash(before time.Time) (int, error) {
+	purged := 0
+	for {
+		var posts []models.Post
+		if err := r.db.Unscoped().
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+			Order("id").Limit(trashPurgeBatchSize).
+			Find(&posts).Error; err != nil {
+			return purged, err
+		}
+
+		for i := range posts {
+			removed, err := r.remove(&posts[i], &before)
+			if err != nil {
+				return purged, err
+			}
+			// Restored after it was read
+			if !removed {
+				continue
+			}
+			purged++
+		}
+
+		if len(posts) < trashPurgeBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+// Posts purged with one query
+const trashPurgeBatchSize = 100
+
+// How long deleted posts stay in the trash, POST_TRASH_RETENTION in days (default 30)
+func PostTrashRetention() time.Duration {
+	return time.Duration(envInt64("POST_TRASH_RETENTION", 30)) * 24 * time.Hour
+}
+
+// This method deletes a post without checking who asks, callers must authorize first
+//
+// A post with replies or quotes is turned into a tombstone, its content goes away but conversations and quotes stay whole.
+// Reposts of the post are removed, and its media if it is inside the uploads directory. The post is removed from the timelines of the author's followers too.
+func (r *postRepository) RemovePost(post *models.Post) error {
+	if post.TombstonedAt != nil {
+		return ErrPostDeleted
+	}
+	_, err := r.remove(post, nil)
+	return err
+}
+
+// Removes a post for good, a post in the trash too
+//
+// With trashedBefore set the post is only removed if it is still in the trash since before that time,
+// false is returned for a post that was restored in the meantime.
+func (r *postRepository) remove(post *models.Post, trashedBefore *time.Time) (bool, error) {
+	trashed := func(db *gorm.DB) *gorm.DB {
+		if trashedBefore == nil {
+			return db
+		}
+		return db.Where("deleted_at IS NOT NULL AND deleted_at < ?", *trashedBefore)
+	}
+
+	mediaPath := post.MediaPath
+
+	// Only a post nothing points to is deleted, checked in the same query so a new reply or quote is never orphaned
+	result := r.db.Unscoped().Scopes(trashed).Where("reply_count = 0 AND quote_count = 0").Delete(post)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		now := time.Now()
+		// A tombstone is shown in conversations, so it leaves the trash
+		result := r.db.Unscoped().Scopes(trashed).Model(post).Updates(map[string]interface{}{
+			"title":         "",
+			"content":       "",
+			"media_path":    "",
+			"tombstoned_at": &now,
+			"deleted_at":    nil,
+		})
+		if result.Error != nil {
+			return false, result.Error
+		}
+		if result.RowsAffected == 0 && trashedBefore != nil {
+			return false, nil
+		}
+		log.Printf("[INFO] Post %d has replies or quotes and was turned into a tombstone", post.ID)
+	} else if err := r.detach(post); err != nil {
+		log.Printf("[ERROR] Failed to update counters of posts referenced by post %d: %v", post.ID, err)
+	}
+
+	// Paths of old rows were set by clients, only files in the uploads directory are ours to remove
+	if utils.IsUploadPath(mediaPath) {
+		if err := os.Remove(mediaPath); err != nil && !os.IsNotExist(err) {
+			log.Printf("[ERROR] Failed to remove media of post %d: %v", post.ID, err)
+		}
+	}
+	if err := deleteEntities(r.db, post.ID); err != nil {
+		log.Printf("[ERROR] Failed to delete hashtags and mentions of post %d: %v", post.ID, err)
+	}
+	if err := r.searchIndex().Remove(post.ID); err != nil {
+		log.Printf("[ERROR] Failed to remove post %d from search index: %v", post.ID, err)
+	}
+	if err := r.removeReposts(post.ID); err != nil {
+		log.Printf("[ERROR] Failed to remove reposts of post %d: %v", post.ID, err)
+	}
+	if err := NewReactionRepository(r.db, r.rdb).DeleteForPost(post.ID); err != nil {
+		log.Printf("[ERROR] Failed to delete reactions of post %d: %v", post.ID, err)
+	}
+
 	utils.PostQueue(post, r.rdb, false)
 	log.Printf("[INFO] Post added to queue for delete successfully: ID=%d, AuthorID=%d", post.ID, post.AuthorID)
 
-	log.Printf("[INFO] Post %d deleted successfully by user %d", post.ID, userID)
+	return true, nil
+}
 
+// Reposts only show the original, so they go away with it
+func (r *postRepository) removeReposts(originalID uint) error {
+	var reposts []models.Post
+	if err := r.db.Unscoped().Where("repost_of_id = ?", originalID).Find(&reposts).Error; err != nil {
+		return err
+	}
+	for i := range reposts {
+		if err := r.RemovePost(&reposts[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Lowers the counters of the posts a deleted post replied to, quoted or reposted, even if they are in the trash
+func (r *postRepository) detach(post *models.Post) error {
+	var pending []uint
+	for _, ref := range []struct {
+		id      *uint
+		counter string
+	}{
+		{post.ParentID, "reply_count"},
+		{post.QuoteOfID, "quote_count"},
+		{post.RepostOfID, "repost_count"},
+	} {
+		if ref.id == nil {
+			continue
+		}
+		if err := r.db.Unscoped().Model(&models.Post{}).Where("id = ? AND "+ref.counter+" > 0", *ref.id).
+			UpdateColumn(ref.counter, gorm.Expr(ref.counter+" - 1")).Error; err != nil {
+			return err
+		}
+		pending = append(pending, *ref.id)
+	}
+
+	for _, id := range pending {
+		if err := r.pruneTombstone(id); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// A tombstone is only kept for its replies and quotes, once the last one is gone it is deleted too
+func (r *postRepository) pruneTombstone(id uint) error {
+	var post models.Post
+	err := r.db.Where("id = ? AND tombstoned_at IS NOT NULL AND reply_count = 0 AND quote_count = 0", id).First(&post).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	result := r.db.Unscoped().Where("reply_count = 0 AND quote_count = 0").Delete(&post)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+	log.Printf("[INFO] Tombstone %d has no replies or quotes left and was deleted", post.ID)
+
+	return r.detach(&post)
+}
+
+// This method reposts a post for a user, a repost of a repost shares the original post
+//
+// The repost goes to the timelines of the user's followers like any other post.
+func (r *postRepository) Repost(original *models.Post, userID uint) (*models.Post, error) {
+	original, err := r.shareable(original, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	repost := models.Post{
+		AuthorID:   userID,
+		RepostOfID: &original.ID,
+	}
+	if err := r.createShare(&repost, original.ID, "repost_count"); err != nil {
+		if isDuplicate(err) {
+			return nil, ErrAlreadyReposted
+		}
+		log.Printf("[ERROR] Failed to repost post %d for user %d: %v", original.ID, userID, err)
+		return nil, err
+	}
+
+	log.Printf("[INFO] User %d reposted post %d: repost_id=%d", userID, original.ID, repost.ID)
+	return &repost, nil
+}
+
+// This method removes the repost a user made of a post
+func (r *postRepository) Unrepost(original *models.Post, userID uint) error {
+	originalID := original.ID
+	if original.RepostOfID != nil {
+		originalID = *original.RepostOfID
+	}
+
+	var repost models.Post
+	err := r.db.Where("author_id = ? AND repost_of_id = ?", userID, originalID).First(&repost).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotReposted
+	}
+	if err != nil {
+		return err
+	}
+	return r.RemovePost(&repost)
+}
+
+// This method creates a post that quotes another post, a quote of a repost quotes the original post
+func (r *postRepository) Quote(original *models.Post, quote *models.Post) error {
+	original, err := r.shareable(original, quote.AuthorID)
+	if err != nil {
+		return err
+	}
+
+	quote.QuoteOfID = &original.ID
+	if err := r.createShare(quote, original.ID, "quote_count"); err != nil {
+		log.Printf("[ERROR] Failed to quote post %d for user %d: %v", original.ID, quote.AuthorID, err)
+		return err
+	}
+
+	log.Printf("[INFO] User %d quoted post %d: quote_id=%d", quote.AuthorID, original.ID, quote.ID)
+	return nil
+}
+
+// Returns the post that is really shared
+//
+// Posts of private accounts can only be shared by their author, so they never reach other followers.
+func (r *postRepository) shareable(post *models.Post, userID uint) (*models.Post, error) {
+	if post.RepostOfID != nil {
+		original, err := r.GetByID(*post.RepostOfID)
+		if err != nil {
+			return nil, ErrPostDeleted
+		}
+		post = original
+	}
+	if post.TombstonedAt != nil {
+		return nil, ErrPostDeleted
+	}
+	if post.Author.IsPrivate && post.AuthorID != userID {
+		return nil, ErrCannotSharePrivate
+	}
+	return post, nil
+}
+
+// Creates the repost or quote and counts it on the original in one transaction, then fans it out
+func (r *postRepository) createShare(post *models.Post, originalID uint, counter string) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(post).Error; err != nil {
+			return err
+		}
+		if err := saveEntities(tx, post); err != nil {
+			return err
+		}
+		return tx.Model(&models.Post{}).Where("id = ?", originalID).
+			UpdateColumn(counter, gorm.Expr(counter+" + 1")).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	if post.RepostOfID == nil {
+		r.index(post)
+	}
+	utils.PostQueue(post, r.rdb, true)
+	return nil
+}
+
+// Adds the shared posts to reposts and quotes
+//
+// Reposts of posts that are gone or hidden from the viewer are left out, quotes of them get a tombstone.
+func (r *postRepository) withOriginals(viewerID uint, posts []models.Post) ([]models.Post, error) {
+	var originalIDs []uint
+	for _, post := range posts {
+		if id := sharedID(&post); id != nil {
+			originalIDs = append(originalIDs, *id)
+		}
+	}
+	if len(originalIDs) == 0 {
+		return posts, nil
+	}
+
+	originals, err := r.GetPostsByIDs(originalIDs)
+	if err != nil {
+		return posts, err
+	}
+	if err := NewReactionRepository(r.db, r.rdb).Attach(originals, viewerID); err != nil {
+		return posts, err
+	}
+	if err := r.attachEntities(originals); err != nil {
+		return posts, err
+	}
+
+	followRepo := NewFollowRepository(r.db, r.rdb)
+	visibleAuthors := map[uint]bool{}
+	available := map[uint]*models.Post{}
+	for i := range originals {
+		original := &originals[i]
+		if original.TombstonedAt != nil {
+			continue
+		}
+		visible, ok := visibleAuthors[original.AuthorID]
+		if !ok {
+			if visible, err = followRepo.CanView(viewerID, original.AuthorID); err != nil {
+				return posts, err
+			}
+			visibleAuthors[original.AuthorID] = visible
+		}
+		if visible {
+			available[original.ID] = original
+		}
+	}
+
+	now := time.Now()
+	result := make([]models.Post, 0, len(posts))
+	for _, post := range posts {
+		id := sharedID(&post)
+		if id == nil {
+			result = append(result, post)
+			continue
+		}
+		original, ok := available[*id]
+		switch {
+		case ok:
+			post.Original = original
+		case post.RepostOfID != nil:
+			continue
+		default:
+			post.Original = &models.Post{ID: *id, TombstonedAt: &now}
+		}
+		result = append(result, post)
+	}
+	return result, nil
+}
+
+func sharedID(post *models.Post) *uint {
+	if post.RepostOfID != nil {
+		return post.RepostOfID
+	}
+	return post.QuoteOfID
+}
+
 func (r *postRepository) GetFollowingsPosts(userID uint, start, end int64) (posts []models.Post, err error) {
 	log.Printf("[INFO] Fetching posts from followings of user %d", userID)
 
@@ -195,7 +908,7 @@ func (r *postRepository) GetFollowingsPosts(userID uint, start, end int64) (post
 	limit := int(end - start + 1)
 	page := int((int(start) / limit) + 1)
 	if err := r.db.Preload("Author").
-		Where("author_id IN ?", followingIDs).
+		Where("author_id IN ? AND tombstoned_at IS NULL", followingIDs).
 		Order("created_at DESC").
 		Offset((page - 1) * limit).Limit(limit).Find(&posts).Error; err != nil {
 		return posts, err
@@ -232,21 +945,19 @@ func (r *postRepository) GetTimeline(userID uint, start, end int64) ([]models.Po
 				Member: post.ID,
 			})
 		}
-		return posts, nil
+		return r.timelinePage(userID, posts)
 
 	}
 	postIds := []uint{}
 	for _, s := range result {
 		num, err := strconv.ParseUint(s, 10, 64)
 		if err != nil {
-			fmt.Println("Conversion error:", err)
+			log.Printf("[ERROR] Invalid post id %q in timeline of user %d: %v", s, userID, err)
 			continue
 		}
 		postIds = append(postIds, uint(num))
 	}
 	log.Printf("[INFO] Timeline for user %d fetched from Redis", userID)
-	a, _ := r.rdb.ZRevRange(ctx, key, 0, 100).Result()
-	fmt.Println(a)
 	posts, err = r.GetPostsByIDs(postIds)
 	if err != nil {
 		return posts, err
@@ -260,5 +971,163 @@ func (r *postRepository) GetTimeline(userID uint, start, end int64) ([]models.Po
 	})
 	log.Printf("[INFO] Timeline was sent for user %d", userID)
 
+	return r.timelinePage(userID, posts)
+}
+
+// Leaves out muted authors and decorates the page
+func (r *postRepository) timelinePage(userID uint, posts []models.Post) ([]models.Post, error) {
+	posts, err := r.withoutMuted(userID, posts)
+	if err != nil {
+		return posts, err
+	}
+	return r.decorate(userID, posts)
+}
+
+// Adds what is shown with posts but not stored in them: shared posts, reactions and entities
+//
+// Reposts whose original is not available are left out.
+func (r *postRepository) decorate(viewerID uint, posts []models.Post) ([]models.Post, error) {
+	posts, err := r.withOriginals(viewerID, posts)
+	if err != nil {
+		return posts, err
+	}
+	if err := NewReactionRepository(r.db, r.rdb).Attach(posts, viewerID); err != nil {
+		return posts, err
+	}
+	if err := r.attachEntities(posts); err != nil {
+		return posts, err
+	}
 	return posts, nil
 }
+
+// Muted users stay in the timeline set, so unmuting brings their posts back; they are filtered when read
+func (r *postRepository) withoutMuted(userID uint, posts []models.Post) ([]models.Post, error) {
+	mutedIDs, err := NewBlockRepository(r.db, r.rdb).MutedIDs(userID)
+	if err != nil {
+		return posts, err
+	}
+	if len(mutedIDs) == 0 {
+		return posts, nil
+	}
+
+	visible := make([]models.Post, 0, len(posts))
+	for _, post := range posts {
+		if !slices.Contains(mutedIDs, post.AuthorID) {
+			visible = append(visible, post)
+		}
+	}
+	return visible, nil
+}
+
+// Replaces the hashtag and mention links of a post with the ones in its content
+//
+// Mentions are only linked to users that exist.
+func saveEntities(tx *gorm.DB, post *models.Post) error {
+	if err := deleteEntities(tx, post.ID); err != nil {
+		return err
+	}
+
+	var tags, usernames []string
+	for _, entity := range utils.ParseEntities(post.Content) {
+		switch {
+		case entity.Type == models.EntityHashtag && !slices.Contains(tags, entity.Text):
+			tags = append(tags, entity.Text)
+		case entity.Type == models.EntityMention && !slices.Contains(usernames, entity.Text):
+			usernames = append(usernames, entity.Text)
+		}
+	}
+
+	if len(tags) > 0 {
+		hashtags := make([]models.Hashtag, 0, len(tags))
+		for _, tag := range tags {
+			hashtags = append(hashtags, models.Hashtag{Name: tag})
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&hashtags).Error; err != nil {
+			return err
+		}
+		var hashtagIDs []uint
+		if err := tx.Model(&models.Hashtag{}).Where("name IN ?", tags).Pluck("id", &hashtagIDs).Error; err != nil {
+			return err
+		}
+		links := make([]models.PostHashtag, 0, len(hashtagIDs))
+		for _, id := range hashtagIDs {
+			links = append(links, models.PostHashtag{PostID: post.ID, HashtagID: id})
+		}
+		if err := tx.Create(&links).Error; err != nil {
+			return err
+		}
+	}
+
+	if len(usernames) > 0 {
+		var userIDs []uint
+		if err := tx.Model(&models.User{}).Where("username IN ?", usernames).Pluck("id", &userIDs).Error; err != nil {
+			return err
+		}
+		mentions := make([]models.Mention, 0, len(userIDs))
+		for _, id := range userIDs {
+			mentions = append(mentions, models.Mention{PostID: post.ID, UserID: id})
+		}
+		if len(mentions) > 0 {
+			if err := tx.Create(&mentions).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func deleteEntities(tx *gorm.DB, postID uint) error {
+	if err := tx.Where("post_id = ?", postID).Delete(&models.PostHashtag{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("post_id = ?", postID).Delete(&models.Mention{}).Error
+}
+
+// Adds the hashtags and mentions with their offsets to the posts
+//
+// Mentions of all posts are resolved to users with one query, a mention of no user is not an entity.
+func (r *postRepository) attachEntities(posts []models.Post) error {
+	postIDs := make([]uint, 0, len(posts))
+	for _, post := range posts {
+		postIDs = append(postIDs, post.ID)
+	}
+
+	type mentioned struct {
+		PostID   uint
+		UserID   uint
+		Username string
+	}
+	var rows []mentioned
+	if len(postIDs) > 0 {
+		if err := r.db.Model(&models.Mention{}).
+			Select("mentions.post_id, mentions.user_id, users.username").
+			Joins("JOIN users ON users.id = mentions.user_id").
+			Where("mentions.post_id IN ?", postIDs).
+			Scan(&rows).Error; err != nil {
+			return err
+		}
+	}
+	mentions := map[uint][]mentioned{}
+	for _, row := range rows {
+		mentions[row.PostID] = append(mentions[row.PostID], row)
+	}
+
+	for i := range posts {
+		entities := []models.PostEntity{}
+		for _, entity := range utils.ParseEntities(posts[i].Content) {
+			if entity.Type == models.EntityMention {
+				for _, user := range mentions[posts[i].ID] {
+					if strings.EqualFold(user.Username, entity.Text) {
+						entity.UserID = user.UserID
+					}
+				}
+				if entity.UserID == 0 {
+					continue
+				}
+			}
+			entities = append(entities, entity)
+		}
+		posts[i].Entities = entities
+	}
+	return nil
+}
@@ -0,0 +1,410 @@
+package repositories
+
+import (
+	"golang_task/models"
+	"golang_task/utils"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Search backends, chosen with SEARCH_BACKEND
+const (
+	SearchBackendMySQL  = "mysql"
+	SearchBackendMemory = "memory"
+)
+
+// PostSearchQuery is a parsed search with its filters
+type PostSearchQuery struct {
+	// Words that must all match, a word in Prefixes matches every word starting with it
+	Terms    []string
+	Prefixes []string
+	// Word sequences that must match in this order
+	Phrases  [][]string
+	AuthorID uint
+	From     *time.Time
+	To       *time.Time
+	HasMedia *bool
+	Offset   int
+	Limit    int
+}
+
+// SearchHit is a matching post and its relevance
+type SearchHit struct {
+	PostID uint    `gorm:"column:id"`
+	Score  float64 `gorm:"column:score"`
+}
+
+// SearchIndex finds posts by the words in their title and content
+type SearchIndex interface {
+	Index(post *models.Post) error
+	Remove(postID uint) error
+	Search(query PostSearchQuery) ([]SearchHit, error)
+}
+
+// ParsePostSearchQuery reads words, "quoted phrases" and prefix* words from a search box
+func ParsePostSearchQuery(q string) PostSearchQuery {
+	var query PostSearchQuery
+
+	parts := strings.Split(q, `"`)
+	for i, part := range parts {
+		// Odd parts are between quotes
+		if i%2 == 1 {
+			switch words := utils.SearchTokens(part); len(words) {
+			case 0:
+			case 1:
+				query.Terms = append(query.Terms, words[0])
+			default:
+				query.Phrases = append(query.Phrases, words)
+			}
+			continue
+		}
+		for _, field := range strings.Fields(part) {
+			prefix := strings.HasSuffix(field, "*")
+			words := utils.SearchTokens(field)
+			for j, word := range words {
+				if prefix && j == len(words)-1 {
+					query.Prefixes = append(query.Prefixes, word)
+				} else {
+					query.Terms = append(query.Terms, word)
+				}
+			}
+		}
+	}
+	return query
+}
+
+// IsEmpty reports whether the query has nothing to look for
+func (q PostSearchQuery) IsEmpty() bool {
+	return len(q.Terms) == 0 && len(q.Prefixes) == 0 && len(q.Phrases) == 0
+}
+
+// The index every post repository keeps updated, set once at startup
+var postSearchIndex SearchIndex
+
+// UsePostSearchIndex sets the index posts are searched in, MySQL is used if it is never called
+func UsePostSearchIndex(index SearchIndex) {
+	postSearchIndex = index
+}
+
+// NewSearchIndexFromEnv returns the index chosen with SEARCH_BACKEND, mysql by default
+//
+// The in-process index starts empty, LoadSearchIndex fills it.
+func NewSearchIndexFromEnv(db *gorm.DB) SearchIndex {
+	if strings.ToLower(os.Getenv("SEARCH_BACKEND")) == SearchBackendMemory {
+		return NewMemorySearchIndex()
+	}
+	return NewMySQLSearchIndex(db)
+}
+
+// LoadSearchIndex adds all posts to an index, for indexes that do not live in the database
+func LoadSearchIndex(db *gorm.DB, index SearchIndex) error {
+	if _, ok := index.(*mysqlSearchIndex); ok {
+		return nil
+	}
+
+	var posts []models.Post
+	count := 0
+	err := db.Where("tombstoned_at IS NULL").FindInBatches(&posts, 500, func(tx *gorm.DB, batch int) error {
+		for i := range posts {
+			if err := index.Index(&posts[i]); err != nil {
+				return err
+			}
+		}
+		count += len(posts)
+		return nil
+	}).Error
+	if err != nil {
+		return err
+	}
+	log.Printf("[INFO] Search index loaded with %d posts", count)
+	return nil
+}
+
+// MySQL FULLTEXT index
+
+// The posts table is the index, so there is nothing to keep updated
+type mysqlSearchIndex struct {
+	db *gorm.DB
+}
+
+func NewMySQLSearchIndex(db *gorm.DB) SearchIndex {
+	return &mysqlSearchIndex{db: db}
+}
+
+func (i *mysqlSearchIndex) Index(post *models.Post) error {
+	return nil
+}
+
+func (i *mysqlSearchIndex) Remove(postID uint) error {
+	return nil
+}
+
+// Every word and phrase is required in boolean mode, words only hold letters, digits and _ so they can not be operators
+func (i *mysqlSearchIndex) Search(query PostSearchQuery) ([]SearchHit, error) {
+	var required []string
+	for _, term := range query.Terms {
+		required = append(required, "+"+term)
+	}
+	for _, prefix := range query.Prefixes {
+		required = append(required, "+"+prefix+"*")
+	}
+	for _, phrase := range query.Phrases {
+		required = append(required, `+"`+strings.Join(phrase, " ")+`"`)
+	}
+	against := strings.Join(required, " ")
+
+	db := i.db.Model(&models.Post{}).
+		Select("id, MATCH(title, content) AGAINST(? IN BOOLEAN MODE) AS score", against).
+		Where("MATCH(title, content) AGAINST(? IN BOOLEAN MODE)", against).
+		Where("tombstoned_at IS NULL")
+	if query.AuthorID != 0 {
+		db = db.Where("author_id = ?", query.AuthorID)
+	}
+	if query.From != nil {
+		db = db.Where("created_at >= ?", *query.From)
+	}
+	if query.To != nil {
+		db = db.Where("created_at < ?", *query.To)
+	}
+	if query.HasMedia != nil {
+		if *query.HasMedia {
+			db = db.Where("media_path <> ''")
+		} else {
+			db = db.Where("media_path = ''")
+		}
+	}
+
+	var hits []SearchHit
+	err := db.Order("score DESC").Order("id DESC").
+		Offset(query.Offset).Limit(query.Limit).
+		Scan(&hits).Error
+	return hits, err
+}
+
+// In-process inverted index
+
+// BM25 parameters
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+type indexedPost struct {
+	authorID  uint
+	createdAt time.Time
+	hasMedia  bool
+	length    int
+	// Distinct words, to find the postings of the post when it is removed
+	words []string
+}
+
+// Keeps positions of every word so phrases can be matched, the title and content are one text
+type memorySearchIndex struct {
+	mu          sync.RWMutex
+	postings    map[string]map[uint][]int
+	posts       map[uint]indexedPost
+	totalLength int
+}
+
+func NewMemorySearchIndex() SearchIndex {
+	return &memorySearchIndex{
+		postings: map[string]map[uint][]int{},
+		posts:    map[uint]indexedPost{},
+	}
+}
+
+func (i *memorySearchIndex) Index(post *models.Post) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.remove(post.ID)
+	if post.TombstonedAt != nil {
+		return nil
+	}
+
+	words := utils.SearchTokens(post.Title + "\n" + post.Content)
+	var distinct []string
+	for position, word := range words {
+		if i.postings[word] == nil {
+			i.postings[word] = map[uint][]int{}
+		}
+		if len(i.postings[word][post.ID]) == 0 {
+			distinct = append(distinct, word)
+		}
+		i.postings[word][post.ID] = append(i.postings[word][post.ID], position)
+	}
+	i.posts[post.ID] = indexedPost{
+		authorID:  post.AuthorID,
+		createdAt: post.CreatedAt,
+		hasMedia:  post.MediaPath != "",
+		length:    len(words),
+		words:     distinct,
+	}
+	i.totalLength += len(words)
+	return nil
+}
+
+func (i *memorySearchIndex) Remove(postID uint) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.remove(postID)
+	return nil
+}
+
+func (i *memorySearchIndex) remove(postID uint) {
+	post, ok := i.posts[postID]
+	if !ok {
+		return
+	}
+	for _, word := range post.words {
+		delete(i.postings[word], postID)
+		if len(i.postings[word]) == 0 {
+			delete(i.postings, word)
+		}
+	}
+	delete(i.posts, postID)
+	i.totalLength -= post.length
+}
+
+func (i *memorySearchIndex) Search(query PostSearchQuery) ([]SearchHit, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	if len(i.posts) == 0 || query.IsEmpty() {
+		return []SearchHit{}, nil
+	}
+
+	// Every word, prefix and phrase adds its score, a post missing one of them is not a hit
+	var scores map[uint]float64
+	require := func(matches map[uint]float64) {
+		if scores == nil {
+			scores = matches
+			return
+		}
+		for id, score := range scores {
+			if match, ok := matches[id]; ok {
+				scores[id] = score + match
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+
+	for _, term := range query.Terms {
+		require(i.termScores(term))
+	}
+	for _, prefix := range query.Prefixes {
+		matches := map[uint]float64{}
+		for word := range i.postings {
+			if !strings.HasPrefix(word, prefix) {
+				continue
+			}
+			// A post matching several words of the prefix counts its best one
+			for id, score := range i.termScores(word) {
+				matches[id] = math.Max(matches[id], score)
+			}
+		}
+		require(matches)
+	}
+	for _, phrase := range query.Phrases {
+		found := i.phrasePosts(phrase)
+		matches := make(map[uint]float64, len(found))
+		for _, word := range phrase {
+			for id, score := range i.termScores(word) {
+				if found[id] {
+					matches[id] += score
+				}
+			}
+		}
+		require(matches)
+	}
+
+	hits := make([]SearchHit, 0, len(scores))
+	for id, score := range scores {
+		post := i.posts[id]
+		if query.AuthorID != 0 && post.authorID != query.AuthorID {
+			continue
+		}
+		if query.From != nil && post.createdAt.Before(*query.From) {
+			continue
+		}
+		if query.To != nil && !post.createdAt.Before(*query.To) {
+			continue
+		}
+		if query.HasMedia != nil && post.hasMedia != *query.HasMedia {
+			continue
+		}
+		hits = append(hits, SearchHit{PostID: id, Score: score})
+	}
+	sort.Slice(hits, func(a, b int) bool {
+		if hits[a].Score != hits[b].Score {
+			return hits[a].Score > hits[b].Score
+		}
+		return hits[a].PostID > hits[b].PostID
+	})
+
+	if query.Offset >= len(hits) {
+		return []SearchHit{}, nil
+	}
+	hits = hits[query.Offset:]
+	if query.Limit > 0 && len(hits) > query.Limit {
+		hits = hits[:query.Limit]
+	}
+	return hits, nil
+}
+
+// BM25 score of a word for the posts having it
+func (i *memorySearchIndex) termScores(word string) map[uint]float64 {
+	postings := i.postings[word]
+	scores := make(map[uint]float64, len(postings))
+	if len(postings) == 0 {
+		return scores
+	}
+
+	total := float64(len(i.posts))
+	df := float64(len(postings))
+	idf := math.Log(1 + (total-df+0.5)/(df+0.5))
+	avgLength := float64(i.totalLength) / total
+
+	for id, positions := range postings {
+		tf := float64(len(positions))
+		length := float64(i.posts[id].length)
+		scores[id] = idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*length/avgLength))
+	}
+	return scores
+}
+
+// Posts where the words of the phrase follow each other
+func (i *memorySearchIndex) phrasePosts(phrase []string) map[uint]bool {
+	found := map[uint]bool{}
+	for id, starts := range i.postings[phrase[0]] {
+		for _, start := range starts {
+			matched := true
+			for offset, word := range phrase[1:] {
+				if !containsPosition(i.postings[word][id], start+offset+1) {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				found[id] = true
+				break
+			}
+		}
+	}
+	return found
+}
+
+// Positions are appended in order, so they are sorted
+func containsPosition(positions []int, position int) bool {
+	index := sort.SearchInts(positions, position)
+	return index < len(positions) && positions[index] == position
+}
@@ -0,0 +1,221 @@
+package repositories
+
+import (
+	"golang_task/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Indexes the posts in a new in-memory index
+func newTestSearchIndex(t *testing.T, posts ...models.Post) SearchIndex {
+	t.Helper()
+	index := NewMemorySearchIndex()
+	for i := range posts {
+		if err := index.Index(&posts[i]); err != nil {
+			t.Fatalf("index post %d: %v", posts[i].ID, err)
+		}
+	}
+	return index
+}
+
+// Searches the index and returns the ids of the hits in order
+func searchIDs(t *testing.T, index SearchIndex, q string) []uint {
+	t.Helper()
+	hits, err := index.Search(ParsePostSearchQuery(q))
+	if err != nil {
+		t.Fatalf("search %q: %v", q, err)
+	}
+	ids := []uint{}
+	for _, hit := range hits {
+		ids = append(ids, hit.PostID)
+	}
+	return ids
+}
+
+func TestParsePostSearchQuery(t *testing.T) {
+	for _, tc := range []struct {
+		q    string
+		want PostSearchQuery
+	}{
+		{"Golang, Fiber!", PostSearchQuery{Terms: []string{"golang", "fiber"}}},
+		{"go*", PostSearchQuery{Prefixes: []string{"go"}}},
+		{`"Hello World" redis`, PostSearchQuery{Terms: []string{"redis"}, Phrases: [][]string{{"hello", "world"}}}},
+		{`"single"`, PostSearchQuery{Terms: []string{"single"}}},
+		{"user_name سلام", PostSearchQuery{Terms: []string{"user_name", "سلام"}}},
+		{`" ... " !!`, PostSearchQuery{}},
+	} {
+		if got := ParsePostSearchQuery(tc.q); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("ParsePostSearchQuery(%q) = %+v, want %+v", tc.q, got, tc.want)
+		}
+	}
+}
+
+func TestMemorySearchIndexMatchesAllWords(t *testing.T) {
+	index := newTestSearchIndex(t,
+		models.Post{ID: 1, Title: "Go tips", Content: "Channels and goroutines in Go"},
+		models.Post{ID: 2, Title: "Redis", Content: "Caching with Redis from Go"},
+		models.Post{ID: 3, Title: "Cooking", Content: "A quick pasta recipe"},
+	)
+
+	for _, tc := range []struct {
+		q    string
+		want []uint
+	}{
+		{"redis", []uint{2}},
+		{"REDIS caching", []uint{2}},
+		{"go redis", []uint{2}},
+		{"go pasta", []uint{}},
+		{"gorout*", []uint{1}},
+		{`"pasta recipe"`, []uint{3}},
+		{`"recipe pasta"`, []uint{}},
+		{"python", []uint{}},
+	} {
+		if got := searchIDs(t, index, tc.q); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("search %q = %v, want %v", tc.q, got, tc.want)
+		}
+	}
+}
+
+func TestMemorySearchIndexRanksByRelevance(t *testing.T) {
+	index := newTestSearchIndex(t,
+		// The same length, the word once and three times
+		models.Post{ID: 1, Content: "redis one two three four five"},
+		models.Post{ID: 2, Content: "redis redis redis one two three"},
+		// Once in a long post
+		models.Post{ID: 3, Content: "redis one two three four five six seven eight nine ten eleven twelve"},
+		models.Post{ID: 4, Content: "nothing to see here"},
+	)
+
+	if got, want := searchIDs(t, index, "redis"), []uint{2, 1, 3}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("search redis = %v, want %v", got, want)
+	}
+
+	// A rarer word weighs more than a common one
+	index = newTestSearchIndex(t,
+		models.Post{ID: 1, Content: "common rare"},
+		models.Post{ID: 2, Content: "common common"},
+		models.Post{ID: 3, Content: "common words"},
+	)
+	hits, err := index.Search(PostSearchQuery{Terms: []string{"common"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rare, err := index.Search(PostSearchQuery{Terms: []string{"rare"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rare) != 1 || len(hits) != 3 {
+		t.Fatalf("got %d rare and %d common hits, want 1 and 3", len(rare), len(hits))
+	}
+	for _, hit := range hits {
+		if hit.PostID == 1 && hit.Score >= rare[0].Score {
+			t.Errorf("common scored %f in post 1, not below rare with %f", hit.Score, rare[0].Score)
+		}
+	}
+}
+
+func TestMemorySearchIndexFiltersAndPages(t *testing.T) {
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	index := newTestSearchIndex(t,
+		models.Post{ID: 1, AuthorID: 1, Content: "golang", CreatedAt: day},
+		models.Post{ID: 2, AuthorID: 2, Content: "golang", CreatedAt: day.Add(24 * time.Hour), MediaPath: "./uploads/a.png"},
+		models.Post{ID: 3, AuthorID: 1, Content: "golang", CreatedAt: day.Add(48 * time.Hour)},
+	)
+	from, to := day.Add(24*time.Hour), day.Add(48*time.Hour)
+	hasMedia := false
+
+	for _, tc := range []struct {
+		name  string
+		query PostSearchQuery
+		want  []uint
+	}{
+		// Equal scores are ordered newest id first
+		{"all", PostSearchQuery{Terms: []string{"golang"}}, []uint{3, 2, 1}},
+		{"author", PostSearchQuery{Terms: []string{"golang"}, AuthorID: 1}, []uint{3, 1}},
+		{"dates", PostSearchQuery{Terms: []string{"golang"}, From: &from, To: &to}, []uint{2}},
+		{"without media", PostSearchQuery{Terms: []string{"golang"}, HasMedia: &hasMedia}, []uint{3, 1}},
+		{"page", PostSearchQuery{Terms: []string{"golang"}, Offset: 1, Limit: 1}, []uint{2}},
+		{"past the end", PostSearchQuery{Terms: []string{"golang"}, Offset: 3}, []uint{}},
+	} {
+		hits, err := index.Search(tc.query)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		got := []uint{}
+		for _, hit := range hits {
+			got = append(got, hit.PostID)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestMemorySearchIndexRemove(t *testing.T) {
+	index := newTestSearchIndex(t,
+		models.Post{ID: 1, Content: "shared words only here"},
+		models.Post{ID: 2, Content: "shared words"},
+	)
+
+	if err := index.Remove(1); err != nil {
+		t.Fatal(err)
+	}
+	if got := searchIDs(t, index, "shared"); !reflect.DeepEqual(got, []uint{2}) {
+		t.Errorf("search shared = %v, want [2]", got)
+	}
+	if got := searchIDs(t, index, "here"); len(got) != 0 {
+		t.Errorf("search here = %v after its only post was removed", got)
+	}
+	// The removed post's words are gone from the index, so prefixes do not walk them
+	if memory := index.(*memorySearchIndex); memory.postings["here"] != nil || memory.totalLength != 2 {
+		t.Errorf("removed post left postings %v and length %d", memory.postings["here"], memory.totalLength)
+	}
+
+	// Removing a post that is not indexed does nothing
+	if err := index.Remove(42); err != nil {
+		t.Fatal(err)
+	}
+	if err := index.Remove(2); err != nil {
+		t.Fatal(err)
+	}
+	if got := searchIDs(t, index, "shared"); len(got) != 0 {
+		t.Errorf("search shared = %v on an empty index", got)
+	}
+}
+
+func TestMemorySearchIndexUpdatesPost(t *testing.T) {
+	post := models.Post{ID: 1, Title: "Draft", Content: "about mysql"}
+	index := newTestSearchIndex(t, post, models.Post{ID: 2, Content: "about redis"})
+
+	// Indexing the post again replaces its old words
+	post.Title, post.Content = "Final", "about postgres"
+	if err := index.Index(&post); err != nil {
+		t.Fatal(err)
+	}
+	if got := searchIDs(t, index, "mysql"); len(got) != 0 {
+		t.Errorf("search mysql = %v after the post was edited", got)
+	}
+	if got := searchIDs(t, index, "draft"); len(got) != 0 {
+		t.Errorf("search draft = %v after the title was edited", got)
+	}
+	if got := searchIDs(t, index, "postgres final"); !reflect.DeepEqual(got, []uint{1}) {
+		t.Errorf("search postgres final = %v, want [1]", got)
+	}
+	if got := searchIDs(t, index, "about"); !reflect.DeepEqual(got, []uint{2, 1}) {
+		t.Errorf("search about = %v, want [2 1]", got)
+	}
+	if memory := index.(*memorySearchIndex); memory.totalLength != 5 {
+		t.Errorf("total length = %d after the edit, want 5", memory.totalLength)
+	}
+
+	// A tombstoned post has no content left to find
+	now := time.Now()
+	post.TombstonedAt = &now
+	if err := index.Index(&post); err != nil {
+		t.Fatal(err)
+	}
+	if got := searchIDs(t, index, "postgres"); len(got) != 0 {
+		t.Errorf("search postgres = %v after the post was tombstoned", got)
+	}
+}
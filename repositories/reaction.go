@@ -0,0 +1,324 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang_task/models"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Posts whose counters changed since the last reconcile
+const ReactionsDirtyKey = "reactions_dirty"
+
+const (
+	// Marks a loaded counter hash, so posts without reactions are cached too
+	reactionsLoadedField = "_"
+	reactionCountersTTL  = 24 * time.Hour
+)
+
+var (
+	ErrInvalidReaction  = errors.New("unknown reaction type")
+	ErrReactionNotFound = errors.New("you have not reacted to this post")
+)
+
+// Changes counters of a hash that is already loaded, a missing hash is loaded from MySQL when it is read
+var incrementLoadedCounters = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	for i = 1, #ARGV, 2 do
+		redis.call('HINCRBY', KEYS[1], ARGV[i], ARGV[i + 1])
+	end
+end
+return 0
+`)
+
+// Reaction Repository interface
+type ReactionRepositoryInterface interface {
+	React(postID, userID uint, reaction string) error
+	Unreact(postID, userID uint) error
+	Attach(posts []models.Post, viewerID uint) error
+	DeleteForPost(postID uint) error
+	Reconcile(limit int64) (int, error)
+}
+
+// Reaction repository struct
+type reactionRepository struct {
+	db  *gorm.DB
+	rdb *redis.Client
+}
+
+// Reaction repository constructor
+func NewReactionRepository(db *gorm.DB, rdb *redis.Client) ReactionRepositoryInterface {
+	return &reactionRepository{
+		db:  db,
+		rdb: rdb,
+	}
+}
+
+// How often counters are checked against MySQL, REACTION_RECONCILE_INTERVAL in seconds (default 60)
+func ReactionReconcileInterval() time.Duration {
+	return time.Duration(envInt64("REACTION_RECONCILE_INTERVAL", 60)) * time.Second
+}
+
+func reactionsKey(postID uint) string {
+	return fmt.Sprintf("post_reactions:%d", postID)
+}
+
+// Reaction repository methods
+
+// This method sets the reaction of a user to a post, an earlier reaction of the user is replaced
+func (r *reactionRepository) React(postID, userID uint, reaction string) error {
+	if !models.IsReactionType(reaction) {
+		return ErrInvalidReaction
+	}
+
+	var previous string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var existing models.Reaction
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("post_id = ? AND user_id = ?", postID, userID).
+			First(&existing).Error
+		switch {
+		case err == nil:
+			previous = existing.Type
+			if previous == reaction {
+				return nil
+			}
+			return tx.Model(&existing).Update("type", reaction).Error
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return tx.Create(&models.Reaction{PostID: postID, UserID: userID, Type: reaction}).Error
+		default:
+			return err
+		}
+	})
+	if err != nil {
+		log.Printf("[ERROR] Failed to save reaction of user %d to post %d: %v", userID, postID, err)
+		return err
+	}
+	if previous == reaction {
+		return nil
+	}
+
+	changes := []interface{}{reaction, 1}
+	if previous != "" {
+		changes = append(changes, previous, -1)
+	}
+	r.adjust(postID, changes...)
+
+	log.Printf("[INFO] User %d reacted %s to post %d", userID, reaction, postID)
+	return nil
+}
+
+// This method removes the reaction of a user to a post
+func (r *reactionRepository) Unreact(postID, userID uint) error {
+	var existing models.Reaction
+	err := r.db.Where("post_id = ? AND user_id = ?", postID, userID).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrReactionNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	result := r.db.Delete(&existing)
+	if result.Error != nil {
+		log.Printf("[ERROR] Failed to remove reaction of user %d to post %d: %v", userID, postID, result.Error)
+		return result.Error
+	}
+	// Someone else removed it in the meantime, the counter was already lowered
+	if result.RowsAffected == 0 {
+		return ErrReactionNotFound
+	}
+	r.adjust(postID, existing.Type, -1)
+
+	log.Printf("[INFO] User %d removed reaction from post %d", userID, postID)
+	return nil
+}
+
+// MySQL is the source of truth, a counter that could not be changed is fixed by the next reconcile
+func (r *reactionRepository) adjust(postID uint, changes ...interface{}) {
+	ctx := context.Background()
+	if err := incrementLoadedCounters.Run(ctx, r.rdb, []string{reactionsKey(postID)}, changes...).Err(); err != nil && !errors.Is(err, redis.Nil) {
+		log.Printf("[ERROR] Failed to update reaction counters of post %d: %v", postID, err)
+	}
+	if err := r.rdb.SAdd(ctx, ReactionsDirtyKey, postID).Err(); err != nil {
+		log.Printf("[ERROR] Failed to mark reaction counters of post %d for reconcile: %v", postID, err)
+	}
+}
+
+// This method fills the reaction counts of the posts and the viewer's own reaction
+//
+// Counters of all posts are read in one Redis round trip, missing ones are loaded with a single query.
+func (r *reactionRepository) Attach(posts []models.Post, viewerID uint) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	ctx := context.Background()
+
+	postIDs := make([]uint, 0, len(posts))
+	for _, post := range posts {
+		postIDs = append(postIDs, post.ID)
+	}
+
+	pipe := r.rdb.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, len(postIDs))
+	for i, id := range postIDs {
+		cmds[i] = pipe.HGetAll(ctx, reactionsKey(id))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+
+	counts := make(map[uint]map[string]int64, len(postIDs))
+	var missing []uint
+	for i, id := range postIDs {
+		fields := cmds[i].Val()
+		if _, loaded := fields[reactionsLoadedField]; !loaded {
+			missing = append(missing, id)
+			continue
+		}
+		counts[id] = parseCounters(fields)
+	}
+
+	if len(missing) > 0 {
+		loaded, err := r.countReactions(missing)
+		if err != nil {
+			return err
+		}
+		if err := r.storeCounters(missing, loaded); err != nil {
+			log.Printf("[ERROR] Failed to cache reaction counters: %v", err)
+		}
+		for _, id := range missing {
+			counts[id] = loaded[id]
+		}
+	}
+
+	mine := map[uint]string{}
+	if viewerID != 0 {
+		var reactions []models.Reaction
+		if err := r.db.Select("post_id", "type").
+			Where("user_id = ? AND post_id IN ?", viewerID, postIDs).
+			Find(&reactions).Error; err != nil {
+			return err
+		}
+		for _, reaction := range reactions {
+			mine[reaction.PostID] = reaction.Type
+		}
+	}
+
+	for i := range posts {
+		posts[i].Reactions = counts[posts[i].ID]
+		if posts[i].Reactions == nil {
+			posts[i].Reactions = map[string]int64{}
+		}
+		posts[i].MyReaction = mine[posts[i].ID]
+	}
+	return nil
+}
+
+func parseCounters(fields map[string]string) map[string]int64 {
+	counts := map[string]int64{}
+	for field, value := range fields {
+		if field == reactionsLoadedField {
+			continue
+		}
+		count, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || count <= 0 {
+			continue
+		}
+		counts[field] = count
+	}
+	return counts
+}
+
+// Counts reactions of the posts by type in one query
+func (r *reactionRepository) countReactions(postIDs []uint) (map[uint]map[string]int64, error) {
+	var rows []struct {
+		PostID uint
+		Type   string
+		Total  int64
+	}
+	if err := r.db.Model(&models.Reaction{}).
+		Select("post_id, type, COUNT(*) AS total").
+		Where("post_id IN ?", postIDs).
+		Group("post_id, type").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[uint]map[string]int64, len(postIDs))
+	for _, id := range postIDs {
+		counts[id] = map[string]int64{}
+	}
+	for _, row := range rows {
+		counts[row.PostID][row.Type] = row.Total
+	}
+	return counts, nil
+}
+
+// Replaces the counter hashes of the posts
+func (r *reactionRepository) storeCounters(postIDs []uint, counts map[uint]map[string]int64) error {
+	ctx := context.Background()
+	_, err := r.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, id := range postIDs {
+			key := reactionsKey(id)
+			fields := []interface{}{reactionsLoadedField, 0}
+			for reaction, count := range counts[id] {
+				fields = append(fields, reaction, count)
+			}
+			pipe.Del(ctx, key)
+			pipe.HSet(ctx, key, fields...)
+			pipe.Expire(ctx, key, reactionCountersTTL)
+		}
+		return nil
+	})
+	return err
+}
+
+// This method deletes all reactions to a post and its counters
+func (r *reactionRepository) DeleteForPost(postID uint) error {
+	if err := r.db.Where("post_id = ?", postID).Delete(&models.Reaction{}).Error; err != nil {
+		return err
+	}
+	return r.rdb.Del(context.Background(), reactionsKey(postID)).Err()
+}
+
+// This method recounts the counters of up to limit changed posts from MySQL
+//
+// It returns how many posts were reconciled, less than limit means nothing is left.
+func (r *reactionRepository) Reconcile(limit int64) (int, error) {
+	ctx := context.Background()
+	members, err := r.rdb.SPopN(ctx, ReactionsDirtyKey, limit).Result()
+	if err != nil {
+		return 0, err
+	}
+	if len(members) == 0 {
+		return 0, nil
+	}
+
+	postIDs := make([]uint, 0, len(members))
+	for _, member := range members {
+		id, err := strconv.ParseUint(member, 10, 64)
+		if err != nil {
+			continue
+		}
+		postIDs = append(postIDs, uint(id))
+	}
+
+	counts, err := r.countReactions(postIDs)
+	if err == nil {
+		err = r.storeCounters(postIDs, counts)
+	}
+	if err != nil {
+		// Keep them for the next run
+		r.rdb.SAdd(ctx, ReactionsDirtyKey, members)
+		return 0, err
+	}
+	return len(members), nil
+}
@@ -0,0 +1,195 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang_task/models"
+	"golang_task/utils"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// How long the state of a session is cached, and how often last seen is written
+const (
+	sessionStateTTL   = 5 * time.Minute
+	sessionSeenPeriod = time.Minute
+)
+
+// ClientInfo describes the device a session was created on
+type ClientInfo struct {
+	UserAgent string
+	IP        string
+}
+
+// Session Repository interface
+type SessionRepositoryInterface interface {
+	Create(userID uint, familyID string, client ClientInfo) error
+	ListActive(userID uint) ([]models.Session, error)
+	GetForUser(id, userID uint) (*models.Session, error)
+	Extend(familyID string) error
+	MarkRevoked(familyID string) error
+	MarkAllRevoked(userID uint) error
+	IsActive(familyID string) (bool, error)
+	Touch(familyID string)
+}
+
+// Session repository struct
+type sessionRepository struct {
+	db  *gorm.DB
+	rdb *redis.Client
+}
+
+// Session repository constructor
+func NewSessionRepository(db *gorm.DB, rdb *redis.Client) SessionRepositoryInterface {
+	return &sessionRepository{
+		db:  db,
+		rdb: rdb,
+	}
+}
+
+// Caches whether a session is active ("1") or revoked ("0")
+func sessionStateKey(familyID string) string {
+	return fmt.Sprintf("session_state:%s", familyID)
+}
+
+// Exists while last seen was written recently
+func sessionSeenKey(familyID string) string {
+	return fmt.Sprintf("session_seen:%s", familyID)
+}
+
+// Session repository methods
+
+// This method records a new login
+func (r *sessionRepository) Create(userID uint, familyID string, client ClientInfo) error {
+	now := time.Now()
+	userAgent := client.UserAgent
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+	session := models.Session{
+		UserID:     userID,
+		FamilyID:   familyID,
+		UserAgent:  userAgent,
+		IP:         client.IP,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(utils.RefreshTokenTTL()),
+	}
+	if err := r.db.Create(&session).Error; err != nil {
+		log.Printf("[ERROR] Failed to create session for user %d: %v", userID, err)
+		return err
+	}
+	return nil
+}
+
+// This method lists sessions that are neither revoked nor expired, most recently used first
+func (r *sessionRepository) ListActive(userID uint) ([]models.Session, error) {
+	sessions := []models.Session{}
+	err := r.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+// This method retrieves an active session, only its owner can see it
+func (r *sessionRepository) GetForUser(id, userID uint) (*models.Session, error) {
+	var session models.Session
+	err := r.db.Where("id = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > ?", id, userID, time.Now()).
+		First(&session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// This method moves the expiry of a session forward after its refresh token was rotated
+func (r *sessionRepository) Extend(familyID string) error {
+	now := time.Now()
+	return r.db.Model(&models.Session{}).Where("family_id = ?", familyID).
+		Updates(map[string]interface{}{"last_seen_at": now, "expires_at": now.Add(utils.RefreshTokenTTL())}).Error
+}
+
+// This method marks a session as revoked
+func (r *sessionRepository) MarkRevoked(familyID string) error {
+	if err := r.db.Model(&models.Session{}).Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+	return r.rdb.Set(context.Background(), sessionStateKey(familyID), "0", sessionStateTTL).Err()
+}
+
+// This method marks every session of a user as revoked
+func (r *sessionRepository) MarkAllRevoked(userID uint) error {
+	var familyIDs []string
+	if err := r.db.Model(&models.Session{}).Where("user_id = ? AND revoked_at IS NULL", userID).
+		Pluck("family_id", &familyIDs).Error; err != nil {
+		return err
+	}
+	if len(familyIDs) == 0 {
+		return nil
+	}
+	if err := r.db.Model(&models.Session{}).Where("family_id IN ?", familyIDs).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+
+	pipe := r.rdb.Pipeline()
+	for _, familyID := range familyIDs {
+		pipe.Set(context.Background(), sessionStateKey(familyID), "0", sessionStateTTL)
+	}
+	_, err := pipe.Exec(context.Background())
+	return err
+}
+
+// This method tells whether the tokens of a session may still be used
+//
+// The answer is cached in Redis. Token families without a session row come from
+// logins before sessions were recorded and are treated as active.
+func (r *sessionRepository) IsActive(familyID string) (bool, error) {
+	ctx := context.Background()
+	state, err := r.rdb.Get(ctx, sessionStateKey(familyID)).Result()
+	if err == nil {
+		return state == "1", nil
+	}
+	if !errors.Is(err, redis.Nil) {
+		return false, err
+	}
+
+	var session models.Session
+	active := true
+	if err := r.db.Select("revoked_at").Where("family_id = ?", familyID).First(&session).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, err
+		}
+	} else {
+		active = session.RevokedAt == nil
+	}
+
+	state = "0"
+	if active {
+		state = "1"
+	}
+	if err := r.rdb.Set(ctx, sessionStateKey(familyID), state, sessionStateTTL).Err(); err != nil {
+		log.Printf("[ERROR] Failed to cache state of session %s: %v", familyID, err)
+	}
+	return active, nil
+}
+
+// This method updates the last seen time, at most once a minute per session
+func (r *sessionRepository) Touch(familyID string) {
+	fresh, err := r.rdb.SetNX(context.Background(), sessionSeenKey(familyID), 1, sessionSeenPeriod).Result()
+	if err != nil || !fresh {
+		return
+	}
+	if err := r.db.Model(&models.Session{}).Where("family_id = ?", familyID).
+		Update("last_seen_at", time.Now()).Error; err != nil {
+		log.Printf("[ERROR] Failed to update last seen of session %s: %v", familyID, err)
+	}
+}
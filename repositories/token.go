@@ -0,0 +1,247 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang_task/utils"
+	"log"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+var ErrRefreshTokenReused = errors.New("refresh token was already used, please login again")
+
+// TokenPair is returned to the client on login, signup and refresh
+type TokenPair struct {
+	AccessToken  string `json:"access_token" example:"jwt_token_string"`
+	RefreshToken string `json:"refresh_token" example:"jwt_refresh_token_string"`
+	ExpiresIn    int64  `json:"expires_in" example:"900"`
+}
+
+// Token Repository interface
+type TokenRepositoryInterface interface {
+	IssuePair(userID uint, client ClientInfo) (*TokenPair, error)
+	Rotate(refreshToken string) (*TokenPair, error)
+	Revoke(claims *utils.JwtClaims) error
+	RevokeFamily(familyID string) error
+	RevokeAllForUser(userID uint) error
+	IsRevoked(claims *utils.JwtClaims) (bool, error)
+	CurrentGeneration(userID uint) (int64, error)
+}
+
+// Token repository struct
+//
+// Every token family is a session, so revoking tokens also revokes the session.
+type tokenRepository struct {
+	rdb      *redis.Client
+	sessions SessionRepositoryInterface
+}
+
+// Token repository constructor
+func NewTokenRepository(db *gorm.DB, rdb *redis.Client) TokenRepositoryInterface {
+	return &tokenRepository{
+		rdb:      rdb,
+		sessions: NewSessionRepository(db, rdb),
+	}
+}
+
+// Redis keys
+
+// Holds the only refresh token of a family that can still be used
+func refreshTokenKey(tokenID string) string {
+	return fmt.Sprintf("refresh_token:%s", tokenID)
+}
+
+// Marks a whole login (access and refresh tokens) as revoked
+func revokedFamilyKey(familyID string) string {
+	return fmt.Sprintf("revoked_family:%s", familyID)
+}
+
+// Denylist entry of a single token
+func deniedTokenKey(tokenID string) string {
+	return fmt.Sprintf("denied_jti:%s", tokenID)
+}
+
+// Current token generation of a user, tokens of older generations are revoked
+func tokenGenerationKey(userID uint) string {
+	return fmt.Sprintf("token_generation:%d", userID)
+}
+
+// Token repository methods
+
+// This method creates a new token family and returns its first access and refresh tokens
+//
+// The family is recorded as a session of the given client.
+func (r *tokenRepository) IssuePair(userID uint, client ClientInfo) (*TokenPair, error) {
+	familyID, err := utils.NewTokenID()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.sessions.Create(userID, familyID, client); err != nil {
+		return nil, err
+	}
+	return r.issue(userID, familyID)
+}
+
+func (r *tokenRepository) issue(userID uint, familyID string) (*TokenPair, error) {
+	generation, err := r.CurrentGeneration(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, _, err := utils.SignJwt(&utils.JwtClaims{
+		UserID:     userID,
+		FamilyID:   familyID,
+		Type:       utils.AccessTokenType,
+		Generation: generation,
+	}, utils.AccessTokenTTL())
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, refreshClaims, err := utils.SignJwt(&utils.JwtClaims{
+		UserID:     userID,
+		FamilyID:   familyID,
+		Type:       utils.RefreshTokenType,
+		Generation: generation,
+	}, utils.RefreshTokenTTL())
+	if err != nil {
+		return nil, err
+	}
+
+	// Remember the refresh token so it can be used exactly once
+	if err := r.rdb.Set(context.Background(), refreshTokenKey(refreshClaims.TokenID), familyID, utils.RefreshTokenTTL()).Err(); err != nil {
+		log.Printf("[ERROR] Failed to store refresh token for user %d: %v", userID, err)
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(utils.AccessTokenTTL().Seconds()),
+	}, nil
+}
+
+// This method exchanges a refresh token for a new pair
+//
+// A refresh token can be used only once. Presenting it again revokes the whole family.
+func (r *tokenRepository) Rotate(refreshToken string) (*TokenPair, error) {
+	claims, err := utils.VerifyJwt(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Type != utils.RefreshTokenType {
+		return nil, utils.ErrInvalidToken
+	}
+
+	revoked, err := r.IsRevoked(claims)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		log.Printf("[ERROR] User %d used a refresh token of revoked family %s", claims.UserID, claims.FamilyID)
+		return nil, utils.ErrInvalidToken
+	}
+
+	// Consume the refresh token, only one caller can delete it
+	deleted, err := r.rdb.Del(context.Background(), refreshTokenKey(claims.TokenID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if deleted == 0 {
+		log.Printf("[ERROR] Refresh token reuse detected for user %d, revoking family %s", claims.UserID, claims.FamilyID)
+		if err := r.RevokeFamily(claims.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	if err := r.sessions.Extend(claims.FamilyID); err != nil {
+		log.Printf("[ERROR] Failed to extend session %s of user %d: %v", claims.FamilyID, claims.UserID, err)
+	}
+
+	log.Printf("[INFO] Refresh token rotated for user %d", claims.UserID)
+	return r.issue(claims.UserID, claims.FamilyID)
+}
+
+// This method adds a single token to the denylist until it expires
+func (r *tokenRepository) Revoke(claims *utils.JwtClaims) error {
+	ttl := claims.RemainingTTL()
+	if ttl == 0 {
+		return nil
+	}
+	return r.rdb.Set(context.Background(), deniedTokenKey(claims.TokenID), 1, ttl).Err()
+}
+
+// This method revokes every access and refresh token of a family
+func (r *tokenRepository) RevokeFamily(familyID string) error {
+	if familyID == "" {
+		return nil
+	}
+	if err := r.rdb.Set(context.Background(), revokedFamilyKey(familyID), 1, utils.RefreshTokenTTL()).Err(); err != nil {
+		return err
+	}
+	return r.sessions.MarkRevoked(familyID)
+}
+
+// This method revokes every token issued to the user so far, e.g. after a password change
+func (r *tokenRepository) RevokeAllForUser(userID uint) error {
+	if err := r.rdb.Incr(context.Background(), tokenGenerationKey(userID)).Err(); err != nil {
+		log.Printf("[ERROR] Failed to revoke tokens of user %d: %v", userID, err)
+		return err
+	}
+	if err := r.sessions.MarkAllRevoked(userID); err != nil {
+		log.Printf("[ERROR] Failed to revoke sessions of user %d: %v", userID, err)
+		return err
+	}
+	log.Printf("[INFO] All tokens of user %d revoked", userID)
+	return nil
+}
+
+// This method returns the token generation new tokens of the user are signed with
+func (r *tokenRepository) CurrentGeneration(userID uint) (int64, error) {
+	generation, err := r.rdb.Get(context.Background(), tokenGenerationKey(userID)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return generation, err
+}
+
+// This method checks the denylist for the token, its family and the user's token generation,
+// then whether the session of the token was revoked
+func (r *tokenRepository) IsRevoked(claims *utils.JwtClaims) (bool, error) {
+	ctx := context.Background()
+	keys := []string{deniedTokenKey(claims.TokenID)}
+	if claims.FamilyID != "" {
+		keys = append(keys, revokedFamilyKey(claims.FamilyID))
+	}
+
+	pipe := r.rdb.Pipeline()
+	existsCmd := pipe.Exists(ctx, keys...)
+	generationCmd := pipe.Get(ctx, tokenGenerationKey(claims.UserID))
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return false, err
+	}
+
+	if existsCmd.Val() > 0 {
+		return true, nil
+	}
+	generation, err := generationCmd.Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return false, err
+	}
+	if claims.Generation < generation {
+		return true, nil
+	}
+
+	if claims.FamilyID == "" {
+		return false, nil
+	}
+	active, err := r.sessions.IsActive(claims.FamilyID)
+	if err != nil {
+		return false, err
+	}
+	return !active, nil
+}
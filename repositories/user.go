@@ -1,16 +1,30 @@
 package repositories
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"golang_task/models"
 	"golang_task/utils"
 	"log"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 )
 
+// Sorted set with every searchable term as "term:id", all scores are 0 so it is ordered lexicographically
+const userSearchIndexKey = "user_search_index"
+
+var (
+	ErrUserNotFound      = errors.New("user not found")
+	ErrUserAlreadyExists = errors.New("username or email already exists")
+	ErrInvalidRole       = errors.New("role must be user, moderator or admin")
+)
+
 // User Repository interface
 type UserRepositoryInterface interface {
 	Create(user *models.User) error
@@ -18,19 +32,28 @@ type UserRepositoryInterface interface {
 	GetByUsername(username string) (*models.User, error)
 	GetByEmail(email string) (*models.User, error)
 	Update(id uint, updates map[string]interface{}) error
+	MarkEmailVerified(id uint, email string) error
 	DeleteById(id uint) error
 	DeleteByUsername(username string) error
+	Search(query string, limit int) ([]models.User, error)
+	ListRecent(offset, limit int) ([]models.User, error)
+	SetRole(id uint, role string) error
+	Suspend(id uint) error
+	Unsuspend(id uint) error
+	RebuildSearchIndex() error
 }
 
 // User Repository
 type userRepository struct {
-	db *gorm.DB
+	db  *gorm.DB
+	rdb *redis.Client
 }
 
 // User Repository Constructor
-func NewUserRepository(db *gorm.DB) UserRepositoryInterface {
+func NewUserRepository(db *gorm.DB, rdb *redis.Client) UserRepositoryInterface {
 	return &userRepository{
-		db: db,
+		db:  db,
+		rdb: rdb,
 	}
 }
 
@@ -55,11 +78,12 @@ func (r *userRepository) Create(user *models.User) error {
 			strings.Contains(err.Error(), "constraint failed") ||
 			strings.Contains(err.Error(), "Duplicate") {
 			log.Printf("[ERROR] User with username %s or email %s already exists", user.Username, user.Email)
-			return fmt.Errorf("username or email already exists")
+			return ErrUserAlreadyExists
 		}
 		return err
 	}
 
+	r.indexUser(user)
 	return err
 }
 
@@ -71,7 +95,7 @@ func (r *userRepository) GetByID(id uint) (*models.User, error) {
 	if err := r.db.First(&user, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			log.Printf("[ERROR] User with id %d not found", id)
-			return nil, fmt.Errorf("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -86,7 +110,7 @@ func (r *userRepository) GetByUsername(username string) (*models.User, error) {
 	if err := r.db.Where("username = ?", username).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			log.Printf("[ERROR] User with username %s not found", username)
-			return nil, fmt.Errorf("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -101,7 +125,7 @@ func (r *userRepository) GetByEmail(email string) (*models.User, error) {
 	if err := r.db.Where("email = ?", email).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			log.Printf("[ERROR] User with email %s not found", email)
-			return nil, fmt.Errorf("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -114,29 +138,70 @@ func (r *userRepository) GetByEmail(email string) (*models.User, error) {
 func (r *userRepository) Update(id uint, updates map[string]interface{}) error {
 	var err error
 
+	// The old terms are needed to drop them from the search index
+	var previous *models.User
+	if updatesSearchTerms(updates) {
+		previous, err = r.GetByID(id)
+		if err != nil {
+			return err
+		}
+	}
+
 	user := models.User{
 		ID: id,
 	}
 	if err = r.db.Model(&user).Updates(updates).Error; err != nil {
 		if strings.Contains(err.Error(), "UNIQUE") || strings.Contains(err.Error(), "Duplicate") {
-			return fmt.Errorf("username or email already exists")
+			return ErrUserAlreadyExists
 		} else if errors.Is(err, gorm.ErrRecordNotFound) {
 			log.Printf("[ERROR] User with id %d not found", id)
 			return err
 		}
+		return err
 	}
-	return err
+
+	if previous != nil {
+		if current, err := r.GetByID(id); err == nil {
+			r.unindexUser(previous)
+			r.indexUser(current)
+		}
+	}
+	return nil
+}
+
+// This method marks the email of a user as verified
+//
+// The email must still be the one the verification link was sent to.
+func (r *userRepository) MarkEmailVerified(id uint, email string) error {
+	result := r.db.Model(&models.User{}).
+		Where("id = ? AND email = ?", id, email).
+		Update("email_verified_at", time.Now())
+	if result.Error != nil {
+		log.Printf("[ERROR] Failed to verify email of user %d: %v", id, result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		log.Printf("[ERROR] User %d with email %s not found for verification", id, email)
+		return fmt.Errorf("user not found or email has changed")
+	}
+	log.Printf("[INFO] Email of user %d verified", id)
+	return nil
 }
 
 // This method deletes a user by ID
 //
 // If the user is found, it deletes the user. If not, it returns an error.
 func (r *userRepository) DeleteById(id uint) error {
+	var user models.User
+	if err := r.db.First(&user, id).Error; err == nil {
+		r.unindexUser(&user)
+	}
+
 	var err error
 	if err = r.db.Where("id = ?", id).Delete(&models.User{}).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			log.Printf("[ERROR] User with id %d not found", id)
-			return fmt.Errorf("user not found")
+			return ErrUserNotFound
 		}
 	}
 	return err
@@ -146,12 +211,286 @@ func (r *userRepository) DeleteById(id uint) error {
 //
 // If the user is found, it deletes the user. If not, it returns an error.
 func (r *userRepository) DeleteByUsername(username string) error {
+	var user models.User
+	if err := r.db.Where("username = ?", username).First(&user).Error; err == nil {
+		r.unindexUser(&user)
+	}
+
 	var err error
 	if err = r.db.Where("username = ?", username).Delete(&models.User{}).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			log.Printf("[ERROR] User with username %s not found", username)
-			return fmt.Errorf("user not found")
+			return ErrUserNotFound
 		}
 	}
 	return err
 }
+
+// This method lists accounts, newest first
+func (r *userRepository) ListRecent(offset, limit int) ([]models.User, error) {
+	users := []models.User{}
+	err := r.db.Order("created_at DESC").Order("id DESC").Offset(offset).Limit(limit).Find(&users).Error
+	return users, err
+}
+
+// This method changes the role of a user
+func (r *userRepository) SetRole(id uint, role string) error {
+	if role != models.RoleUser && role != models.RoleModerator && role != models.RoleAdmin {
+		return ErrInvalidRole
+	}
+	result := r.db.Model(&models.User{}).Where("id = ?", id).Update("role", role)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		// Also the case when the role was already set, tell them apart
+		if _, err := r.GetByID(id); err != nil {
+			return err
+		}
+	}
+	log.Printf("[INFO] Role of user %d set to %s", id, role)
+	return nil
+}
+
+// This method suspends a user, suspended users can not log in
+func (r *userRepository) Suspend(id uint) error {
+	return r.setSuspendedAt(id, time.Now())
+}
+
+// This method lifts the suspension of a user
+func (r *userRepository) Unsuspend(id uint) error {
+	return r.setSuspendedAt(id, nil)
+}
+
+func (r *userRepository) setSuspendedAt(id uint, value interface{}) error {
+	if _, err := r.GetByID(id); err != nil {
+		return err
+	}
+	if err := r.db.Model(&models.User{}).Where("id = ?", id).Update("suspended_at", value).Error; err != nil {
+		log.Printf("[ERROR] Failed to change suspension of user %d: %v", id, err)
+		return err
+	}
+	return nil
+}
+
+// Search index
+
+// Lowercase terms a user can be found by
+func searchTerms(user *models.User) []string {
+	terms := []string{}
+	for _, term := range []string{user.Username, user.Firstname, user.Lastname} {
+		term = strings.ToLower(strings.TrimSpace(term))
+		if term != "" {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+func updatesSearchTerms(updates map[string]interface{}) bool {
+	for _, column := range []string{"username", "firstname", "lastname"} {
+		if _, ok := updates[column]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func searchMembers(user *models.User) []interface{} {
+	members := []interface{}{}
+	for _, term := range searchTerms(user) {
+		members = append(members, fmt.Sprintf("%s:%d", term, user.ID))
+	}
+	return members
+}
+
+// The index is only a cache of the users table, failures are logged and Search falls back to MySQL
+func (r *userRepository) indexUser(user *models.User) {
+	members := searchMembers(user)
+	if len(members) == 0 {
+		return
+	}
+	zs := make([]redis.Z, 0, len(members))
+	for _, member := range members {
+		zs = append(zs, redis.Z{Member: member})
+	}
+	if err := r.rdb.ZAdd(context.Background(), userSearchIndexKey, zs...).Err(); err != nil {
+		log.Printf("[ERROR] Failed to index user %d for search: %v", user.ID, err)
+	}
+}
+
+func (r *userRepository) unindexUser(user *models.User) {
+	members := searchMembers(user)
+	if len(members) == 0 {
+		return
+	}
+	if err := r.rdb.ZRem(context.Background(), userSearchIndexKey, members...).Err(); err != nil {
+		log.Printf("[ERROR] Failed to remove user %d from search index: %v", user.ID, err)
+	}
+}
+
+// This method fills the search index from the users table if it is empty
+func (r *userRepository) RebuildSearchIndex() error {
+	ctx := context.Background()
+	count, err := r.rdb.ZCard(ctx, userSearchIndexKey).Result()
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	var users []models.User
+	err = r.db.Select("id", "username", "firstname", "lastname").
+		FindInBatches(&users, 500, func(tx *gorm.DB, batch int) error {
+			zs := []redis.Z{}
+			for i := range users {
+				for _, member := range searchMembers(&users[i]) {
+					zs = append(zs, redis.Z{Member: member})
+				}
+			}
+			if len(zs) == 0 {
+				return nil
+			}
+			return r.rdb.ZAdd(ctx, userSearchIndexKey, zs...).Err()
+		}).Error
+	if err != nil {
+		log.Printf("[ERROR] Failed to rebuild user search index: %v", err)
+		return err
+	}
+	log.Printf("[INFO] User search index rebuilt")
+	return nil
+}
+
+var errSearchIndexEmpty = errors.New("search index is empty")
+
+// Rank of a match, lower is better
+const (
+	rankExactUsername = iota
+	rankExactName
+	rankPrefixUsername
+	rankPrefixName
+	rankNoMatch
+)
+
+func searchRank(user *models.User, query string) int {
+	username := strings.ToLower(user.Username)
+	firstname := strings.ToLower(user.Firstname)
+	lastname := strings.ToLower(user.Lastname)
+	switch {
+	case username == query:
+		return rankExactUsername
+	case firstname == query || lastname == query:
+		return rankExactName
+	case strings.HasPrefix(username, query):
+		return rankPrefixUsername
+	case strings.HasPrefix(firstname, query) || strings.HasPrefix(lastname, query):
+		return rankPrefixName
+	default:
+		return rankNoMatch
+	}
+}
+
+// This method finds users whose username, first name or last name starts with the query
+//
+// Exact matches come first, then prefix matches, username before names and shorter usernames first.
+// The Redis index is used when it is available, otherwise MySQL is queried.
+func (r *userRepository) Search(query string, limit int) ([]models.User, error) {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return []models.User{}, nil
+	}
+
+	users, err := r.searchIndex(query, limit)
+	if err != nil {
+		if !errors.Is(err, errSearchIndexEmpty) {
+			log.Printf("[ERROR] User search index unavailable, falling back to MySQL: %v", err)
+		}
+		users, err = r.searchDatabase(query, limit)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Drop entries the index still had for renamed users
+	matches := users[:0]
+	for _, user := range users {
+		if searchRank(&user, query) != rankNoMatch {
+			matches = append(matches, user)
+		}
+	}
+	users = matches
+
+	sort.SliceStable(users, func(i, j int) bool {
+		rankI, rankJ := searchRank(&users[i], query), searchRank(&users[j], query)
+		if rankI != rankJ {
+			return rankI < rankJ
+		}
+		if len(users[i].Username) != len(users[j].Username) {
+			return len(users[i].Username) < len(users[j].Username)
+		}
+		return users[i].Username < users[j].Username
+	})
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+func (r *userRepository) searchIndex(query string, limit int) ([]models.User, error) {
+	ctx := context.Background()
+	count, err := r.rdb.ZCard(ctx, userSearchIndexKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, errSearchIndexEmpty
+	}
+
+	// Members are term:id and '-', '.' and digits sort before ':', so longer terms like john.doe come before john.
+	// Exact matches are read on their own first, then prefix matches fill the rest of the page.
+	// A user can match with up to three terms, read enough to fill the page after dedup.
+	ranges := []*redis.ZRangeBy{
+		{Min: "[" + query + ":", Max: "[" + query + ":\xff", Count: int64(limit * 3)},
+		{Min: "[" + query, Max: "[" + query + "\xff", Count: int64(limit * 3)},
+	}
+	ids := []uint{}
+	seen := map[uint]bool{}
+	for _, by := range ranges {
+		if len(ids) >= limit {
+			break
+		}
+		members, err := r.rdb.ZRangeByLex(ctx, userSearchIndexKey, by).Result()
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range members {
+			id, err := strconv.ParseUint(member[strings.LastIndex(member, ":")+1:], 10, 64)
+			if err != nil || seen[uint(id)] {
+				continue
+			}
+			seen[uint(id)] = true
+			ids = append(ids, uint(id))
+		}
+	}
+
+	users := []models.User{}
+	if len(ids) == 0 {
+		return users, nil
+	}
+	if err := r.db.Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *userRepository) searchDatabase(query string, limit int) ([]models.User, error) {
+	pattern := strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(query) + "%"
+	users := []models.User{}
+	err := r.db.Where("username LIKE ? OR firstname LIKE ? OR lastname LIKE ?", pattern, pattern, pattern).
+		Order(gorm.Expr("CASE WHEN username = ? THEN 0 WHEN firstname = ? OR lastname = ? THEN 1 WHEN username LIKE ? THEN 2 ELSE 3 END", query, query, query, pattern)).
+		Order("CHAR_LENGTH(username)").
+		Limit(limit).
+		Find(&users).Error
+	return users, err
+}
@@ -0,0 +1,36 @@
+package routers
+
+import (
+	"golang_task/handlers"
+	"golang_task/middlewares"
+	"golang_task/models"
+	"golang_task/repositories"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+func AdminRoutes(app *fiber.App, db *gorm.DB, rdb *redis.Client) {
+	admin := app.Group("/admin")
+
+	attemptRepo := repositories.NewLoginAttemptRepository(rdb, repositories.LoginAttemptPolicyFromEnv())
+	erasureRepo := repositories.NewErasureRepository(db, rdb)
+	userRepo := repositories.NewUserRepository(db, rdb)
+	postRepo := repositories.NewPostRepository(db, rdb)
+	tokenRepo := repositories.NewTokenRepository(db, rdb)
+
+	// Moderation, open to moderators and admins
+	admin.Use(middlewares.AuthRequired(db, rdb), middlewares.RequireRole(db, rdb, models.RoleModerator, models.RoleAdmin))
+	admin.Delete("/posts/:id", handlers.RemovePostHandler(postRepo))
+	admin.Get("/users", handlers.ListRecentUsersHandler(userRepo))
+	admin.Post("/users/:user_id/suspend", handlers.SuspendUserHandler(userRepo, tokenRepo))
+	admin.Post("/users/:user_id/unsuspend", handlers.UnsuspendUserHandler(userRepo))
+
+	// Admin only
+	adminOnly := middlewares.RequireRole(db, rdb, models.RoleAdmin)
+	admin.Put("/users/:user_id/role", adminOnly, handlers.SetRoleHandler(userRepo))
+	admin.Delete("/lockouts/:user_id", adminOnly, handlers.ClearLockoutHandler(attemptRepo))
+	admin.Post("/users/:user_id/erasure", adminOnly, handlers.RequestErasureHandler(erasureRepo))
+	admin.Get("/erasures/:id", adminOnly, handlers.GetErasureJobHandler(erasureRepo))
+}
@@ -0,0 +1,25 @@
+package routers
+
+import (
+	"golang_task/handlers"
+	"golang_task/middlewares"
+	"golang_task/repositories"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+func BlockRoute(app *fiber.App, db *gorm.DB, rdb *redis.Client) {
+	repo := repositories.NewBlockRepository(db, rdb)
+
+	blocks := app.Group("/blocks", middlewares.AuthRequired(db, rdb))
+	blocks.Get("/", handlers.GetBlocked(repo))
+	blocks.Post("/:user_id", handlers.BlockUser(repo))
+	blocks.Delete("/:user_id", handlers.UnblockUser(repo))
+
+	mutes := app.Group("/mutes", middlewares.AuthRequired(db, rdb))
+	mutes.Get("/", handlers.GetMuted(repo))
+	mutes.Post("/:user_id", handlers.MuteUser(repo))
+	mutes.Delete("/:user_id", handlers.UnmuteUser(repo))
+}
@@ -3,6 +3,7 @@ package routers
 import (
 	"golang_task/handlers"
 	"golang_task/middlewares"
+	"golang_task/models"
 	"golang_task/repositories"
 
 	"github.com/gofiber/fiber/v2"
@@ -15,10 +16,15 @@ func FollowRoute(app *fiber.App, db *gorm.DB, rdb *redis.Client) {
 
 	repo := repositories.NewFollowRepository(db, rdb)
 	
-	follows.Use(middlewares.AuthRequired())
-	follows.Get("/followers", handlers.GetFollowers(repo))
-	follows.Get("/followings", handlers.GetFollowing(repo))
-	follows.Post("/:following_id", handlers.Follow(repo))
-	follows.Delete("/:following_id", handlers.Unfollow(repo))
+	read := middlewares.AuthRequired(db, rdb, models.ScopeFollowsRead)
+	write := middlewares.AuthRequired(db, rdb, models.ScopeFollowsWrite)
+
+	follows.Get("/followers", read, handlers.GetFollowers(repo))
+	follows.Get("/followings", read, handlers.GetFollowing(repo))
+	follows.Get("/requests", read, handlers.GetFollowRequests(repo))
+	follows.Post("/requests/:id/approve", write, handlers.ApproveFollowRequest(repo))
+	follows.Post("/requests/:id/reject", write, handlers.RejectFollowRequest(repo))
+	follows.Post("/:following_id", write, handlers.Follow(repo))
+	follows.Delete("/:following_id", write, handlers.Unfollow(repo))
 	
 }
\ No newline at end of file
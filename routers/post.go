@@ -3,6 +3,7 @@ package routers
 import (
 	"golang_task/handlers"
 	"golang_task/middlewares"
+	"golang_task/models"
 	"golang_task/repositories"
 
 	"github.com/gofiber/fiber/v2"
@@ -14,12 +15,26 @@ func PostRoute(app *fiber.App, db *gorm.DB, rdb *redis.Client) {
 	posts := app.Group("/posts")
 
 	repo := repositories.NewPostRepository(db, rdb)
+	reactionRepo := repositories.NewReactionRepository(db, rdb)
+	userRepo := repositories.NewUserRepository(db, rdb)
 
-	posts.Use(middlewares.AuthRequired())
-	posts.Post("/", handlers.PostCreate(repo))
-	posts.Get("/timeline/:limit/:page", handlers.PostTimeline(repo))
-	posts.Get("/:id", handlers.PostGetByID(repo))
-	posts.Delete("/:id", handlers.DeletePost(repo))
-	posts.Put("/:id", handlers.PostEdit(repo))
+	read := middlewares.AuthRequired(db, rdb, models.ScopePostsRead)
+	write := middlewares.AuthRequired(db, rdb, models.ScopePostsWrite)
+
+	posts.Post("/", write, middlewares.RequireVerifiedEmail(db, rdb), handlers.PostCreate(repo))
+	posts.Get("/timeline/:limit/:page", read, handlers.PostTimeline(repo))
+	posts.Get("/search", read, handlers.SearchPostsHandler(repo, userRepo))
+	posts.Get("/trash", read, handlers.GetTrashHandler(repo))
+	posts.Get("/:id", read, handlers.PostGetByID(repo))
+	posts.Get("/:id/thread", read, handlers.PostThread(repo))
+	posts.Post("/:id/replies", write, middlewares.RequireVerifiedEmail(db, rdb), handlers.PostReply(repo))
+	posts.Post("/:id/repost", write, middlewares.RequireVerifiedEmail(db, rdb), handlers.RepostHandler(repo))
+	posts.Delete("/:id/repost", write, handlers.UndoRepostHandler(repo))
+	posts.Post("/:id/quote", write, middlewares.RequireVerifiedEmail(db, rdb), handlers.QuotePostHandler(repo))
+	posts.Post("/:id/reactions", write, handlers.ReactToPostHandler(repo, reactionRepo))
+	posts.Delete("/:id/reactions", write, handlers.RemoveReactionHandler(repo, reactionRepo))
+	posts.Delete("/:id", write, handlers.DeletePost(repo))
+	posts.Post("/:id/restore", write, handlers.RestorePostHandler(repo))
+	posts.Put("/:id", write, handlers.PostEdit(repo))
 	
 }
\ No newline at end of file
@@ -0,0 +1,19 @@
+package routers
+
+import (
+	"golang_task/handlers"
+	"golang_task/middlewares"
+	"golang_task/models"
+	"golang_task/repositories"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+func TagRoute(app *fiber.App, db *gorm.DB, rdb *redis.Client) {
+	repo := repositories.NewPostRepository(db, rdb)
+
+	tags := app.Group("/tags", middlewares.AuthRequired(db, rdb, models.ScopePostsRead))
+	tags.Get("/:tag/posts", handlers.GetTagPostsHandler(repo))
+}
@@ -2,18 +2,80 @@ package routers
 
 import (
 	"golang_task/handlers"
+	"golang_task/middlewares"
+	"golang_task/models"
 	"golang_task/repositories"
+	"golang_task/utils"
+	"log"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 )
 
-func UserRoutes(app *fiber.App, db *gorm.DB) {
+func UserRoutes(app *fiber.App, db *gorm.DB, rdb *redis.Client, mailer utils.Mailer) {
 	users := app.Group("/users")
 	
-	repo := repositories.NewUserRepository(db)
+	repo := repositories.NewUserRepository(db, rdb)
+	tokenRepo := repositories.NewTokenRepository(db, rdb)
+	sessionRepo := repositories.NewSessionRepository(db, rdb)
+	resetRepo := repositories.NewPasswordResetRepository(rdb)
+	mfaRepo := repositories.NewMfaRepository(db, rdb, utils.SystemClock{})
+	attemptRepo := repositories.NewLoginAttemptRepository(rdb, repositories.LoginAttemptPolicyFromEnv())
+	erasureRepo := repositories.NewErasureRepository(db, rdb)
+	exportRepo := repositories.NewExportRepository(db, rdb)
+	followRepo := repositories.NewFollowRepository(db, rdb)
+	identityRepo := repositories.NewIdentityRepository(db, rdb)
+	passwordPolicy := utils.PasswordPolicyFromEnv()
+	personalTokenRepo := repositories.NewPersonalTokenRepository(db, rdb)
+	blockRepo := repositories.NewBlockRepository(db, rdb)
 
-	users.Post("/signup", handlers.RegisterHandler(repo))
-	users.Post("/login", handlers.LoginHandler(repo))
+	oidcProviders, err := utils.LoadOIDCProviders()
+	if err != nil {
+		log.Printf("[ERROR] Login providers are disabled: %v", err)
+		oidcProviders = map[string]*utils.OIDCProvider{}
+	}
+	postRepo := repositories.NewPostRepository(db, rdb)
+
+	users.Post("/signup", handlers.RegisterHandler(repo, tokenRepo, mailer, passwordPolicy))
+	users.Post("/login", handlers.LoginHandler(repo, tokenRepo, attemptRepo))
+	users.Post("/login/2fa", handlers.MfaLoginHandler(mfaRepo, tokenRepo, attemptRepo))
+	users.Get("/oidc/:provider/login", handlers.OIDCLoginHandler(oidcProviders, identityRepo))
+	users.Get("/oidc/:provider/callback", handlers.OIDCCallbackHandler(oidcProviders, identityRepo, tokenRepo))
+	users.Post("/refresh", handlers.RefreshHandler(tokenRepo))
+	users.Post("/logout", middlewares.AuthRequired(db, rdb), handlers.LogoutHandler(tokenRepo))
+	users.Post("/password/forgot", handlers.ForgotPasswordHandler(repo, resetRepo, mailer))
+	users.Post("/password/reset", handlers.ResetPasswordHandler(repo, resetRepo, tokenRepo, passwordPolicy))
+	users.Get("/verify", handlers.VerifyEmailHandler(repo))
+	users.Post("/verify/resend", middlewares.AuthRequired(db, rdb), handlers.ResendVerificationHandler(repo, mailer))
+	users.Get("/export/download", handlers.DownloadExportHandler(exportRepo))
+
+	// Account settings need a login, personal access tokens can only read the profile
+	login := middlewares.AuthRequired(db, rdb)
+	me := users.Group("/me")
+	me.Get("/", middlewares.AuthRequired(db, rdb, models.ScopeProfileRead), handlers.GetProfileHandler(repo))
+	me.Patch("/", login, handlers.UpdateProfileHandler(repo, followRepo, mailer))
+	me.Put("/avatar", login, handlers.UploadAvatarHandler(repo))
+	me.Delete("/avatar", login, handlers.DeleteAvatarHandler(repo))
+	me.Put("/banner", login, handlers.UploadBannerHandler(repo))
+	me.Delete("/banner", login, handlers.DeleteBannerHandler(repo))
+	me.Delete("/", login, handlers.DeleteAccountHandler(repo, tokenRepo, erasureRepo))
+	me.Put("/password", login, handlers.ChangePasswordHandler(repo, tokenRepo, passwordPolicy))
+	me.Post("/2fa/setup", login, handlers.MfaSetupHandler(mfaRepo))
+	me.Post("/2fa/confirm", login, handlers.MfaConfirmHandler(mfaRepo))
+	me.Post("/2fa/disable", login, handlers.MfaDisableHandler(repo, mfaRepo))
+	me.Get("/sessions", login, handlers.ListSessionsHandler(sessionRepo))
+	me.Delete("/sessions/:id", login, handlers.RevokeSessionHandler(sessionRepo, tokenRepo))
+	me.Get("/tokens", login, handlers.ListPersonalTokensHandler(personalTokenRepo))
+	me.Post("/tokens", login, handlers.CreatePersonalTokenHandler(personalTokenRepo))
+	me.Delete("/tokens/:id", login, handlers.DeletePersonalTokenHandler(personalTokenRepo))
+	me.Post("/export", login, handlers.RequestExportHandler(exportRepo))
+	me.Get("/export/:id", login, handlers.GetExportHandler(exportRepo))
+
+	// Registered last, so they do not shadow the routes above
+	usersRead := middlewares.AuthRequired(db, rdb, models.ScopeUsersRead)
+	users.Get("/search", usersRead, handlers.SearchUsersHandler(repo))
+	users.Get("/:username", usersRead, handlers.GetPublicProfileHandler(repo, followRepo, postRepo, blockRepo))
+	users.Get("/:username/posts", usersRead, handlers.GetUserPostsHandler(repo, postRepo, followRepo, blockRepo))
 
 }
\ No newline at end of file
@@ -0,0 +1,13 @@
+package routers
+
+import (
+	"golang_task/handlers"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func WellKnownRoutes(app *fiber.App) {
+	wellKnown := app.Group("/.well-known")
+
+	wellKnown.Get("/jwks.json", handlers.JwksHandler())
+}
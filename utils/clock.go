@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock lets time dependent code (TOTP) run against a fake time in tests
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock returns the real time
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// FakeClock returns a time that only changes when it is told to
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// FakeClock constructor
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// This method moves the clock forward
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// This method sets the clock to a fixed time
+func (c *FakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"golang_task/models"
+	"strings"
+	"unicode"
+)
+
+// Longest hashtag and mention, longer ones are not entities
+const (
+	maxHashtagLength = 100
+	maxMentionLength = 50
+)
+
+// Zero width non-joiner, part of many Persian words
+const zwnj = '\u200c'
+
+func isEntityRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) || r == '_' || r == zwnj
+}
+
+// ParseEntities finds #hashtags and @mentions in a post's content
+//
+// A # or @ only starts an entity at the beginning or after a character that can not be part of one,
+// so emails and URL fragments are left alone. Hashtags need a letter and are lowercased.
+func ParseEntities(content string) []models.PostEntity {
+	runes := []rune(content)
+	entities := []models.PostEntity{}
+
+	for i := 0; i < len(runes); i++ {
+		sigil := runes[i]
+		if sigil != '#' && sigil != '@' {
+			continue
+		}
+		if i > 0 && (isEntityRune(runes[i-1]) || runes[i-1] == '&' || runes[i-1] == '#' || runes[i-1] == '@') {
+			continue
+		}
+
+		end := i + 1
+		for end < len(runes) && isEntityRune(runes[end]) {
+			end++
+		}
+		// A trailing non-joiner is not part of the word
+		for end > i+1 && runes[end-1] == zwnj {
+			end--
+		}
+		text := string(runes[i+1 : end])
+		length := end - i - 1
+
+		switch {
+		case length == 0:
+			continue
+		case sigil == '#':
+			if length > maxHashtagLength || !strings.ContainsFunc(text, unicode.IsLetter) {
+				i = end - 1
+				continue
+			}
+			entities = append(entities, models.PostEntity{Type: models.EntityHashtag, Text: strings.ToLower(text), Start: i, End: end})
+		default:
+			if length > maxMentionLength {
+				i = end - 1
+				continue
+			}
+			entities = append(entities, models.PostEntity{Type: models.EntityMention, Text: text, Start: i, End: end})
+		}
+		i = end - 1
+	}
+	return entities
+}
+
+// NormalizeHashtag returns the stored form of a tag, an optional leading # is removed
+func NormalizeHashtag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+}
+
+// SearchTokens splits text into lowercase words for search, with the same word characters as hashtags
+func SearchTokens(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !isEntityRune(r)
+	})
+}
@@ -1,15 +1,189 @@
 package utils
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
 
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
 
-// This function hashes password with bcrypt
+// Supported password hash algorithms, PASSWORD_HASH_ALGORITHM picks the one new hashes use
+const (
+	HashArgon2id = "argon2id"
+	HashBcrypt   = "bcrypt"
+)
+
+var (
+	ErrEmptyPassword       = errors.New("password can not be empty")
+	ErrPasswordMismatch    = errors.New("password does not match")
+	ErrUnknownPasswordHash = errors.New("unknown password hash format")
+)
+
+// PasswordHashParams are the settings new password hashes are made with
+type PasswordHashParams struct {
+	Algorithm   string
+	BcryptCost  int
+	Memory      uint32 // argon2id memory in KiB
+	Iterations  uint32
+	Parallelism uint8
+}
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+func uintFromEnv(key string, fallback uint64) uint64 {
+	value, err := strconv.ParseUint(os.Getenv(key), 10, 32)
+	if err != nil || value == 0 {
+		return fallback
+	}
+	return value
+}
+
+// This function reads the hash settings from the environment
+//
+// PASSWORD_HASH_ALGORITHM is argon2id (default) or bcrypt. BCRYPT_COST defaults to 12,
+// ARGON2_MEMORY (KiB), ARGON2_ITERATIONS and ARGON2_PARALLELISM default to 65536, 3 and 2.
+func PasswordHashParamsFromEnv() PasswordHashParams {
+	params := PasswordHashParams{
+		Algorithm:   HashArgon2id,
+		BcryptCost:  int(uintFromEnv("BCRYPT_COST", 12)),
+		Memory:      uint32(uintFromEnv("ARGON2_MEMORY", 64*1024)),
+		Iterations:  uint32(uintFromEnv("ARGON2_ITERATIONS", 3)),
+		Parallelism: uint8(min(uintFromEnv("ARGON2_PARALLELISM", 2), 255)),
+	}
+	if strings.ToLower(os.Getenv("PASSWORD_HASH_ALGORITHM")) == HashBcrypt {
+		params.Algorithm = HashBcrypt
+	}
+	params.BcryptCost = max(min(params.BcryptCost, bcrypt.MaxCost), bcrypt.MinCost)
+	return params
+}
+
+// This function hashes password with the configured algorithm
+//
+// argon2id hashes are stored in the PHC string format: $argon2id$v=19$m=65536,t=3,p=2$salt$hash
 func HashPassword(password string) (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(hash), err
+	if password == "" {
+		return "", ErrEmptyPassword
+	}
+
+	params := PasswordHashParamsFromEnv()
+	if params.Algorithm == HashBcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), params.BcryptCost)
+		return string(hash), err
+	}
+
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, argon2KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, params.Memory, params.Iterations, params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// argon2id hash parsed from its PHC string
+type argon2Hash struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
 }
 
-// This function checks password with bcrypt
+func parseArgon2Hash(hash string) (*argon2Hash, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != HashArgon2id {
+		return nil, ErrUnknownPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, ErrUnknownPasswordHash
+	}
+
+	var parsed argon2Hash
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &parsed.memory, &parsed.iterations, &parsed.parallelism); err != nil {
+		return nil, ErrUnknownPasswordHash
+	}
+
+	var err error
+	if parsed.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, ErrUnknownPasswordHash
+	}
+	if parsed.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(parsed.key) == 0 {
+		return nil, ErrUnknownPasswordHash
+	}
+	return &parsed, nil
+}
+
+// This function checks password against a bcrypt or argon2id hash
 func CheckPasswordHash(password, hash string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if strings.HasPrefix(hash, "$argon2id$") {
+		parsed, err := parseArgon2Hash(hash)
+		if err != nil {
+			return err
+		}
+		key := argon2.IDKey([]byte(password), parsed.salt, parsed.iterations, parsed.memory, parsed.parallelism, uint32(len(parsed.key)))
+		if subtle.ConstantTimeCompare(key, parsed.key) != 1 {
+			return ErrPasswordMismatch
+		}
+		return nil
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrPasswordMismatch
+		}
+		return err
+	}
+	return nil
+}
+
+// This function reports whether a hash was made with another algorithm or weaker settings than the configured ones
+//
+// Call it after a successful CheckPasswordHash and store a new hash of the password if it returns true.
+func PasswordNeedsRehash(hash string) bool {
+	params := PasswordHashParamsFromEnv()
+
+	if params.Algorithm == HashBcrypt {
+		cost, err := bcrypt.Cost([]byte(hash))
+		return err != nil || cost != params.BcryptCost
+	}
+
+	parsed, err := parseArgon2Hash(hash)
+	if err != nil {
+		return true
+	}
+	return parsed.memory != params.Memory ||
+		parsed.iterations != params.Iterations ||
+		parsed.parallelism != params.Parallelism ||
+		len(parsed.key) != argon2KeyLength
+}
+
+var (
+	dummyHash     string
+	dummyHashOnce sync.Once
+)
+
+// This function takes as long as CheckPasswordHash but always fails
+//
+// It is used when the user does not exist, so response times do not reveal valid usernames.
+func CheckPasswordAgainstDummy(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = HashPassword("dummy password used for timing")
+	})
+	_ = CheckPasswordHash(password, dummyHash)
 }
@@ -1,16 +1,55 @@
 package utils
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
+	"crypto/rand"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 )
 
+// Token types stored in the "typ" claim
+const (
+	AccessTokenType      = "access"
+	RefreshTokenType     = "refresh"
+	EmailVerifyTokenType = "email_verify"
+	MfaPendingTokenType  = "mfa_pending"
+)
+
+// Time the user has to enter the two-factor code after the password was accepted
+const MfaPendingTokenTTL = 5 * time.Minute
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrExpiredToken = errors.New("token had expired")
+)
+
+// JwtClaims is the payload of every token created by CreateJwt
+type JwtClaims struct {
+	UserID    uint   `json:"sub"`
+	TokenID   string `json:"jti"`
+	FamilyID  string `json:"fam"`
+	Type      string `json:"typ"`
+	Email     string `json:"email,omitempty"`
+	// Generation is compared with the user's current token generation, bumping it revokes every older token
+	Generation int64 `json:"gen,omitempty"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// This method returns how long the token is still valid
+func (c *JwtClaims) RemainingTTL() time.Duration {
+	ttl := time.Until(time.Unix(c.ExpiresAt, 0))
+	if ttl < 0 {
+		return 0
+	}
+	return ttl
+}
+
 func base64UrlEncode(data []byte) string {
 	enc := base64.URLEncoding.WithPadding(base64.NoPadding)
 	return enc.EncodeToString(data)
@@ -21,83 +60,150 @@ func base64UrlDecode(encoded string) ([]byte, error) {
 	return enc.DecodeString(encoded)
 }
 
+func durationFromEnv(key string, unit time.Duration, fallback uint64) time.Duration {
+	value, err := strconv.ParseUint(os.Getenv(key), 10, 64)
+	if err != nil || value == 0 {
+		value = fallback
+	}
+	return time.Duration(value) * unit
+}
+
+// Access token lifetime, ACCESS_TOKEN_TTL in minutes (default 15)
+func AccessTokenTTL() time.Duration {
+	return durationFromEnv("ACCESS_TOKEN_TTL", time.Minute, 15)
+}
+
+// Refresh token lifetime, REFRESH_TOKEN_TTL in hours (default 720)
+func RefreshTokenTTL() time.Duration {
+	return durationFromEnv("REFRESH_TOKEN_TTL", time.Hour, 720)
+}
+
+// This function returns a random hex id used for jti and token families
+func NewTokenID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid,omitempty"`
+}
 
 // Create JWT function
-func CreateJwt(userID uint) (string, error) {
+//
+// Every token gets a fresh jti. familyID ties access and refresh tokens of one login together.
+func CreateJwt(userID uint, tokenType, familyID string, ttl time.Duration) (string, *JwtClaims, error) {
+	return SignJwt(&JwtClaims{
+		UserID:   userID,
+		FamilyID: familyID,
+		Type:     tokenType,
+	}, ttl)
+}
+
+// This function fills jti, iat and exp of the claims and signs them
+//
+// The token is signed with the signing key of the keyring and carries its kid.
+func SignJwt(claims *JwtClaims, ttl time.Duration) (string, *JwtClaims, error) {
+	keyring, err := GetJwtKeyring()
+	if err != nil {
+		return "", nil, err
+	}
+	key := keyring.SigningKey()
+
 	// Create header and marshal
-	header := map[string]interface{}{
-		"alg": "HS256",
-		"typ": "JWT",
+	header := jwtHeader{
+		Alg: key.Algorithm,
+		Typ: "JWT",
+		Kid: key.ID,
 	}
 
 	headerJson, err := json.Marshal(header)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
-	// Create payload and marshal
-	payload := map[string]interface{}{
-		"sub": userID,
-		"iat": time.Now().Unix(),
-		"exp": time.Now().Add(time.Hour * 24).Unix(),
+	tokenID, err := NewTokenID()
+	if err != nil {
+		return "", nil, err
 	}
 
-	payloadJson, err := json.Marshal(payload)
+	// Create payload and marshal
+	now := time.Now()
+	claims.TokenID = tokenID
+	claims.IssuedAt = now.Unix()
+	claims.ExpiresAt = now.Add(ttl).Unix()
+
+	payloadJson, err := json.Marshal(claims)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
 	// Encode header and payload
-	encodedHeader := base64UrlEncode(headerJson)
-	encodedPayload := base64UrlEncode(payloadJson)
-
-	unsignedToken := encodedHeader + "." + encodedPayload
-
+	unsignedToken := base64UrlEncode(headerJson) + "." + base64UrlEncode(payloadJson)
 
-	// Hash token
-	h := hmac.New(sha256.New, []byte(os.Getenv("JWT_SECRET")))
-	h.Write([]byte(unsignedToken))
-	signature := base64UrlEncode(h.Sum(nil))
+	// Sign token
+	signature, err := key.Sign([]byte(unsignedToken))
+	if err != nil {
+		return "", nil, err
+	}
 
-	return unsignedToken + "." + signature, nil
+	return unsignedToken + "." + base64UrlEncode(signature), claims, nil
 }
 
-func VerifyJwt(token string) (userID uint, ok bool, err error) {
-	// Split token and decode 
+// This function checks the token signature and expire time and returns its claims
+func VerifyJwt(token string) (*JwtClaims, error) {
+	// Split token
 	parts := strings.Split(token, ".")
 	if len(parts) != 3 {
-		return 0, false, errors.New("invalid token")
+		return nil, ErrInvalidToken
 	}
 
-	unsignedToken := parts[0] + "." + parts[1]
-	signature := parts[2]
+	keyring, err := GetJwtKeyring()
+	if err != nil {
+		return nil, err
+	}
 
-	payloadJson, err := base64UrlDecode(parts[1])
+	// Find the key by kid, tokens without kid were signed with JWT_SECRET
+	headerJson, err := base64UrlDecode(parts[0])
 	if err != nil {
-		return 0, false, err
+		return nil, ErrInvalidToken
+	}
+	var header jwtHeader
+	if err := json.Unmarshal(headerJson, &header); err != nil {
+		return nil, ErrInvalidToken
+	}
+	key, ok := keyring.Key(header.Kid)
+	if !ok || key.Algorithm != header.Alg {
+		return nil, ErrInvalidToken
 	}
 
-	var payloadMap map[string]interface{}
-	if err := json.Unmarshal(payloadJson, &payloadMap); err != nil {
-		return 0, false, err
+	// Check signature before trusting the payload
+	signature, err := base64UrlDecode(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if !key.Verify([]byte(parts[0]+"."+parts[1]), signature) {
+		return nil, ErrInvalidToken
 	}
 
-	// Check expire time
-	var tokenExpireTime = int64(payloadMap["exp"].(float64))
-	if tokenExpireTime <= time.Now().Unix() {
-		return 0, false, errors.New("token had expired")
+	payloadJson, err := base64UrlDecode(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
 	}
 
-	// Check Token
-	h := hmac.New(sha256.New, []byte(os.Getenv("JWT_SECRET")))
-	h.Write([]byte(unsignedToken))
-	expectedSignature := base64UrlEncode(h.Sum(nil))
+	var claims JwtClaims
+	if err := json.Unmarshal(payloadJson, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
 
-	check := hmac.Equal([]byte(signature), []byte(expectedSignature))
-	if check {
-		userID = uint(payloadMap["sub"].(float64))
-	} else {
-		userID = 0
+	// Check expire time
+	if claims.ExpiresAt <= time.Now().Unix() {
+		return nil, ErrExpiredToken
 	}
-	return userID, check, nil
+
+	return &claims, nil
 }
@@ -0,0 +1,312 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Supported signing algorithms
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// Key id used for JWT_SECRET and for tokens issued before key ids existed
+const legacyKeyID = "default"
+
+// JwtKey is one key of the keyring
+//
+// HS256 keys only have a secret. Asymmetric keys always have a public key and
+// have a private key only if they are allowed to sign.
+type JwtKey struct {
+	ID         string
+	Algorithm  string
+	secret     []byte
+	privateKey crypto.Signer
+	publicKey  crypto.PublicKey
+}
+
+// This method reports whether the key can create signatures
+func (k *JwtKey) CanSign() bool {
+	return len(k.secret) > 0 || k.privateKey != nil
+}
+
+// This method signs data with the key
+func (k *JwtKey) Sign(data []byte) ([]byte, error) {
+	switch k.Algorithm {
+	case AlgHS256:
+		h := hmac.New(sha256.New, k.secret)
+		h.Write(data)
+		return h.Sum(nil), nil
+	case AlgRS256:
+		if k.privateKey == nil {
+			return nil, fmt.Errorf("key %s can not sign", k.ID)
+		}
+		digest := sha256.Sum256(data)
+		return k.privateKey.Sign(rand.Reader, digest[:], crypto.SHA256)
+	case AlgEdDSA:
+		if k.privateKey == nil {
+			return nil, fmt.Errorf("key %s can not sign", k.ID)
+		}
+		return k.privateKey.Sign(rand.Reader, data, crypto.Hash(0))
+	}
+	return nil, fmt.Errorf("unsupported algorithm %s", k.Algorithm)
+}
+
+// This method checks a signature created by Sign
+func (k *JwtKey) Verify(data, signature []byte) bool {
+	switch k.Algorithm {
+	case AlgHS256:
+		expected, _ := k.Sign(data)
+		return hmac.Equal(signature, expected)
+	case AlgRS256:
+		publicKey, ok := k.publicKey.(*rsa.PublicKey)
+		if !ok {
+			return false
+		}
+		digest := sha256.Sum256(data)
+		return rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature) == nil
+	case AlgEdDSA:
+		publicKey, ok := k.publicKey.(ed25519.PublicKey)
+		if !ok {
+			return false
+		}
+		return ed25519.Verify(publicKey, data, signature)
+	}
+	return false
+}
+
+// Jwk is the JSON Web Key representation of a public key
+type Jwk struct {
+	Kty string `json:"kty" example:"OKP"`
+	Kid string `json:"kid" example:"2025-01"`
+	Alg string `json:"alg" example:"EdDSA"`
+	Use string `json:"use" example:"sig"`
+	Crv string `json:"crv,omitempty" example:"Ed25519"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+// This method returns the public part of the key, HS256 keys are never published
+func (k *JwtKey) PublicJwk() (*Jwk, bool) {
+	switch publicKey := k.publicKey.(type) {
+	case ed25519.PublicKey:
+		return &Jwk{Kty: "OKP", Kid: k.ID, Alg: AlgEdDSA, Use: "sig", Crv: "Ed25519", X: base64UrlEncode(publicKey)}, true
+	case *rsa.PublicKey:
+		return &Jwk{
+			Kty: "RSA",
+			Kid: k.ID,
+			Alg: AlgRS256,
+			Use: "sig",
+			N:   base64UrlEncode(publicKey.N.Bytes()),
+			E:   base64UrlEncode(big.NewInt(int64(publicKey.E)).Bytes()),
+		}, true
+	}
+	return nil, false
+}
+
+// This method turns a published key into a verify-only key, e.g. a key of an OpenID provider
+//
+// RSA keys are used with RS256 and Ed25519 keys with EdDSA.
+func (j Jwk) JwtKey() (*JwtKey, error) {
+	switch j.Kty {
+	case "RSA":
+		if j.Alg != "" && j.Alg != AlgRS256 {
+			return nil, fmt.Errorf("key %s has unsupported algorithm %s", j.Kid, j.Alg)
+		}
+		n, err := base64UrlDecode(j.N)
+		if err != nil {
+			return nil, fmt.Errorf("key %s has an invalid modulus: %w", j.Kid, err)
+		}
+		e, err := base64UrlDecode(j.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("key %s has an invalid exponent", j.Kid)
+		}
+		publicKey := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		return &JwtKey{ID: j.Kid, Algorithm: AlgRS256, publicKey: publicKey}, nil
+	case "OKP":
+		if j.Crv != "Ed25519" {
+			return nil, fmt.Errorf("key %s has unsupported curve %s", j.Kid, j.Crv)
+		}
+		x, err := base64UrlDecode(j.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("key %s has an invalid public key", j.Kid)
+		}
+		return &JwtKey{ID: j.Kid, Algorithm: AlgEdDSA, publicKey: ed25519.PublicKey(x)}, nil
+	}
+	return nil, fmt.Errorf("key %s has unsupported key type %s", j.Kid, j.Kty)
+}
+
+// JwtKeyring holds every key that can verify tokens and the one that signs new tokens
+type JwtKeyring struct {
+	signingKey *JwtKey
+	keys       map[string]*JwtKey
+}
+
+// This method finds a verification key by its id
+func (k *JwtKeyring) Key(id string) (*JwtKey, bool) {
+	if id == "" {
+		id = legacyKeyID
+	}
+	key, ok := k.keys[id]
+	return key, ok
+}
+
+// This method returns the key used for new tokens
+func (k *JwtKeyring) SigningKey() *JwtKey {
+	return k.signingKey
+}
+
+// This method returns the public keys of the keyring as a JWKS document
+func (k *JwtKeyring) Jwks() []Jwk {
+	keys := []Jwk{}
+	for _, key := range k.keys {
+		if jwk, ok := key.PublicJwk(); ok {
+			keys = append(keys, *jwk)
+		}
+	}
+	return keys
+}
+
+// This function builds the keyring from the environment
+//
+// JWT_KEYS is a comma separated list of kid:alg:path entries. For HS256 the file
+// holds the secret, for RS256 and EdDSA it holds a PEM private key (can sign)
+// or public key (verify only). JWT_SIGNING_KEY selects the kid used to sign.
+// JWT_SECRET is kept as an HS256 key with kid "default" so old tokens stay valid.
+func LoadJwtKeyring() (*JwtKeyring, error) {
+	keyring := &JwtKeyring{keys: map[string]*JwtKey{}}
+
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		keyring.keys[legacyKeyID] = &JwtKey{ID: legacyKeyID, Algorithm: AlgHS256, secret: []byte(secret)}
+	}
+
+	for _, entry := range strings.Split(os.Getenv("JWT_KEYS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid JWT_KEYS entry %q, expected kid:alg:path", entry)
+		}
+		key, err := loadJwtKey(parts[0], parts[1], parts[2])
+		if err != nil {
+			return nil, err
+		}
+		keyring.keys[key.ID] = key
+	}
+
+	signingKeyID := os.Getenv("JWT_SIGNING_KEY")
+	if signingKeyID == "" {
+		signingKeyID = legacyKeyID
+	}
+	signingKey, ok := keyring.keys[signingKeyID]
+	if !ok {
+		return nil, fmt.Errorf("signing key %q is not configured", signingKeyID)
+	}
+	if !signingKey.CanSign() {
+		return nil, fmt.Errorf("signing key %q has no private key", signingKeyID)
+	}
+	keyring.signingKey = signingKey
+
+	return keyring, nil
+}
+
+func loadJwtKey(id, algorithm, path string) (*JwtKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key %s: %w", id, err)
+	}
+	key := &JwtKey{ID: id, Algorithm: algorithm}
+
+	if algorithm == AlgHS256 {
+		key.secret = []byte(strings.TrimSpace(string(data)))
+		if len(key.secret) == 0 {
+			return nil, fmt.Errorf("key %s is empty", id)
+		}
+		return key, nil
+	}
+	if algorithm != AlgRS256 && algorithm != AlgEdDSA {
+		return nil, fmt.Errorf("key %s has unsupported algorithm %s", id, algorithm)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %s is not a PEM file", id)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		err = fmt.Errorf("unsupported PEM block %s", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key %s: %w", id, err)
+	}
+
+	switch parsedKey := parsed.(type) {
+	case ed25519.PrivateKey:
+		key.privateKey, key.publicKey = parsedKey, parsedKey.Public()
+	case *rsa.PrivateKey:
+		key.privateKey, key.publicKey = parsedKey, parsedKey.Public()
+	case ed25519.PublicKey, *rsa.PublicKey:
+		key.publicKey = parsedKey
+	}
+
+	// The key type has to match the configured algorithm
+	switch key.publicKey.(type) {
+	case ed25519.PublicKey:
+		if algorithm != AlgEdDSA {
+			return nil, fmt.Errorf("key %s is an Ed25519 key but configured as %s", id, algorithm)
+		}
+	case *rsa.PublicKey:
+		if algorithm != AlgRS256 {
+			return nil, fmt.Errorf("key %s is an RSA key but configured as %s", id, algorithm)
+		}
+	default:
+		return nil, fmt.Errorf("key %s has an unsupported key type", id)
+	}
+
+	return key, nil
+}
+
+var (
+	jwtKeyring     *JwtKeyring
+	jwtKeyringErr  error
+	jwtKeyringOnce sync.Once
+)
+
+// This function returns the process wide keyring, loading it on first use
+func GetJwtKeyring() (*JwtKeyring, error) {
+	jwtKeyringOnce.Do(func() {
+		jwtKeyring, jwtKeyringErr = LoadJwtKeyring()
+	})
+	if jwtKeyringErr != nil {
+		return nil, jwtKeyringErr
+	}
+	if jwtKeyring == nil {
+		return nil, errors.New("jwt keyring is not loaded")
+	}
+	return jwtKeyring, nil
+}
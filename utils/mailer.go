@@ -0,0 +1,138 @@
+package utils
+
+import (
+	"fmt"
+	"log"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Mail is a plain text email
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails to users
+type Mailer interface {
+	Send(mail Mail) error
+}
+
+// This function creates the mailer selected by MAILER (smtp, file or memory)
+//
+// The file mailer is the default so local setups work without an SMTP server.
+func NewMailerFromEnv() Mailer {
+	switch os.Getenv("MAILER") {
+	case "smtp":
+		return &SMTPMailer{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     os.Getenv("SMTP_PORT"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("MAIL_FROM"),
+		}
+	case "memory":
+		return NewMemoryMailer()
+	default:
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = "./mails"
+		}
+		return &FileMailer{Dir: dir}
+	}
+}
+
+// SMTPMailer sends emails through an SMTP server
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// This method sends the mail with net/smtp
+func (m *SMTPMailer) Send(mail Mail) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	return smtp.SendMail(m.Host+":"+m.Port, auth, m.From, []string{mail.To}, formatMail(m.From, mail))
+}
+
+// FileMailer writes every email as an .eml file into a directory
+type FileMailer struct {
+	Dir string
+}
+
+// This method writes the mail to Dir
+func (m *FileMailer) Send(mail Mail) error {
+	if err := os.MkdirAll(m.Dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	filename := fmt.Sprintf("%d_%s.eml", time.Now().UnixNano(), strings.ReplaceAll(mail.To, "/", "_"))
+	path := filepath.Join(m.Dir, filename)
+	if err := os.WriteFile(path, formatMail(os.Getenv("MAIL_FROM"), mail), 0o600); err != nil {
+		return err
+	}
+	log.Printf("[INFO] Mail to %s written to %s", mail.To, path)
+
+	return nil
+}
+
+// MemoryMailer keeps sent emails in memory, it is meant for tests
+type MemoryMailer struct {
+	mu    sync.Mutex
+	mails []Mail
+}
+
+// MemoryMailer constructor
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+// This method stores the mail
+func (m *MemoryMailer) Send(mail Mail) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mails = append(m.mails, mail)
+	return nil
+}
+
+// This method returns a copy of every mail sent so far
+func (m *MemoryMailer) Sent() []Mail {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Mail(nil), m.mails...)
+}
+
+// Header values come from user input, new lines would allow header injection
+var headerSanitizer = strings.NewReplacer("\r", "", "\n", "")
+
+func formatMail(from string, mail Mail) []byte {
+	var builder strings.Builder
+	builder.WriteString("From: " + headerSanitizer.Replace(from) + "\r\n")
+	builder.WriteString("To: " + headerSanitizer.Replace(mail.To) + "\r\n")
+	builder.WriteString("Subject: " + headerSanitizer.Replace(mail.Subject) + "\r\n")
+	builder.WriteString("MIME-Version: 1.0\r\n")
+	builder.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString(mail.Body)
+	return []byte(builder.String())
+}
+
+// Public URL of the API used to build links in emails, APP_BASE_URL
+func AppBaseURL() string {
+	baseURL := os.Getenv("APP_BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:3001"
+	}
+	return strings.TrimSuffix(baseURL, "/")
+}
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// File types that can be uploaded
+var (
+	ImageTypes     = []string{"png", "jpg", "jpeg", "gif", "bmp", "webp"}
+	PostMediaTypes = append(slices.Clone(ImageTypes), "mp4", "mov", "avi", "mkv", "flv", "wmv", "webm")
+)
+
+var ErrInvalidFileType = errors.New("invalid file type")
+
+// Directory uploaded files are saved in
+const UploadsDir = "./uploads"
+
+// IsUploadPath reports whether a stored path points into the uploads directory
+//
+// Paths come from the database, files outside the uploads directory must never be read or removed through them.
+func IsUploadPath(path string) bool {
+	return IsPathInside(UploadsDir, path)
+}
+
+// IsPathInside reports whether path points to a file inside dir
+func IsPathInside(dir, path string) bool {
+	if path == "" {
+		return false
+	}
+	rel, err := filepath.Rel(filepath.Clean(dir), filepath.Clean(path))
+	if err != nil {
+		return false
+	}
+	return rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Largest post media in MB, MAX_FILE_SIZE (default 50)
+func MaxFileSize() int64 {
+	return fileSizeFromEnv("MAX_FILE_SIZE", 50)
+}
+
+// Largest avatar or banner in MB, MAX_PROFILE_IMAGE_SIZE (default 5)
+func MaxProfileImageSize() int64 {
+	return fileSizeFromEnv("MAX_PROFILE_IMAGE_SIZE", 5)
+}
+
+func fileSizeFromEnv(key string, fallback int64) int64 {
+	size, err := strconv.ParseInt(os.Getenv(key), 10, 64)
+	if err != nil || size <= 0 {
+		return fallback
+	}
+	return size
+}
+
+// This function checks the size (in MB) and the extension of an uploaded file
+func ValidateUpload(file *multipart.FileHeader, allowed []string, maxSize int64) error {
+	if file.Size > maxSize*1024*1024 {
+		return fmt.Errorf("file size exceeds %dMB", maxSize)
+	}
+	suffix := strings.ToLower(strings.TrimPrefix(filepath.Ext(file.Filename), "."))
+	if !slices.Contains(allowed, suffix) {
+		return ErrInvalidFileType
+	}
+	return nil
+}
+
+// This function validates an uploaded file and saves it in ./uploads, it returns the saved path
+func SaveUpload(c *fiber.Ctx, file *multipart.FileHeader, userID uint, allowed []string, maxSize int64) (string, error) {
+	if err := ValidateUpload(file, allowed, maxSize); err != nil {
+		return "", err
+	}
+
+	filename := strings.ReplaceAll(fmt.Sprintf("%d_%d_%s", time.Now().Unix(), userID, filepath.Base(file.Filename)), " ", "-")
+	path := fmt.Sprintf("%s/%s", UploadsDir, filename)
+	if err := c.SaveFile(file, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,378 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidIDToken      = errors.New("id token is invalid")
+	ErrUnknownOIDCProvider = errors.New("unknown login provider")
+)
+
+// Allowed difference between our clock and the provider's when checking iat and exp
+const oidcClockSkew = time.Minute
+
+// OIDCDiscovery is the part of the provider's openid-configuration document we use
+type OIDCDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+}
+
+// OIDCTokenResponse is the answer of the token endpoint
+type OIDCTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// IDTokenClaims are the claims of a validated ID token
+type IDTokenClaims struct {
+	Issuer            string    `json:"iss"`
+	Subject           string    `json:"sub"`
+	Audience          audience  `json:"aud"`
+	AuthorizedParty   string    `json:"azp"`
+	Nonce             string    `json:"nonce"`
+	IssuedAt          int64     `json:"iat"`
+	ExpiresAt         int64     `json:"exp"`
+	Email             string    `json:"email"`
+	EmailVerified     boolClaim `json:"email_verified"`
+	Name              string    `json:"name"`
+	GivenName         string    `json:"given_name"`
+	FamilyName        string    `json:"family_name"`
+	PreferredUsername string    `json:"preferred_username"`
+}
+
+// aud can be a string or a list of strings
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+// Some providers send email_verified as the string "true"
+type boolClaim bool
+
+func (b *boolClaim) UnmarshalJSON(data []byte) error {
+	var value bool
+	if err := json.Unmarshal(data, &value); err == nil {
+		*b = boolClaim(value)
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	*b = boolClaim(text == "true")
+	return nil
+}
+
+// OIDCProvider is an OpenID Connect provider we accept logins from
+//
+// Discovery and the provider's keys are fetched on first use and cached. HTTPClient
+// and Clock can be replaced, e.g. to talk to a mock provider in tests.
+type OIDCProvider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	HTTPClient   *http.Client
+	Clock        Clock
+
+	mu        sync.Mutex
+	discovery *OIDCDiscovery
+	keys      map[string]*JwtKey
+}
+
+// This function reads the providers from the environment
+//
+// OIDC_PROVIDERS is a comma separated list of names. Every name needs
+// OIDC_{NAME}_ISSUER, OIDC_{NAME}_CLIENT_ID and OIDC_{NAME}_CLIENT_SECRET,
+// OIDC_{NAME}_SCOPES is optional (default "openid email profile").
+func LoadOIDCProviders() (map[string]*OIDCProvider, error) {
+	providers := map[string]*OIDCProvider{}
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+
+		provider := &OIDCProvider{
+			Name:         name,
+			Issuer:       strings.TrimSuffix(os.Getenv(prefix+"ISSUER"), "/"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  fmt.Sprintf("%s/users/oidc/%s/callback", AppBaseURL(), name),
+			Scopes:       strings.Fields(os.Getenv(prefix + "SCOPES")),
+		}
+		if provider.Issuer == "" || provider.ClientID == "" {
+			return nil, fmt.Errorf("login provider %s needs %sISSUER and %sCLIENT_ID", name, prefix, prefix)
+		}
+		providers[name] = provider
+	}
+	return providers, nil
+}
+
+func (p *OIDCProvider) httpClient() *http.Client {
+	if p.HTTPClient != nil {
+		return p.HTTPClient
+	}
+	return &http.Client{Timeout: 10 * time.Second}
+}
+
+func (p *OIDCProvider) now() time.Time {
+	if p.Clock != nil {
+		return p.Clock.Now()
+	}
+	return time.Now()
+}
+
+func (p *OIDCProvider) getJSON(endpoint string, target interface{}) error {
+	response, err := p.httpClient().Get(endpoint)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s answered with status %d", endpoint, response.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(response.Body, 1<<20)).Decode(target)
+}
+
+// This method fetches the provider's openid-configuration document
+func (p *OIDCProvider) Discover() (*OIDCDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var discovery OIDCDiscovery
+	if err := p.getJSON(p.Issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, fmt.Errorf("discovery of %s failed: %w", p.Name, err)
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != p.Issuer {
+		return nil, fmt.Errorf("discovery of %s returned issuer %q", p.Name, discovery.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JwksURI == "" {
+		return nil, fmt.Errorf("discovery of %s is missing endpoints", p.Name)
+	}
+	p.discovery = &discovery
+	return p.discovery, nil
+}
+
+// This method returns the URL the user is sent to, with PKCE (S256) and a nonce
+func (p *OIDCProvider) AuthCodeURL(state, nonce, codeVerifier string) (string, error) {
+	discovery, err := p.Discover()
+	if err != nil {
+		return "", err
+	}
+
+	scopes := p.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	}
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.ClientID},
+		"redirect_uri":          {p.RedirectURL},
+		"scope":                 {strings.Join(scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {PKCEChallenge(codeVerifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// This method exchanges the authorization code for tokens
+func (p *OIDCProvider) Exchange(code, codeVerifier string) (*OIDCTokenResponse, error) {
+	discovery, err := p.Discover()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.RedirectURL},
+		"client_id":     {p.ClientID},
+		"code_verifier": {codeVerifier},
+	}
+	if p.ClientSecret != "" {
+		form.Set("client_secret", p.ClientSecret)
+	}
+
+	response, err := p.httpClient().PostForm(discovery.TokenEndpoint, form)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint of %s answered with status %d", p.Name, response.StatusCode)
+	}
+
+	var tokens OIDCTokenResponse
+	if err := json.NewDecoder(io.LimitReader(response.Body, 1<<20)).Decode(&tokens); err != nil {
+		return nil, err
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("token endpoint of %s returned no id token", p.Name)
+	}
+	return &tokens, nil
+}
+
+// This method finds a signing key of the provider, the keys are fetched again once for an unknown kid
+func (p *OIDCProvider) key(kid string) (*JwtKey, error) {
+	discovery, err := p.Discover()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+
+	var jwks struct {
+		Keys []Jwk `json:"keys"`
+	}
+	if err := p.getJSON(discovery.JwksURI, &jwks); err != nil {
+		return nil, fmt.Errorf("keys of %s could not be fetched: %w", p.Name, err)
+	}
+	keys := map[string]*JwtKey{}
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.JwtKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	p.keys = keys
+
+	key, ok := keys[kid]
+	if !ok {
+		return nil, ErrInvalidIDToken
+	}
+	return key, nil
+}
+
+// This method checks the signature and the claims of an ID token
+//
+// The token must be signed by the provider, issued for our client, not expired
+// and carry the nonce of the login it belongs to.
+func (p *OIDCProvider) VerifyIDToken(rawToken, nonce string) (*IDTokenClaims, error) {
+	parts := strings.Split(rawToken, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidIDToken
+	}
+
+	headerJson, err := base64UrlDecode(parts[0])
+	if err != nil {
+		return nil, ErrInvalidIDToken
+	}
+	var header jwtHeader
+	if err := json.Unmarshal(headerJson, &header); err != nil {
+		return nil, ErrInvalidIDToken
+	}
+	key, err := p.key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if key.Algorithm != header.Alg {
+		return nil, ErrInvalidIDToken
+	}
+	signature, err := base64UrlDecode(parts[2])
+	if err != nil || !key.Verify([]byte(parts[0]+"."+parts[1]), signature) {
+		return nil, ErrInvalidIDToken
+	}
+
+	payloadJson, err := base64UrlDecode(parts[1])
+	if err != nil {
+		return nil, ErrInvalidIDToken
+	}
+	var claims IDTokenClaims
+	if err := json.Unmarshal(payloadJson, &claims); err != nil {
+		return nil, ErrInvalidIDToken
+	}
+
+	now := p.now()
+	switch {
+	case strings.TrimSuffix(claims.Issuer, "/") != p.Issuer:
+		return nil, fmt.Errorf("%w: wrong issuer", ErrInvalidIDToken)
+	case !claims.hasAudience(p.ClientID):
+		return nil, fmt.Errorf("%w: wrong audience", ErrInvalidIDToken)
+	case len(claims.Audience) > 1 && claims.AuthorizedParty != p.ClientID:
+		return nil, fmt.Errorf("%w: wrong authorized party", ErrInvalidIDToken)
+	case claims.Subject == "":
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidIDToken)
+	case claims.Nonce == "" || claims.Nonce != nonce:
+		return nil, fmt.Errorf("%w: wrong nonce", ErrInvalidIDToken)
+	case now.Add(-oidcClockSkew).Unix() >= claims.ExpiresAt:
+		return nil, fmt.Errorf("%w: expired", ErrInvalidIDToken)
+	case claims.IssuedAt > now.Add(oidcClockSkew).Unix():
+		return nil, fmt.Errorf("%w: issued in the future", ErrInvalidIDToken)
+	}
+
+	return &claims, nil
+}
+
+func (c *IDTokenClaims) hasAudience(clientID string) bool {
+	for _, aud := range c.Audience {
+		if aud == clientID {
+			return true
+		}
+	}
+	return false
+}
+
+// This function returns a random string for state, nonce and PKCE verifiers
+func NewOIDCSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// This function returns the S256 code challenge of a PKCE verifier
+func PKCEChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -1,7 +1,9 @@
 package utils
 
 import (
+	"errors"
 	"log"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -12,4 +14,43 @@ func BodyParse(c *fiber.Ctx, input interface{}) error {
 		return err
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+// Default and largest page size of paginated lists
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// PageQuery reads the page and limit query parameters, missing or invalid values fall back to the first page
+func PageQuery(c *fiber.Ctx) (page, limit int) {
+	page = c.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	limit = c.QueryInt("limit", DefaultPageSize)
+	if limit < 1 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+	return page, limit
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// CursorQuery reads the cursor and limit query parameters, the cursor is the id of the last item already seen
+//
+// A missing cursor starts from the beginning.
+func CursorQuery(c *fiber.Ctx) (cursor uint, limit int, err error) {
+	_, limit = PageQuery(c)
+	if value := c.Query("cursor"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return 0, limit, ErrInvalidCursor
+		}
+		cursor = uint(id)
+	}
+	return cursor, limit, nil
+}
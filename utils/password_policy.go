@@ -0,0 +1,215 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PasswordPolicy describes which new passwords are accepted
+type PasswordPolicy struct {
+	MinLength  int
+	MaxLength  int
+	MinEntropy float64 // estimated bits, see PasswordEntropy
+	// Path of the breached password list, empty to skip the check
+	BreachedList string
+}
+
+// PasswordPolicyError explains why a password was rejected, its message can be shown to the user
+type PasswordPolicyError struct {
+	Reason string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return e.Reason
+}
+
+// This function reads the password policy from the environment
+//
+// PASSWORD_MIN_LENGTH and PASSWORD_MAX_LENGTH default to 8 and 128 characters, PASSWORD_MIN_ENTROPY
+// to 35 bits. PASSWORD_BREACHED_LIST is the path of a breached password list, see BreachedPasswordList.
+func PasswordPolicyFromEnv() PasswordPolicy {
+	minEntropy, err := strconv.ParseFloat(os.Getenv("PASSWORD_MIN_ENTROPY"), 64)
+	if err != nil || minEntropy < 0 {
+		minEntropy = 35
+	}
+	return PasswordPolicy{
+		MinLength:    int(uintFromEnv("PASSWORD_MIN_LENGTH", 8)),
+		MaxLength:    int(uintFromEnv("PASSWORD_MAX_LENGTH", 128)),
+		MinEntropy:   minEntropy,
+		BreachedList: os.Getenv("PASSWORD_BREACHED_LIST"),
+	}
+}
+
+// This method checks a new password, userInputs (username, email, names) must not be part of it
+//
+// A *PasswordPolicyError is returned for a rejected password, other errors mean the check itself failed.
+func (p PasswordPolicy) Validate(password string, userInputs ...string) error {
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		return &PasswordPolicyError{fmt.Sprintf("password must be at least %d characters", p.MinLength)}
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		return &PasswordPolicyError{fmt.Sprintf("password can be at most %d characters", p.MaxLength)}
+	}
+
+	lower := strings.ToLower(password)
+	for _, input := range userInputs {
+		input = strings.ToLower(strings.TrimSpace(input))
+		if local, _, found := strings.Cut(input, "@"); found {
+			input = local
+		}
+		if utf8.RuneCountInString(input) >= 3 && strings.Contains(lower, input) {
+			return &PasswordPolicyError{"password must not contain your username, name or email"}
+		}
+	}
+
+	if PasswordEntropy(password) < p.MinEntropy {
+		return &PasswordPolicyError{"password is too easy to guess, use a longer password with more kinds of characters"}
+	}
+
+	if p.BreachedList != "" {
+		breached, err := BreachedPasswordList(p.BreachedList).Contains(password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			return &PasswordPolicyError{"password has appeared in a data breach, choose another one"}
+		}
+	}
+	return nil
+}
+
+// This function estimates the entropy of a password in bits
+//
+// Every character adds log2 of the size of the character classes used (lowercase, uppercase,
+// digits, symbols, others). Repeated characters and steps of a sequence like "abc" or "321"
+// add nothing, so "aaaaaaaa" and "12345678" score low.
+func PasswordEntropy(password string) float64 {
+	var hasLower, hasUpper, hasDigit, hasSymbol, hasOther bool
+	effective := 0
+	var previous, step rune
+	for i, r := range []rune(password) {
+		switch {
+		case r > unicode.MaxASCII:
+			hasOther = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		default:
+			hasSymbol = true
+		}
+
+		if i > 0 {
+			diff := r - previous
+			if diff == 0 || ((diff == 1 || diff == -1) && diff == step) {
+				step = diff
+				previous = r
+				continue
+			}
+			step = diff
+		}
+		effective++
+		previous = r
+	}
+
+	pool := 0
+	for _, class := range []struct {
+		used bool
+		size int
+	}{{hasLower, 26}, {hasUpper, 26}, {hasDigit, 10}, {hasSymbol, 33}, {hasOther, 100}} {
+		if class.used {
+			pool += class.size
+		}
+	}
+	if pool == 0 {
+		return 0
+	}
+	return float64(effective) * math.Log2(float64(pool))
+}
+
+// BreachedPasswordList is the path of a file with the SHA-1 hashes of breached passwords
+//
+// Every line is a hex hash, optionally followed by ":count", sorted by hash, like the
+// "ordered by hash" download of Have I Been Pwned. The file is binary searched on disk,
+// so it is never loaded into memory and passwords never leave the server.
+type BreachedPasswordList string
+
+// This method reports whether the password is in the list
+func (l BreachedPasswordList) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	target := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	file, err := os.Open(string(l))
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	// Invariant: a matching line starts in [low, high)
+	low, high := int64(0), info.Size()
+	for low < high {
+		mid := low + (high-low)/2
+		hash, end, err := breachedLineAt(file, mid)
+		if errors.Is(err, io.EOF) {
+			high = mid
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+
+		switch compare := strings.Compare(target, hash); {
+		case compare == 0:
+			return true, nil
+		case compare < 0:
+			high = mid
+		default:
+			low = end
+		}
+	}
+	return false, nil
+}
+
+// Reads the hash of the first line starting at or after offset, end is where the next line starts
+func breachedLineAt(file *os.File, offset int64) (hash string, end int64, err error) {
+	start := offset
+	if offset > 0 {
+		start = offset - 1
+	}
+	reader := bufio.NewReader(io.NewSectionReader(file, start, math.MaxInt64-start))
+
+	if offset > 0 {
+		// Skip the rest of the line offset is in, unless offset is already a line start
+		skipped, err := reader.ReadString('\n')
+		if err != nil {
+			return "", 0, io.EOF
+		}
+		start += int64(len(skipped))
+	}
+
+	line, err := reader.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		return "", 0, io.EOF
+	}
+	end = start + int64(len(line))
+
+	hash, _, _ = strings.Cut(strings.TrimSpace(line), ":")
+	return strings.ToUpper(hash), end, nil
+}
@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// "12345678901234567890", the secret of the RFC 6238 test vectors
+const testTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeMatchesRFC6238(t *testing.T) {
+	// The RFC lists 8 digit codes, the last 6 digits are the 6 digit code
+	for _, tc := range []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	} {
+		code, err := TOTPCode(testTOTPSecret, TOTPStep(time.Unix(tc.unix, 0)))
+		if err != nil {
+			t.Fatalf("TOTPCode(%d): %v", tc.unix, err)
+		}
+		if code != tc.code {
+			t.Errorf("TOTPCode(%d) = %s, want %s", tc.unix, code, tc.code)
+		}
+	}
+}
+
+func TestValidateTOTPAcceptsOneStepOfSkew(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000010, 0))
+	current := TOTPStep(clock.Now())
+
+	for offset := int64(-2); offset <= 2; offset++ {
+		code, err := TOTPCode(testTOTPSecret, current+offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		step, ok := ValidateTOTP(testTOTPSecret, code, clock.Now())
+		want := offset >= -1 && offset <= 1
+		if ok != want {
+			t.Errorf("code of step %+d: valid = %v, want %v", offset, ok, want)
+		}
+		if ok && step != current+offset {
+			t.Errorf("code of step %+d: matched step %d, want %d", offset, step, current+offset)
+		}
+	}
+}
+
+func TestValidateTOTPRejectsExpiredCode(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000010, 0))
+	code, err := TOTPCode(testTOTPSecret, TOTPStep(clock.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Still valid one step later because of the allowed drift
+	clock.Advance(totpPeriod * time.Second)
+	if _, ok := ValidateTOTP(testTOTPSecret, code, clock.Now()); !ok {
+		t.Fatal("code was rejected one step later")
+	}
+
+	clock.Advance(totpPeriod * time.Second)
+	if _, ok := ValidateTOTP(testTOTPSecret, code, clock.Now()); ok {
+		t.Fatal("code was accepted two steps later")
+	}
+}
+
+func TestValidateTOTPRejectsMalformedCode(t *testing.T) {
+	now := time.Unix(1700000010, 0)
+	for _, code := range []string{"", "12345", "1234567", "abcdef"} {
+		if _, ok := ValidateTOTP(testTOTPSecret, code, now); ok {
+			t.Errorf("code %q was accepted", code)
+		}
+	}
+}
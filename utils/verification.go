@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Email verification link lifetime, EMAIL_VERIFICATION_TTL in hours (default 24)
+func EmailVerificationTTL() time.Duration {
+	return durationFromEnv("EMAIL_VERIFICATION_TTL", time.Hour, 24)
+}
+
+// Policy switch, when REQUIRE_VERIFIED_EMAIL is true users can not post before verifying their email
+func EmailVerificationRequired() bool {
+	required, _ := strconv.ParseBool(os.Getenv("REQUIRE_VERIFIED_EMAIL"))
+	return required
+}
+
+// This function creates the signed link that is mailed to the user
+//
+// The token is bound to the email so changing the email invalidates old links.
+func CreateEmailVerificationLink(userID uint, email string) (string, error) {
+	token, _, err := SignJwt(&JwtClaims{
+		UserID: userID,
+		Type:   EmailVerifyTokenType,
+		Email:  email,
+	}, EmailVerificationTTL())
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/users/verify?token=%s", AppBaseURL(), token), nil
+}
+
+// This function checks a token created by CreateEmailVerificationLink
+func VerifyEmailVerificationToken(token string) (*JwtClaims, error) {
+	claims, err := VerifyJwt(token)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Type != EmailVerifyTokenType || claims.Email == "" {
+		return nil, errors.New("invalid verification token")
+	}
+	return claims, nil
+}
+
+// This function mails the verification link to the user
+func SendVerificationEmail(mailer Mailer, userID uint, email, username string) error {
+	link, err := CreateEmailVerificationLink(userID, email)
+	if err != nil {
+		return err
+	}
+
+	return mailer.Send(Mail{
+		To:      email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease verify your email by opening the link below:\n\n%s\n\nThe link expires in %s.\n",
+			username, link, EmailVerificationTTL()),
+	})
+}
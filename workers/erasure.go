@@ -0,0 +1,59 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"golang_task/repositories"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// This Function Runs Account Erasure Jobs from queue
+//
+// Jobs that were pending or running when the process stopped are resumed first.
+func ErasureWorker(rdb *redis.Client, db *gorm.DB) {
+	ctx := context.Background()
+
+	erasureRepo := repositories.NewErasureRepository(db, rdb)
+	fmt.Println("[INFO] ErasureWorker started, listening to queue:", repositories.ErasureQueueKey)
+
+	// Resume interrupted jobs
+	jobs, err := erasureRepo.Unfinished()
+	if err != nil {
+		fmt.Printf("[ERROR] Failed to load unfinished erasure jobs: %v\n", err)
+	}
+	for i := range jobs {
+		fmt.Printf("[INFO] Resuming erasure job %d at step %q\n", jobs[i].ID, jobs[i].Step)
+		if err := erasureRepo.Run(&jobs[i]); err != nil {
+			fmt.Printf("[ERROR] Erasure job %d failed: %v\n", jobs[i].ID, err)
+		}
+	}
+
+	for {
+		// Listen to queue
+		result, err := rdb.BLPop(ctx, 0*time.Second, repositories.ErasureQueueKey).Result()
+		if err != nil {
+			fmt.Printf("[ERROR] Failed to pop from erasure queue: %v\n", err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		jobID, err := strconv.ParseUint(result[1], 10, 64)
+		if err != nil {
+			fmt.Printf("[ERROR] Invalid erasure job id %q: %v\n", result[1], err)
+			continue
+		}
+
+		job, err := erasureRepo.GetByID(uint(jobID))
+		if err != nil {
+			fmt.Printf("[ERROR] Failed to load erasure job %d: %v\n", jobID, err)
+			continue
+		}
+		if err := erasureRepo.Run(job); err != nil {
+			fmt.Printf("[ERROR] Erasure job %d failed: %v\n", job.ID, err)
+		}
+	}
+}
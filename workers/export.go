@@ -0,0 +1,70 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"golang_task/repositories"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// This Function Builds Data Export Archives from queue
+//
+// Jobs that were pending or running when the process stopped are built first.
+// Expired archives are removed once an hour.
+func ExportWorker(rdb *redis.Client, db *gorm.DB) {
+	ctx := context.Background()
+
+	exportRepo := repositories.NewExportRepository(db, rdb)
+	fmt.Println("[INFO] ExportWorker started, listening to queue:", repositories.ExportQueueKey)
+
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for ; ; <-ticker.C {
+			if err := exportRepo.PurgeExpired(); err != nil {
+				fmt.Printf("[ERROR] Failed to remove expired exports: %v\n", err)
+			}
+		}
+	}()
+
+	// Resume interrupted jobs
+	jobs, err := exportRepo.Unfinished()
+	if err != nil {
+		fmt.Printf("[ERROR] Failed to load unfinished export jobs: %v\n", err)
+	}
+	for i := range jobs {
+		fmt.Printf("[INFO] Resuming export job %d\n", jobs[i].ID)
+		if err := exportRepo.Run(&jobs[i]); err != nil {
+			fmt.Printf("[ERROR] Export job %d failed: %v\n", jobs[i].ID, err)
+		}
+	}
+
+	for {
+		// Listen to queue
+		result, err := rdb.BLPop(ctx, 0*time.Second, repositories.ExportQueueKey).Result()
+		if err != nil {
+			fmt.Printf("[ERROR] Failed to pop from export queue: %v\n", err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		jobID, err := strconv.ParseUint(result[1], 10, 64)
+		if err != nil {
+			fmt.Printf("[ERROR] Invalid export job id %q: %v\n", result[1], err)
+			continue
+		}
+
+		job, err := exportRepo.GetByID(uint(jobID))
+		if err != nil {
+			fmt.Printf("[ERROR] Failed to load export job %d: %v\n", jobID, err)
+			continue
+		}
+		if err := exportRepo.Run(job); err != nil {
+			fmt.Printf("[ERROR] Export job %d failed: %v\n", job.ID, err)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"golang_task/repositories"
+	"slices"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -18,6 +19,7 @@ func FanOutWorker(rdb *redis.Client, db *gorm.DB) {
 
 	// Create follow repository
 	followRepo := repositories.NewFollowRepository(db, rdb)
+	blockRepo := repositories.NewBlockRepository(db, rdb)
 	fmt.Println("[INFO] FanOutWorker started, listening to queue:", queueKey)
 
 	for {
@@ -52,7 +54,18 @@ func FanOutWorker(rdb *redis.Client, db *gorm.DB) {
 
 		fmt.Printf("[INFO] Author %d has %d followers\n", resultMap.AuthorID, len(authorFollowers))
 
+		// Users who blocked the author never get the author's posts
+		blockerIDs, err := blockRepo.BlockerIDs(resultMap.AuthorID)
+		if err != nil {
+			fmt.Printf("[ERROR] Failed to get users who blocked author %d: %v\n", resultMap.AuthorID, err)
+
+			continue
+		}
+
 		for _, follower := range authorFollowers {
+			if resultMap.IsAdd && slices.Contains(blockerIDs, follower.ID) {
+				continue
+			}
 			key := fmt.Sprintf("timeline:%d", follower.ID)
 			if resultMap.IsAdd {
 			
@@ -0,0 +1,42 @@
+package workers
+
+import (
+	"fmt"
+	"golang_task/repositories"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// Posts recounted per query
+const reconcileBatchSize = 500
+
+// This Function recounts changed reaction counters from MySQL
+//
+// Counters are changed in Redis as reactions come in, a failed or raced change is fixed here.
+func ReactionReconcileWorker(rdb *redis.Client, db *gorm.DB) {
+	reactionRepo := repositories.NewReactionRepository(db, rdb)
+	interval := repositories.ReactionReconcileInterval()
+	fmt.Println("[INFO] ReactionReconcileWorker started, running every", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		total := 0
+		for {
+			count, err := reactionRepo.Reconcile(reconcileBatchSize)
+			if err != nil {
+				fmt.Printf("[ERROR] Failed to reconcile reaction counters: %v\n", err)
+				break
+			}
+			total += count
+			if count < reconcileBatchSize {
+				break
+			}
+		}
+		if total > 0 {
+			fmt.Printf("[INFO] Reconciled reaction counters of %d posts\n", total)
+		}
+	}
+}
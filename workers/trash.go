@@ -0,0 +1,29 @@
+package workers
+
+import (
+	"fmt"
+	"golang_task/repositories"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// This Function permanently deletes posts that stayed in the trash longer than the retention period
+func TrashPurgeWorker(rdb *redis.Client, db *gorm.DB) {
+	postRepo := repositories.NewPostRepository(db, rdb)
+	retention := repositories.PostTrashRetention()
+	fmt.Println("[INFO] TrashPurgeWorker started, posts are kept in the trash for", retention)
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for ; ; <-ticker.C {
+		count, err := postRepo.PurgeTrash(time.Now().Add(-retention))
+		if err != nil {
+			fmt.Printf("[ERROR] Failed to purge trashed posts: %v\n", err)
+		}
+		if count > 0 {
+			fmt.Printf("[INFO] Purged %d posts from the trash\n", count)
+		}
+	}
+}